@@ -1,14 +1,19 @@
 package incr
 
 import (
-	"fmt"
+	"math"
+	"slices"
 	"sync"
 )
 
-func newAdjustHeightsHeap(maxHeightAllowed int) *adjustHeightsHeap {
+// newAdjustHeightsHeap returns a heap with room for the given number of heights up
+// front, which grows past that as nodes are set higher, up to maxHeight heights if
+// maxHeight is positive.
+func newAdjustHeightsHeap(heights, maxHeight int) *adjustHeightsHeap {
 	return &adjustHeightsHeap{
-		nodesByHeight:    make([]*queue[INode], maxHeightAllowed),
-		heightLowerBound: maxHeightAllowed + 1,
+		nodesByHeight:    make([]*queue[INode], heights),
+		heightLowerBound: heights,
+		maxHeight:        maxHeight,
 	}
 }
 
@@ -26,6 +31,8 @@ type adjustHeightsHeap struct {
 	numNodes         int
 	maxHeightSeen    int
 	heightLowerBound int
+	// maxHeight is the number of heights nodes may be set to, or zero for no limit.
+	maxHeight int
 }
 
 func (ah *adjustHeightsHeap) len() int {
@@ -33,7 +40,10 @@ func (ah *adjustHeightsHeap) len() int {
 }
 
 func (ah *adjustHeightsHeap) maxHeightAllowed() int {
-	return len(ah.nodesByHeight) - 1
+	if ah.maxHeight > 0 {
+		return ah.maxHeight - 1
+	}
+	return math.MaxInt
 }
 
 func (ah *adjustHeightsHeap) setHeight(node INode, height int) error {
@@ -77,7 +87,11 @@ func (ah *adjustHeightsHeap) adjustHeights(rh *recomputeHeap, originalChild, ori
 
 func (ah *adjustHeightsHeap) ensureHeightRequirementUnsafe(originalChild, originalParent, child, parent INode) error {
 	if originalParent.Node().id == child.Node().id {
-		return fmt.Errorf("cycle detected at %v to %v", originalChild, originalParent)
+		return &CycleError{
+			Child:  originalChild,
+			Parent: originalParent,
+			Path:   dependentPath(originalChild, originalParent),
+		}
 	}
 	if parent.Node().height >= child.Node().height {
 		// we set `child.height` after adding `child` to the heap, so that `child` goes
@@ -112,6 +126,7 @@ func (ah *adjustHeightsHeap) addUnsafe(node INode) {
 	}
 	height := node.Node().height
 	node.Node().heightInAdjustHeightsHeap = height
+	ah.maybeAddNewHeightsUnsafe(height)
 	if ah.nodesByHeight[height] == nil {
 		ah.nodesByHeight[height] = new(queue[INode])
 	}
@@ -124,7 +139,7 @@ func (ah *adjustHeightsHeap) addUnsafe(node INode) {
 
 func (ah *adjustHeightsHeap) setHeightUnsafe(node INode, height int) error {
 	if height > ah.maxHeightAllowed() {
-		return fmt.Errorf("cannot set node height above %d", ah.maxHeightAllowed())
+		return &HeightExceededError{Node: node, Height: height, MaxHeight: ah.maxHeightAllowed()}
 	}
 	if height > ah.maxHeightSeen {
 		ah.maxHeightSeen = height
@@ -132,3 +147,40 @@ func (ah *adjustHeightsHeap) setHeightUnsafe(node INode, height int) error {
 	node.Node().height = height
 	return nil
 }
+
+// maybeAddNewHeightsUnsafe makes room for a height past the end of the heap, the same
+// way the recompute heap does; append keeps the copying amortized as the graph deepens.
+func (ah *adjustHeightsHeap) maybeAddNewHeightsUnsafe(height int) {
+	if len(ah.nodesByHeight) <= height {
+		ah.nodesByHeight = append(ah.nodesByHeight, make([]*queue[INode], (height-len(ah.nodesByHeight))+1)...)
+	}
+}
+
+// dependentPath finds the way from one node up through its dependents, and the nodes in
+// the scopes of the binds among them, to another, as [CycleError.Path] reports it: from
+// the node reached back down to the one started from. It walks the same edges as
+// adjustHeights, and is only called once that has found a cycle.
+func dependentPath(from, to INode) []INode {
+	seen := make(map[Identifier]struct{})
+	var walk func(INode) []INode
+	walk = func(n INode) []INode {
+		if n.Node().id == to.Node().id {
+			return []INode{n}
+		}
+		if _, ok := seen[n.Node().id]; ok {
+			return nil
+		}
+		seen[n.Node().id] = struct{}{}
+		next := n.Node().children
+		if typed, ok := n.(IBindChange); ok {
+			next = append(slices.Clip(next), typed.RightScopeNodes()...)
+		}
+		for _, c := range next {
+			if path := walk(c); path != nil {
+				return append(path, n)
+			}
+		}
+		return nil
+	}
+	return walk(from)
+}
@@ -8,7 +8,7 @@ import (
 
 func Test_adjustHeightsHeap_len(t *testing.T) {
 	g := New()
-	ahh := newAdjustHeightsHeap(32)
+	ahh := newAdjustHeightsHeap(32, 32)
 
 	testutil.Equal(t, 0, ahh.len())
 
@@ -19,14 +19,31 @@ func Test_adjustHeightsHeap_len(t *testing.T) {
 }
 
 func Test_adjustHeightsHeap_maxHeightAllowed(t *testing.T) {
-	ahh := newAdjustHeightsHeap(32)
+	ahh := newAdjustHeightsHeap(32, 32)
 
 	testutil.Equal(t, 31, ahh.maxHeightAllowed())
 }
 
+func Test_adjustHeightsHeap_addUnsafe_grows(t *testing.T) {
+	g := New()
+	ahh := newAdjustHeightsHeap(4, 0)
+
+	n := newMockBareNodeWithHeight(g, 9)
+	err := ahh.setHeightUnsafe(n, 10)
+	testutil.NoError(t, err)
+	ahh.addUnsafe(n)
+	testutil.Equal(t, 11, len(ahh.nodesByHeight))
+	testutil.Equal(t, 1, ahh.len())
+
+	ahh.heightLowerBound = 0
+	popped, ok := ahh.removeMinUnsafe()
+	testutil.Equal(t, true, ok)
+	testutil.Equal(t, n.Node().id, popped.Node().id)
+}
+
 func Test_adjustHeightsHeap_setHeightUnsafe(t *testing.T) {
 	g := New()
-	ahh := newAdjustHeightsHeap(32)
+	ahh := newAdjustHeightsHeap(32, 32)
 
 	n0 := newMockBareNodeWithHeight(g, 1)
 	ahh.maxHeightSeen = 10
@@ -49,7 +66,7 @@ func Test_adjustHeightsHeap_setHeightUnsafe(t *testing.T) {
 }
 
 func Test_adjustHeightsHeap_ensureHeightRequirementUnsafe(t *testing.T) {
-	ahh := newAdjustHeightsHeap(32)
+	ahh := newAdjustHeightsHeap(32, 32)
 
 	g := New()
 	n0 := newMockBareNodeWithHeight(g, 1)
@@ -81,7 +98,7 @@ func Test_adjustHeightsHeap_ensureHeightRequirementUnsafe(t *testing.T) {
 
 func Test_adjustHeightsHeap_adjustHeights(t *testing.T) {
 	g := New()
-	ahh := newAdjustHeightsHeap(32)
+	ahh := newAdjustHeightsHeap(32, 32)
 
 	n4 := newMockBareNodeWithHeight(g, 5)
 	n5 := newMockBareNodeWithHeight(g, ahh.maxHeightAllowed()+1)
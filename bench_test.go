@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"math/rand"
 	"testing"
+	"time"
 )
 
 func Benchmark_createGraph_512(b *testing.B) {
@@ -112,27 +113,51 @@ func Benchmark_Stabilize_withPreInitialize_16384(b *testing.B) {
 }
 
 func Benchmark_ParallelStabilize_withPreInitialize_512(b *testing.B) {
-	benchmarkParallelSize(512, b)
+	benchmarkParallelSize(512, ParallelSchedulerHeightBlocks, b)
 }
 
 func Benchmark_ParallelStabilize_withPreInitialize_1024(b *testing.B) {
-	benchmarkParallelSize(1024, b)
+	benchmarkParallelSize(1024, ParallelSchedulerHeightBlocks, b)
 }
 
 func Benchmark_ParallelStabilize_withPreInitialize_2048(b *testing.B) {
-	benchmarkParallelSize(2048, b)
+	benchmarkParallelSize(2048, ParallelSchedulerHeightBlocks, b)
 }
 
 func Benchmark_ParallelStabilize_withPreInitialize_4096(b *testing.B) {
-	benchmarkParallelSize(4096, b)
+	benchmarkParallelSize(4096, ParallelSchedulerHeightBlocks, b)
 }
 
 func Benchmark_ParallelStabilize_withPreInitialize_8192(b *testing.B) {
-	benchmarkParallelSize(8192, b)
+	benchmarkParallelSize(8192, ParallelSchedulerHeightBlocks, b)
 }
 
 func Benchmark_ParallelStabilize_withPreInitialize_16384(b *testing.B) {
-	benchmarkParallelSize(16384, b)
+	benchmarkParallelSize(16384, ParallelSchedulerHeightBlocks, b)
+}
+
+func Benchmark_ParallelStabilize_dataflow_withPreInitialize_512(b *testing.B) {
+	benchmarkParallelSize(512, ParallelSchedulerDataflow, b)
+}
+
+func Benchmark_ParallelStabilize_dataflow_withPreInitialize_1024(b *testing.B) {
+	benchmarkParallelSize(1024, ParallelSchedulerDataflow, b)
+}
+
+func Benchmark_ParallelStabilize_dataflow_withPreInitialize_2048(b *testing.B) {
+	benchmarkParallelSize(2048, ParallelSchedulerDataflow, b)
+}
+
+func Benchmark_ParallelStabilize_dataflow_withPreInitialize_4096(b *testing.B) {
+	benchmarkParallelSize(4096, ParallelSchedulerDataflow, b)
+}
+
+func Benchmark_ParallelStabilize_dataflow_withPreInitialize_8192(b *testing.B) {
+	benchmarkParallelSize(8192, ParallelSchedulerDataflow, b)
+}
+
+func Benchmark_ParallelStabilize_dataflow_withPreInitialize_16384(b *testing.B) {
+	benchmarkParallelSize(16384, ParallelSchedulerDataflow, b)
 }
 
 func Benchmark_Stabilize_recombinant_64(b *testing.B) {
@@ -152,19 +177,43 @@ func Benchmark_Stabilize_recombinant_512(b *testing.B) {
 }
 
 func Benchmark_ParallelStabilize_recombinant_64(b *testing.B) {
-	benchmarkParallelRecombinantSize(64, b)
+	benchmarkParallelRecombinantSize(64, ParallelSchedulerHeightBlocks, b)
 }
 
 func Benchmark_ParallelStabilize_recombinant_128(b *testing.B) {
-	benchmarkParallelRecombinantSize(128, b)
+	benchmarkParallelRecombinantSize(128, ParallelSchedulerHeightBlocks, b)
 }
 
 func Benchmark_ParallelStabilize_recombinant_256(b *testing.B) {
-	benchmarkParallelRecombinantSize(256, b)
+	benchmarkParallelRecombinantSize(256, ParallelSchedulerHeightBlocks, b)
 }
 
 func Benchmark_ParallelStabilize_recombinant_512(b *testing.B) {
-	benchmarkParallelRecombinantSize(512, b)
+	benchmarkParallelRecombinantSize(512, ParallelSchedulerHeightBlocks, b)
+}
+
+func Benchmark_ParallelStabilize_dataflow_recombinant_64(b *testing.B) {
+	benchmarkParallelRecombinantSize(64, ParallelSchedulerDataflow, b)
+}
+
+func Benchmark_ParallelStabilize_dataflow_recombinant_128(b *testing.B) {
+	benchmarkParallelRecombinantSize(128, ParallelSchedulerDataflow, b)
+}
+
+func Benchmark_ParallelStabilize_dataflow_recombinant_256(b *testing.B) {
+	benchmarkParallelRecombinantSize(256, ParallelSchedulerDataflow, b)
+}
+
+func Benchmark_ParallelStabilize_dataflow_recombinant_512(b *testing.B) {
+	benchmarkParallelRecombinantSize(512, ParallelSchedulerDataflow, b)
+}
+
+func Benchmark_ParallelStabilize_uneven(b *testing.B) {
+	benchmarkParallelUneven(ParallelSchedulerHeightBlocks, b)
+}
+
+func Benchmark_ParallelStabilize_dataflow_uneven(b *testing.B) {
+	benchmarkParallelUneven(ParallelSchedulerDataflow, b)
 }
 
 func Benchmark_Stabilize_deep_2_32(b *testing.B) {
@@ -327,8 +376,9 @@ func benchmarkSize(size int, b *testing.B) {
 	}
 }
 
-func benchmarkParallelSize(size int, b *testing.B) {
+func benchmarkParallelSize(size int, scheduler ParallelScheduler, b *testing.B) {
 	graph, nodes := makeBenchmarkGraph(size, false /*preallocate*/, _defaultIdentifierProvider)
+	graph.parallelScheduler = scheduler
 	ctx := testContext()
 	var err error
 	for b.Loop() {
@@ -384,8 +434,9 @@ func benchmarkRecombinantSize(size int, b *testing.B) {
 	}
 }
 
-func benchmarkParallelRecombinantSize(size int, b *testing.B) {
+func benchmarkParallelRecombinantSize(size int, scheduler ParallelScheduler, b *testing.B) {
 	graph, input, observer := makeBenchmarkRecombinantGraph(size)
+	graph.parallelScheduler = scheduler
 	ctx := testContext()
 	var err error
 	for b.Loop() {
@@ -415,6 +466,49 @@ func benchmarkParallelRecombinantSize(size int, b *testing.B) {
 	}
 }
 
+// benchmarkParallelUneven measures a graph where one node is much slower than the rest:
+// a single slow node at height 1 beside chains of fast ones eight deep. Height blocks
+// hold every chain at height 2 until the slow node is done; dataflow lets the chains
+// run alongside it.
+func benchmarkParallelUneven(scheduler ParallelScheduler, b *testing.B) {
+	const width, depth = 4, 8
+	graph := New(
+		OptGraphParallelism(width+1),
+		OptGraphParallelScheduler(scheduler),
+	)
+	input := Var(graph, 0)
+	slow := Map(graph, input, func(x int) int {
+		time.Sleep(2 * time.Millisecond)
+		return x
+	})
+	inputs := []Incr[int]{slow}
+	for range width {
+		var cursor Incr[int] = input
+		for range depth {
+			cursor = Map(graph, cursor, func(x int) int {
+				time.Sleep(200 * time.Microsecond)
+				return x + 1
+			})
+		}
+		inputs = append(inputs, cursor)
+	}
+	_ = MustObserve(graph, MapN(graph, func(values ...int) (out int) {
+		for _, v := range values {
+			out += v
+		}
+		return
+	}, inputs...))
+	ctx := testContext()
+	var n int
+	for b.Loop() {
+		n++
+		input.Set(n)
+		if err := graph.ParallelStabilize(ctx); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
 func benchmarkDepth(width, depth int, b *testing.B) {
 	graph := New(
 		OptGraphMaxHeight(1024),
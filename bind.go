@@ -146,6 +146,8 @@ func (b *bind[A, B]) isScopeNecessary() bool    { return b.main.Node().isNecessa
 func (b *bind[A, B]) scopeGraph() *Graph        { return b.graph }
 func (b *bind[A, B]) scopeHeight() int          { return b.lhsChange.Node().height }
 func (b *bind[A, B]) newIdentifier() Identifier { return b.graph.newIdentifier() }
+func (b *bind[A, B]) scopeChange() INode        { return b.lhsChange }
+func (b *bind[A, B]) scopeMain() INode          { return b.main }
 
 func (b *bind[A, B]) addScopeNode(n INode) {
 	b.rhsNodes = append(b.rhsNodes, n)
@@ -210,6 +212,10 @@ func (b *bindMainIncr[A, B]) String() string {
 type bindLeftChangeIncr[A, B any] struct {
 	n    *Node
 	bind *bind[A, B]
+	// oldRhs and oldRightNodes carry the right-hand side being replaced from building
+	// the new one to linking it; see [bindLeftChangeIncr.Stabilize].
+	oldRhs        Incr[B]
+	oldRightNodes []INode
 	// parents is an array rather than a slice so that constructing the node does
 	// not allocate a separate input list; [Parents] hands out a slice over it.
 	parents [1]INode
@@ -230,8 +236,23 @@ func (b *bindLeftChangeIncr[A, B]) RightScopeNodes() []INode {
 }
 
 func (b *bindLeftChangeIncr[A, B]) Stabilize(ctx context.Context) (err error) {
-	oldRightNodes := b.bind.rhsNodes
-	oldRhs := b.bind.rhs
+	if err = b.buildRightHandSide(ctx); err != nil {
+		return
+	}
+	if err = b.linkRightHandSide(ctx); err != nil {
+		return
+	}
+	GraphForNode(b).checkInvariantsAfterBindRebuild(b)
+	return
+}
+
+// buildRightHandSide calls the bind function to build the new right-hand side. It only
+// touches the bind's own scope -- its node list, its slab, the nodes the function
+// creates in it -- so on the parallel path it runs alongside other nodes, other binds
+// included. Nothing is linked into the graph until linkRightHandSide.
+func (b *bindLeftChangeIncr[A, B]) buildRightHandSide(ctx context.Context) (err error) {
+	b.oldRightNodes = b.bind.rhsNodes
+	b.oldRhs = b.bind.rhs
 	// take the buffer from the rebuild before last; oldRightNodes is still needed
 	// below, so the two alternate rather than being reused immediately.
 	b.bind.rhsNodes = b.bind.rhsNodesSpare[:0]
@@ -243,10 +264,21 @@ func (b *bindLeftChangeIncr[A, B]) Stabilize(ctx context.Context) (err error) {
 	// until the swap below completes, which is why two of them alternate.
 	b.bind.nodeSlab, b.bind.nodeSlabSpare = b.bind.nodeSlabSpare, b.bind.nodeSlab
 	b.bind.nodeSlab.reset()
-	b.bind.rhs, err = b.bind.fn(ctx, b.bind, b.bind.lhs.Value())
-	if err != nil {
-		return
+	// the bind's timeout is set on its main node, which is the one callers hold
+	ctx, cancel := b.bind.main.Node().withTimeout(ctx)
+	if cancel != nil {
+		defer cancel()
 	}
+	b.bind.rhs, err = b.bind.fn(ctx, b.bind, b.bind.lhs.Value())
+	return
+}
+
+// linkRightHandSide swaps the right-hand side built by buildRightHandSide into the
+// graph, and tears down the one it replaces. This is the part that changes shared
+// structure: parents' child lists, heights, the recompute heap.
+func (b *bindLeftChangeIncr[A, B]) linkRightHandSide(_ context.Context) error {
+	oldRightNodes, oldRhs := b.oldRightNodes, b.oldRhs
+	b.oldRightNodes, b.oldRhs = nil, nil
 
 	main := b.bind.main
 	main.parentsArray[0] = b
@@ -258,7 +290,7 @@ func (b *bindLeftChangeIncr[A, B]) Stabilize(ctx context.Context) (err error) {
 		main.parents = main.parentsArray[:1]
 	}
 
-	if err = GraphForNode(b).changeParent(b.bind.main, oldRhs, b.bind.rhs); err != nil {
+	if err := GraphForNode(b).changeParent(b.bind.main, oldRhs, b.bind.rhs); err != nil {
 		return err
 	}
 	if oldRhs != nil {
@@ -282,6 +314,8 @@ func (b *bindLeftChangeIncr[A, B]) Stabilize(ctx context.Context) (err error) {
 	return nil
 }
 
+func (b *bindLeftChangeIncr[A, B]) bindMain() INode { return b.bind.main }
+
 func (b *bindLeftChangeIncr[A, B]) String() string {
 	return b.n.String()
 }
@@ -0,0 +1,322 @@
+package incr
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// Bridge returns a node in one graph that follows an observer in another.
+//
+// Splitting a computation across graphs lets the parts stabilize on their own cadence
+// and belong to different owners, but each graph only knows its own nodes, so the
+// value has to be carried across by hand: an update handler on the observer that sets
+// a var in the other graph. That is most of what this does. The difference is in the
+// edges of that arrangement, which are easy to get wrong by hand: a var set from the
+// source's goroutine while the destination is stabilizing, a handler that keeps
+// setting a var nobody reads, or two graphs that end up feeding each other.
+//
+// The source's value is carried over after each stabilization of the source in which
+// it changed, so a cutoff in the source graph cuts off the bridge too: if the observed
+// node does not change, neither does the bridge. The new value is picked up by the
+// next stabilization of the destination, which the program has to run, as it would
+// after setting a var; the bridge never stabilizes the destination itself. Values the
+// source goes through between two stabilizations of the destination are not seen,
+// only the latest.
+//
+// The bridge only forwards while it is necessary in the destination. Once it stops
+// being observed there, the source observer lets go of it, and the latest value is
+// picked up if it is observed again. Once the source observer is unobserved the bridge
+// stops following it for good, and keeps the last value it was given. A bridge still
+// observed in its destination is held by the source observer, and with it the
+// destination graph, so a destination that is done with should unobserve its bridges.
+//
+// Bridges are tracked between graphs rather than between nodes, so a bridge whose
+// destination already feeds its source, through one bridge or several, fails with a
+// [*BridgeCycleError] when the destination stabilizes -- even if the nodes on either
+// side have nothing to do with one another, since a change could still travel round
+// the graphs indefinitely. That includes bridging a graph into itself.
+//
+// The source's current value is read when the bridge is created, so the bridge should
+// be created while the source graph is not stabilizing.
+func Bridge[A any](src ObserveIncr[A], dst *Graph) Incr[A] {
+	source := bridgeSourceFor(src)
+	b := &bridgeIncr[A]{
+		n:       dst.newNode(KindBridge),
+		src:     GraphForNode(src),
+		dst:     dst,
+		source:  source,
+		pending: src.Value(),
+		version: source.currentVersion(),
+		changed: true,
+	}
+	b.n.OnBecameNecessary(b.activate)
+	b.n.OnBecameUnnecessary(b.deactivate)
+	return WithinScope(dst, b)
+}
+
+// bridgeSource is the one update handler a source observer has for all the bridges
+// following it, which join it while they are necessary in their destinations and leave
+// it when they stop being, so that the observer only holds the bridges in use.
+type bridgeSource[A any] struct {
+	mu sync.Mutex
+	// latest is the observer's value as of its last update, which is version.
+	latest  A
+	version uint64
+	bridges map[*bridgeIncr[A]]struct{}
+	// detached is whether the observer has been unobserved.
+	detached bool
+}
+
+// bridgeSourceFor returns the source for an observer's bridges, registering it with the
+// observer the first time.
+func bridgeSourceFor[A any](src ObserveIncr[A]) *bridgeSource[A] {
+	e := src.Node().extra()
+	if source, ok := e.bridgeSource.(*bridgeSource[A]); ok {
+		return source
+	}
+	source := &bridgeSource[A]{bridges: make(map[*bridgeIncr[A]]struct{})}
+	e.bridgeSource = source
+	src.OnUpdate(source.forward)
+	src.Node().onUnobserved(source.detach)
+	return source
+}
+
+func (s *bridgeSource[A]) currentVersion() uint64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.version
+}
+
+// forward is the source observer's update handler, and runs on whichever goroutine
+// stabilized the source.
+func (s *bridgeSource[A]) forward(_ context.Context, v A) {
+	s.mu.Lock()
+	s.latest = v
+	s.version++
+	version := s.version
+	bridges := make([]*bridgeIncr[A], 0, len(s.bridges))
+	for b := range s.bridges {
+		bridges = append(bridges, b)
+	}
+	s.mu.Unlock()
+	for _, b := range bridges {
+		b.receive(v, version)
+	}
+}
+
+// join adds a bridge to those forwarded to, and returns the latest value and its
+// version, unless the observer has been unobserved.
+func (s *bridgeSource[A]) join(b *bridgeIncr[A]) (latest A, version uint64, ok bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.detached {
+		return
+	}
+	s.bridges[b] = struct{}{}
+	return s.latest, s.version, true
+}
+
+func (s *bridgeSource[A]) leave(b *bridgeIncr[A]) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.bridges, b)
+}
+
+// detach stops the bridges following the source once the source observer is
+// unobserved, which happens on the source's goroutine.
+func (s *bridgeSource[A]) detach() {
+	s.mu.Lock()
+	s.detached = true
+	bridges := s.bridges
+	s.bridges = nil
+	s.mu.Unlock()
+	for b := range bridges {
+		b.detach()
+	}
+}
+
+var (
+	_ Incr[string] = (*bridgeIncr[string])(nil)
+	_ IStabilize   = (*bridgeIncr[string])(nil)
+	_ IStale       = (*bridgeIncr[string])(nil)
+	_ fmt.Stringer = (*bridgeIncr[string])(nil)
+)
+
+type bridgeIncr[A any] struct {
+	n      *Node
+	src    *Graph
+	dst    *Graph
+	source *bridgeSource[A]
+	value  A
+
+	// mu guards what the source's goroutine shares with the destination's.
+	mu      sync.Mutex
+	pending A
+	// version is the source's version of pending, so that an update delivered after a
+	// newer value was picked up on joining is not taken for a change.
+	version uint64
+	// changed is whether pending has yet to be picked up by the destination.
+	changed bool
+	// notified is whether the destination has been told about pending, so that a
+	// source stabilizing many times in between tells it once.
+	notified bool
+	// active is whether the bridge is necessary in the destination, joined to its
+	// source and recorded as joining the two graphs, which is when updates are
+	// forwarded.
+	active bool
+	// err is the cycle found when the bridge last became necessary, if any.
+	err error
+}
+
+func (b *bridgeIncr[A]) Node() *Node { return b.n }
+
+func (b *bridgeIncr[A]) Value() A { return b.value }
+
+func (b *bridgeIncr[A]) Stale() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.changed || b.err != nil
+}
+
+func (b *bridgeIncr[A]) Stabilize(_ context.Context) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.err != nil {
+		return b.err
+	}
+	b.value = b.pending
+	b.changed = false
+	b.notified = false
+	return nil
+}
+
+// receive takes an update from the source, on the source's goroutine.
+func (b *bridgeIncr[A]) receive(v A, version uint64) {
+	b.mu.Lock()
+	if !b.active || version <= b.version {
+		b.mu.Unlock()
+		return
+	}
+	b.pending = v
+	b.version = version
+	b.changed = true
+	notify := !b.notified
+	b.notified = true
+	b.mu.Unlock()
+	if notify {
+		b.dst.asyncArrived(b)
+	}
+}
+
+// activate joins the bridge to its source and records it as joining its graphs when it
+// becomes necessary in the destination. Whatever the source did while it was not is
+// picked up as it is recomputed, since a node becoming necessary is recomputed if it
+// is stale.
+func (b *bridgeIncr[A]) activate() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.notified = false
+	if b.active {
+		return
+	}
+	latest, version, ok := b.source.join(b)
+	if !ok {
+		return
+	}
+	if path := linkBridge(b.src, b.dst); path != nil {
+		b.source.leave(b)
+		b.err = &BridgeCycleError{Source: b.src, Destination: b.dst, Path: path}
+		return
+	}
+	if version > b.version {
+		b.pending = latest
+		b.version = version
+		b.changed = true
+	}
+	b.err = nil
+	b.active = true
+}
+
+func (b *bridgeIncr[A]) deactivate() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.err = nil
+	b.release()
+}
+
+func (b *bridgeIncr[A]) detach() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.release()
+}
+
+// release leaves the source and removes the record of the bridge joining its graphs;
+// it must be called with mu held.
+func (b *bridgeIncr[A]) release() {
+	if b.active {
+		b.source.leave(b)
+		unlinkBridge(b.src, b.dst)
+		b.active = false
+	}
+}
+
+func (b *bridgeIncr[A]) String() string { return b.n.String() }
+
+// bridgeLinkMu serializes adding and removing bridges between graphs, so that two
+// bridges linked at once in opposite directions cannot each miss the other. The edges
+// themselves are kept on the graphs they leave from.
+var bridgeLinkMu sync.Mutex
+
+// linkBridge records a bridge from src into dst, unless dst already feeds src, in which
+// case it returns the way there for [BridgeCycleError.Path] instead.
+func linkBridge(src, dst *Graph) []*Graph {
+	bridgeLinkMu.Lock()
+	defer bridgeLinkMu.Unlock()
+	if path := bridgePath(dst, src, make(map[*Graph]struct{})); path != nil {
+		return path
+	}
+	src.bridgesMu.Lock()
+	if src.bridgesTo == nil {
+		src.bridgesTo = make(map[*Graph]int)
+	}
+	src.bridgesTo[dst]++
+	src.bridgesMu.Unlock()
+	return nil
+}
+
+func unlinkBridge(src, dst *Graph) {
+	bridgeLinkMu.Lock()
+	defer bridgeLinkMu.Unlock()
+	src.bridgesMu.Lock()
+	defer src.bridgesMu.Unlock()
+	src.bridgesTo[dst]--
+	if src.bridgesTo[dst] == 0 {
+		delete(src.bridgesTo, dst)
+	}
+}
+
+// bridgePath returns the graphs from one graph to another along the active bridges,
+// both included, or nil if there is no way there. It must be called with bridgeLinkMu
+// held.
+func bridgePath(from, to *Graph, seen map[*Graph]struct{}) []*Graph {
+	if from == to {
+		return []*Graph{from}
+	}
+	if _, ok := seen[from]; ok {
+		return nil
+	}
+	seen[from] = struct{}{}
+	from.bridgesMu.Lock()
+	next := make([]*Graph, 0, len(from.bridgesTo))
+	for g := range from.bridgesTo {
+		next = append(next, g)
+	}
+	from.bridgesMu.Unlock()
+	for _, g := range next {
+		if path := bridgePath(g, to, seen); path != nil {
+			return append([]*Graph{from}, path...)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,236 @@
+package incr
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/wcharczuk/go-incr/testutil"
+)
+
+func Test_Bridge(t *testing.T) {
+	ctx := testContext()
+	src := New()
+	v := Var(src, 2)
+	so := MustObserve(src, Map(src, v, func(x int) int { return x * 10 }))
+	testutil.NoError(t, src.Stabilize(ctx))
+
+	dst := New()
+	b := Bridge(so, dst)
+	testutil.Equal(t, KindBridge, b.Node().Kind())
+	do := MustObserve(dst, Map(dst, b, func(x int) int { return x + 1 }))
+
+	testutil.NoError(t, dst.Stabilize(ctx))
+	testutil.Equal(t, 21, do.Value())
+
+	v.Set(3)
+	testutil.NoError(t, src.Stabilize(ctx))
+	testutil.Equal(t, 21, do.Value(), "the destination only picks the value up as it stabilizes")
+
+	testutil.NoError(t, dst.Stabilize(ctx))
+	testutil.Equal(t, 31, do.Value())
+
+	// only the latest value between two stabilizations of the destination is seen
+	v.Set(4)
+	testutil.NoError(t, src.Stabilize(ctx))
+	v.Set(5)
+	testutil.NoError(t, src.Stabilize(ctx))
+	testutil.Equal(t, 1, len(dst.asyncArrivals))
+	testutil.NoError(t, dst.Stabilize(ctx))
+	testutil.Equal(t, 51, do.Value())
+}
+
+func Test_Bridge_cutoff(t *testing.T) {
+	ctx := testContext()
+	src := New()
+	v := Var(src, 10)
+	c := Cutoff(src, v, func(prev, next int) bool { return next-prev < 5 && prev-next < 5 })
+	so := MustObserve(src, c)
+	testutil.NoError(t, src.Stabilize(ctx))
+
+	dst := New()
+	var recomputes int
+	do := MustObserve(dst, Map(dst, Bridge(so, dst), func(x int) int {
+		recomputes++
+		return x
+	}))
+	testutil.NoError(t, dst.Stabilize(ctx))
+	testutil.Equal(t, 10, do.Value())
+	testutil.Equal(t, 1, recomputes)
+
+	v.Set(12)
+	testutil.NoError(t, src.Stabilize(ctx))
+	testutil.Equal(t, 0, len(dst.asyncArrivals))
+	testutil.NoError(t, dst.Stabilize(ctx))
+	testutil.Equal(t, 10, do.Value())
+	testutil.Equal(t, 1, recomputes)
+
+	v.Set(20)
+	testutil.NoError(t, src.Stabilize(ctx))
+	testutil.NoError(t, dst.Stabilize(ctx))
+	testutil.Equal(t, 20, do.Value())
+	testutil.Equal(t, 2, recomputes)
+}
+
+func Test_Bridge_destinationUnobserved(t *testing.T) {
+	ctx := testContext()
+	src := New()
+	v := Var(src, 1)
+	so := MustObserve(src, v)
+	testutil.NoError(t, src.Stabilize(ctx))
+
+	dst := New()
+	b := Bridge(so, dst)
+	do := MustObserve(dst, b)
+	testutil.NoError(t, dst.Stabilize(ctx))
+	testutil.Equal(t, 1, do.Value())
+
+	do.Unobserve(ctx)
+	testutil.Equal(t, 0, len(src.bridgesTo))
+
+	// updates are held rather than forwarded while nothing in the destination wants them
+	v.Set(2)
+	testutil.NoError(t, src.Stabilize(ctx))
+	testutil.Equal(t, 0, len(dst.asyncArrivals))
+
+	do = MustObserve(dst, b)
+	testutil.NoError(t, dst.Stabilize(ctx))
+	testutil.Equal(t, 2, do.Value())
+
+	v.Set(3)
+	testutil.NoError(t, src.Stabilize(ctx))
+	testutil.NoError(t, dst.Stabilize(ctx))
+	testutil.Equal(t, 3, do.Value())
+}
+
+func Test_Bridge_dropped(t *testing.T) {
+	ctx := testContext()
+	src := New()
+	v := Var(src, 0)
+	so := MustObserve(src, v)
+	testutil.NoError(t, src.Stabilize(ctx))
+	source := bridgeSourceFor(so)
+
+	// bridges made and thrown away do not pile up on the source observer
+	for x := range 10 {
+		dst := New()
+		do := MustObserve(dst, Bridge(so, dst))
+		testutil.NoError(t, dst.Stabilize(ctx))
+		testutil.Equal(t, x, do.Value())
+		testutil.Equal(t, 1, len(source.bridges))
+		do.Unobserve(ctx)
+
+		v.Set(x + 1)
+		testutil.NoError(t, src.Stabilize(ctx))
+	}
+	testutil.Equal(t, 0, len(source.bridges))
+	testutil.Equal(t, 1, len(so.Node().updateHandlers()))
+}
+
+func Test_Bridge_sourceUnobserved(t *testing.T) {
+	ctx := testContext()
+	src := New()
+	v := Var(src, 1)
+	so := MustObserve(src, v)
+	testutil.NoError(t, src.Stabilize(ctx))
+
+	dst := New()
+	do := MustObserve(dst, Bridge(so, dst))
+	testutil.NoError(t, dst.Stabilize(ctx))
+	testutil.Equal(t, 1, len(src.bridgesTo))
+
+	// the bridge's hook on the observer is its own, and leaves the observer's lifecycle
+	// handlers as they were
+	var unnecessary int
+	so.Node().OnBecameUnnecessary(func() { unnecessary++ })
+	so.Unobserve(ctx)
+	testutil.Equal(t, 0, len(src.bridgesTo))
+	testutil.Equal(t, 0, unnecessary)
+	testutil.Nil(t, so.Node().takeUnobservedHandlers())
+
+	_ = MustObserve(src, v)
+	v.Set(2)
+	testutil.NoError(t, src.Stabilize(ctx))
+	testutil.Equal(t, 0, len(dst.asyncArrivals))
+	testutil.NoError(t, dst.Stabilize(ctx))
+	testutil.Equal(t, 1, do.Value(), "the bridge keeps the last value it was given")
+}
+
+func Test_Bridge_cycle(t *testing.T) {
+	ctx := testContext()
+	g0 := New()
+	g1 := New()
+	g2 := New()
+
+	v0 := Var(g0, 1)
+	o0 := MustObserve(g0, v0)
+	o01 := MustObserve(g1, Bridge(o0, g1))
+	o12 := MustObserve(g2, Bridge(o01, g2))
+	testutil.NoError(t, g0.Stabilize(ctx))
+	testutil.NoError(t, g1.Stabilize(ctx))
+	testutil.NoError(t, g2.Stabilize(ctx))
+	testutil.Equal(t, 1, o12.Value())
+
+	back := Bridge(o12, g0)
+	o20 := MustObserve(g0, back)
+	err := g0.Stabilize(ctx)
+	var cycleErr *BridgeCycleError
+	testutil.Equal(t, true, errors.As(err, &cycleErr))
+	testutil.Equal(t, g2, cycleErr.Source)
+	testutil.Equal(t, g0, cycleErr.Destination)
+	testutil.Equal(t, []*Graph{g0, g1, g2}, cycleErr.Path)
+	testutil.Matches(t, `^incr; bridging \{graph:\S+\} into \{graph:\S+\} would cause a cycle: (\{graph:\S+\} -> ){3}\{graph:\S+\}$`, err.Error())
+	testutil.Equal(t, 0, len(g2.bridgesTo))
+
+	// taking a bridge on the way round out of use breaks the cycle
+	o01.Unobserve(ctx)
+	o20.Unobserve(ctx)
+	o20 = MustObserve(g0, back)
+	testutil.NoError(t, g0.Stabilize(ctx))
+	testutil.Equal(t, 1, o20.Value())
+}
+
+func Test_Bridge_self(t *testing.T) {
+	ctx := testContext()
+	g := New()
+	o := MustObserve(g, Var(g, 1))
+	_ = MustObserve(g, Bridge(o, g))
+	err := g.Stabilize(ctx)
+	var cycleErr *BridgeCycleError
+	testutil.Equal(t, true, errors.As(err, &cycleErr))
+	testutil.Equal(t, []*Graph{g}, cycleErr.Path)
+}
+
+func Test_Bridge_concurrent(t *testing.T) {
+	ctx := testContext()
+	src := New()
+	v := Var(src, 0)
+	so := MustObserve(src, v)
+	testutil.NoError(t, src.Stabilize(ctx))
+
+	dst := New()
+	do := MustObserve(dst, Bridge(so, dst))
+
+	const rounds = 200
+	var wg sync.WaitGroup
+	wg.Go(func() {
+		for x := 1; x <= rounds; x++ {
+			v.Set(x)
+			_ = src.Stabilize(ctx)
+		}
+	})
+	wg.Go(func() {
+		previous := 0
+		for range rounds {
+			_ = dst.ParallelStabilize(ctx)
+			if do.Value() < previous {
+				t.Errorf("bridge went backwards from %d to %d", previous, do.Value())
+			}
+			previous = do.Value()
+		}
+	})
+	wg.Wait()
+	testutil.NoError(t, dst.Stabilize(context.Background()))
+	testutil.Equal(t, rounds, do.Value())
+}
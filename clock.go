@@ -3,7 +3,9 @@ package incr
 import (
 	"context"
 	"fmt"
+	"slices"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -23,6 +25,8 @@ type Clock struct {
 	mu      sync.Mutex
 	now     time.Time
 	entries []*clockEntry
+	// graphs are the graphs with nodes registered, which record the clock's advances.
+	graphs []*Graph
 }
 
 // clockEntry is a node's registration with the clock.
@@ -57,6 +61,11 @@ func (c *Clock) Advance(to time.Time) {
 		return
 	}
 	c.now = to
+	for _, graph := range c.graphs {
+		if graph.recorder != nil && atomic.LoadInt32(&graph.status) == StatusNotStabilizing {
+			graph.recorder.recordAdvance(graph, c, to)
+		}
+	}
 	// Registrations are scanned rather than kept in a heap: a graph has few
 	// time-dependent nodes next to its total size, and a scan keeps re-arming a
 	// repeating node to a single field write.
@@ -71,7 +80,7 @@ func (c *Clock) Advance(to time.Time) {
 	// outside the lock, since marking a node stale reaches into the graph
 	for _, node := range due {
 		if graph := GraphForNode(node); graph != nil {
-			graph.SetStale(node)
+			graph.setStale(node)
 		}
 	}
 }
@@ -82,13 +91,29 @@ func (c *Clock) AdvanceBy(d time.Duration) { c.Advance(c.Now().Add(d)) }
 // register adds a node to be woken at a time, returning its entry so the node can
 // re-arm itself.
 func (c *Clock) register(node INode, at time.Time) *clockEntry {
+	graph := GraphForNode(node)
+	if graph != nil {
+		graph.addClock(c)
+	}
 	c.mu.Lock()
 	defer c.mu.Unlock()
 	entry := &clockEntry{node: node, at: at}
 	c.entries = append(c.entries, entry)
+	if graph != nil && !slices.Contains(c.graphs, graph) {
+		c.graphs = append(c.graphs, graph)
+	}
 	return entry
 }
 
+// addClock notes a clock a node in the graph registered with.
+func (graph *Graph) addClock(c *Clock) {
+	graph.clocksMu.Lock()
+	defer graph.clocksMu.Unlock()
+	if !slices.Contains(graph.clocks, c) {
+		graph.clocks = append(graph.clocks, c)
+	}
+}
+
 // rearm sets when an entry should next fire, or clears it with a zero time.
 func (c *Clock) rearm(entry *clockEntry, at time.Time) {
 	c.mu.Lock()
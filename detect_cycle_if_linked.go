@@ -1,9 +1,8 @@
 package incr
 
-import "fmt"
-
 // DetectCycleIfLinked determines if adding a given input to a given
-// child would cause a graph cycle.
+// child would cause a graph cycle, returning a [*CycleError] with the
+// path of the cycle if it would.
 //
 // It is a low-level utility function that should be used in special cases; the vast
 // majority of situations outside very esoteric [Bind] use cases cannot create cycles.
@@ -23,20 +22,22 @@ func DetectCycleIfLinked(child, parent INode) error {
 		}
 		return getParents(n)
 	}
-	if detectCycleFast(child.Node().ID(), parent /*startAt*/, getParentsWithPossibleParent) {
-		return fmt.Errorf("adding %v as child of %v would cause a cycle", child, parent)
+	if path := detectCycleFast(child.Node().ID(), parent /*startAt*/, getParentsWithPossibleParent); path != nil {
+		return &CycleError{Child: child, Parent: parent, Path: path}
 	}
 	return nil
 }
 
-func detectCycleFast(childID Identifier, startAt INode, getParents func(INode) []INode) bool {
+// detectCycleFast returns the path from startAt through its inputs to the node with a
+// given identifier, or nil if there is none.
+func detectCycleFast(childID Identifier, startAt INode, getParents func(INode) []INode) []INode {
 	if startAt.Node().ID() == childID {
-		return true
+		return []INode{startAt}
 	}
 	for _, p := range getParents(startAt) {
-		if detectCycleFast(childID, p, getParents) {
-			return true
+		if path := detectCycleFast(childID, p, getParents); path != nil {
+			return append([]INode{startAt}, path...)
 		}
 	}
-	return false
+	return nil
 }
@@ -119,6 +119,12 @@ serial stabilization checks every 64 nodes, since checking every node would cost
 measurable share of a cheap node's recompute, and parallel stabilization checks once per
 height block.
 
+The error returned for a failed node is a [NodeError], which carries the node's
+identifier, kind and label and the stabilization it failed in, and wraps what the node
+returned, so [errors.Is] and [errors.As] match the cause. A cycle introduced by a bind is a
+[CycleError] with the path of the cycle, and a node placed above the graph's maximum height
+a [HeightExceededError].
+
 A panic in a node's computation is reported the same way, as a [PanicError] carrying the
 panic value, the stack, and the node responsible. This is not only for convenience: under
 [Graph.ParallelStabilize] nodes are recomputed in worker goroutines, where a panic cannot
@@ -1,8 +1,111 @@
 package incr
 
-import "errors"
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
 
 var (
 	// ErrAlreadyStabilizing is returned if you're already stabilizing a graph.
 	ErrAlreadyStabilizing = errors.New("stabilize; already stabilizing, cannot continue")
 )
+
+// NodeError is returned by a stabilization when a node failed, wrapping what the node
+// returned -- or a [*PanicError], or a [*CycleError] from a bind linking its right-hand
+// side -- with which node it was and when.
+//
+// Its message is the cause's, unchanged, so that wrapping does not change what callers
+// already log or match on; [errors.Is] and [errors.As] see through it to the cause. The
+// node's error handlers are given the cause itself, since they already know the node.
+type NodeError struct {
+	// Node is the identifier of the node that failed.
+	Node Identifier
+	// Kind and Label are the node's kind and label, as [Node.Kind] and [Node.Label]
+	// report them.
+	Kind  string
+	Label string
+	// StabilizationNum is the stabilization the node failed in.
+	StabilizationNum uint64
+	// Err is what the node failed with.
+	Err error
+}
+
+// Error implements error.
+func (ne *NodeError) Error() string {
+	return ne.Err.Error()
+}
+
+// Unwrap returns the cause.
+func (ne *NodeError) Unwrap() error {
+	return ne.Err
+}
+
+func (graph *Graph) newNodeError(n INode, err error) *NodeError {
+	nn := n.Node()
+	return &NodeError{
+		Node:             nn.id,
+		Kind:             nn.kind,
+		Label:            nn.Label(),
+		StabilizationNum: graph.stabilizationNum,
+		Err:              err,
+	}
+}
+
+// CycleError is returned when adding an edge would make a node depend on itself, by
+// [DetectCycleIfLinked], and from a stabilization when a bind returns a node that
+// depends on the bind.
+type CycleError struct {
+	// Child and Parent are the nodes of the edge: Child would take Parent as an input.
+	Child, Parent INode
+	// Path is the cycle the edge would close, from Parent to Child, each node taking the
+	// next as an input; the edge makes Child take Parent as one and brings it back round.
+	Path []INode
+}
+
+// Error implements error.
+func (ce *CycleError) Error() string {
+	path := make([]string, len(ce.Path))
+	for index, n := range ce.Path {
+		path[index] = fmt.Sprint(n)
+	}
+	return fmt.Sprintf("incr; adding %v as child of %v would cause a cycle: %s", ce.Child, ce.Parent, strings.Join(path, " -> "))
+}
+
+// HeightExceededError is returned when a node would have to sit higher in the graph
+// than the graph's maximum height, set with [OptGraphMaxHeight], allows.
+type HeightExceededError struct {
+	// Node is the node that would have been placed too high.
+	Node INode
+	// Height is the height it would have needed.
+	Height int
+	// MaxHeight is the highest the graph allows.
+	MaxHeight int
+}
+
+// Error implements error.
+func (he *HeightExceededError) Error() string {
+	return fmt.Sprintf("incr; cannot set %v to height %d, above the maximum height %d", he.Node, he.Height, he.MaxHeight)
+}
+
+// BridgeCycleError is returned by a [Bridge] whose graphs already feed one another the
+// other way, so that changes would travel round the graphs indefinitely.
+type BridgeCycleError struct {
+	// Source and Destination are the graphs the bridge would have joined.
+	Source      *Graph
+	Destination *Graph
+	// Path is the way back from the destination to the source along the bridges already
+	// in place, each graph feeding the next, starting with the destination and ending
+	// with the source.
+	Path []*Graph
+}
+
+// Error implements error.
+func (be *BridgeCycleError) Error() string {
+	path := make([]string, 0, len(be.Path)+1)
+	for _, g := range be.Path {
+		path = append(path, g.String())
+	}
+	path = append(path, be.Destination.String())
+	return fmt.Sprintf("incr; bridging %v into %v would cause a cycle: %s", be.Source, be.Destination, strings.Join(path, " -> "))
+}
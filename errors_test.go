@@ -0,0 +1,134 @@
+package incr
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/wcharczuk/go-incr/testutil"
+)
+
+func Test_NodeError(t *testing.T) {
+	for name, stabilize := range map[string]func(*Graph, context.Context) error{
+		"serial":   (*Graph).Stabilize,
+		"parallel": (*Graph).ParallelStabilize,
+	} {
+		for _, deterministic := range []bool{false, true} {
+			t.Run(fmt.Sprintf("%s deterministic=%v", name, deterministic), func(t *testing.T) {
+				ctx := testContext()
+				g := New(OptGraphDeterministic(deterministic))
+
+				cause := fmt.Errorf("this is only a test")
+				f := Func(g, func(_ context.Context) (string, error) {
+					return "", cause
+				})
+				f.Node().SetLabel("fails")
+				var handled error
+				f.Node().OnError(func(_ context.Context, err error) {
+					handled = err
+				})
+				_ = MustObserve(g, f)
+
+				err := stabilize(g, ctx)
+				testutil.Equal(t, "this is only a test", err.Error(), "the message is the cause's")
+				testutil.Equal(t, true, errors.Is(err, cause))
+
+				var nodeErr *NodeError
+				testutil.Equal(t, true, errors.As(err, &nodeErr))
+				testutil.Equal(t, f.Node().ID(), nodeErr.Node)
+				testutil.Equal(t, KindFunc, nodeErr.Kind)
+				testutil.Equal(t, "fails", nodeErr.Label)
+				testutil.Equal(t, uint64(1), nodeErr.StabilizationNum)
+				testutil.Equal(t, true, handled == cause, "handlers are given the cause itself")
+			})
+		}
+	}
+}
+
+func Test_NodeError_panic(t *testing.T) {
+	ctx := testContext()
+	g := New()
+	v := Var(g, 1)
+	m := Map(g, v, func(int) int { panic("only a test") })
+	_ = MustObserve(g, m)
+
+	err := g.Stabilize(ctx)
+	var nodeErr *NodeError
+	testutil.Equal(t, true, errors.As(err, &nodeErr))
+	testutil.Equal(t, m.Node().ID(), nodeErr.Node)
+	var panicErr *PanicError
+	testutil.Equal(t, true, errors.As(err, &panicErr))
+	testutil.Equal(t, m.Node().ID(), panicErr.NodeID)
+	testutil.Equal(t, "only a test", panicErr.Value)
+}
+
+func Test_CycleError_detectCycleIfLinked(t *testing.T) {
+	g := New()
+	n0 := MapN[any](g, identMany)
+	n1 := MapN[any](g, identMany)
+	n2 := MapN[any](g, identMany)
+	testutil.NoError(t, n1.AddInput(n0))
+	testutil.NoError(t, n2.AddInput(n1))
+
+	err := DetectCycleIfLinked(n0, n2)
+	var cycleErr *CycleError
+	testutil.Equal(t, true, errors.As(err, &cycleErr))
+	testutil.Equal(t, n0.Node().ID(), cycleErr.Child.Node().ID())
+	testutil.Equal(t, n2.Node().ID(), cycleErr.Parent.Node().ID())
+	testutil.Equal(t, []Identifier{n2.Node().ID(), n1.Node().ID(), n0.Node().ID()}, nodeIDs(cycleErr.Path))
+	testutil.Matches(t, `would cause a cycle: map_n\[\w+\]\S* -> map_n\[\w+\]\S* -> map_n\[\w+\]\S*$`, err.Error())
+}
+
+func Test_CycleError_bind(t *testing.T) {
+	ctx := testContext()
+	g := New()
+
+	var b1 BindIncr[string]
+	b0v := Var(g, "a")
+	b0 := Bind(g, b0v, func(bs Scope, which string) Incr[string] {
+		if which == "a" {
+			return Return(bs, "foo")
+		}
+		return b1
+	})
+	b1v := Var(g, "a")
+	b1 = Bind(g, b1v, func(bs Scope, which string) Incr[string] {
+		return b0
+	})
+	_ = MustObserve(g, b1)
+	testutil.NoError(t, g.Stabilize(ctx))
+
+	b0v.Set("b")
+	err := g.Stabilize(ctx)
+
+	var cycleErr *CycleError
+	testutil.Equal(t, true, errors.As(err, &cycleErr))
+	testutil.Equal(t, b0.Node().ID(), cycleErr.Child.Node().ID())
+	testutil.Equal(t, b1.Node().ID(), cycleErr.Parent.Node().ID())
+	testutil.Equal(t, []Identifier{b1.Node().ID(), b0.Node().ID()}, nodeIDs(cycleErr.Path))
+	var nodeErr *NodeError
+	testutil.Equal(t, true, errors.As(err, &nodeErr), "the bind that linked it failed")
+}
+
+func Test_HeightExceededError(t *testing.T) {
+	g := New(OptGraphMaxHeight(4))
+	var n Incr[int] = Var(g, 1)
+	for range 4 {
+		n = Map(g, n, func(x int) int { return x })
+	}
+	_, err := Observe(g, n)
+
+	var heightErr *HeightExceededError
+	testutil.Equal(t, true, errors.As(err, &heightErr))
+	testutil.Equal(t, 3, heightErr.MaxHeight)
+	testutil.Equal(t, 4, heightErr.Height)
+	testutil.NotNil(t, heightErr.Node)
+}
+
+func nodeIDs(nodes []INode) (ids []Identifier) {
+	for _, n := range nodes {
+		ids = append(ids, n.Node().ID())
+	}
+	return
+}
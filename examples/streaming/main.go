@@ -8,6 +8,8 @@ import (
 	"time"
 
 	"github.com/wcharczuk/go-incr"
+	"github.com/wcharczuk/go-incr/incrutil/driver"
+	"github.com/wcharczuk/go-incr/incrutil/source"
 )
 
 func main() {
@@ -53,11 +55,11 @@ func main() {
 		}
 	})
 
-	for {
-		value := <-values
-		currentValue.Set(value)
-		_ = g.Stabilize(ctx)
-	}
+	// the driver owns the graph from here on; the source hands it each value, and
+	// values that arrive while a stabilization is running coalesce into the next one
+	d := driver.New(g)
+	source.Chan(ctx, d, values, source.Latest(currentValue))
+	_ = d.Run(ctx)
 }
 
 func countUpdates[T any](scope incr.Scope, input incr.Incr[T]) incr.Incr[uint64] {
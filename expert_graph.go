@@ -99,7 +99,9 @@ type IExpertGraph interface {
 	// node from a stale input rather than erroring.
 	//
 	// It walks the whole graph, so it belongs in tests and in assembly paths rather
-	// than in a stabilization loop.
+	// than in a stabilization loop; [OptGraphCheckInvariants] has the graph run it
+	// itself where a test or canary wants it there anyway. Each problem in the error
+	// returned is an [*InvariantViolation], for [errors.As].
 	CheckInvariants() error
 
 	// ClearRecomputeHeapOnError is a setting that corresponds to [GraphOptions.ClearRecomputeHeapOnError].
@@ -18,7 +18,31 @@ import (
 // Every problem found is reported, not just the first, since one bad assembly step tends
 // to produce several.
 func (graph *Graph) checkInvariants() error {
-	var problems []error
+	violations := graph.invariantViolations()
+	problems := make([]error, len(violations))
+	for index := range violations {
+		problems[index] = &violations[index]
+	}
+	return errors.Join(problems...)
+}
+
+// invariantViolations is [checkInvariants] as a list of what each problem is and where.
+func (graph *Graph) invariantViolations() (violations []InvariantViolation) {
+	violate := func(invariant Invariant, node, other INode, format string, args ...any) {
+		v := InvariantViolation{
+			Invariant: invariant,
+			Message:   fmt.Sprintf(format, args...),
+		}
+		if node != nil {
+			nn := node.Node()
+			v.Node, v.Kind, v.Height = nn.id, nn.kind, nn.height
+			v.Scope = scopeChain(nn)
+		}
+		if other != nil {
+			v.Other = other.Node().id
+		}
+		violations = append(violations, v)
+	}
 
 	graph.nodesMu.Lock()
 	nodes := make([]INode, 0, len(graph.nodes))
@@ -34,18 +58,18 @@ func (graph *Graph) checkInvariants() error {
 		// The delegates consulted while invalidating are asserted from self at the point of
 		// use rather than cached, so a node in the graph with a nil self silently skips them.
 		if nn.self == nil {
-			problems = append(problems, fmt.Errorf(
-				"node %v is in the graph but does not know its own concrete value", nn.id.Short()))
+			violate(InvariantSelf, node, nil,
+				"node %v is in the graph but does not know its own concrete value", nn.id.Short())
 		}
 		if nn.graphIndex != index {
-			problems = append(problems, fmt.Errorf(
+			violate(InvariantNodeIndex, node, nil,
 				"node %v is at position %d but records position %d",
-				nn.id.Short(), index, nn.graphIndex))
+				nn.id.Short(), index, nn.graphIndex)
 		}
 		if !nn.inGraph {
-			problems = append(problems, fmt.Errorf(
+			violate(InvariantInGraph, node, nil,
 				"node %v is in the graph's node list but is not marked as in the graph",
-				nn.id.Short()))
+				nn.id.Short())
 		}
 	}
 	nodeCount := len(graph.nodes)
@@ -63,9 +87,9 @@ func (graph *Graph) checkInvariants() error {
 	// counts observers and sentinels too, both of which are tracked apart from ordinary
 	// nodes, so the three have to be added up to compare against it.
 	if counted := uint64(nodeCount + sentinelCount + observerCount); graph.numNodes != counted {
-		problems = append(problems, fmt.Errorf(
+		violate(InvariantNodeCount, nil, nil,
 			"graph counts %d nodes but holds %d nodes, %d observers and %d sentinels",
-			graph.numNodes, nodeCount, observerCount, sentinelCount))
+			graph.numNodes, nodeCount, observerCount, sentinelCount)
 	}
 
 	occurrences := func(list []INode, id Identifier) (count int) {
@@ -88,17 +112,17 @@ func (graph *Graph) checkInvariants() error {
 			forward := occurrences(nn.parents, pn.id)
 			backward := occurrences(pn.children, nn.id)
 			if forward != backward {
-				problems = append(problems, fmt.Errorf(
+				violate(InvariantEdgeSymmetry, node, parent,
 					"edge asymmetry: %v lists %v as a parent %d time(s), but %v lists it as a child %d time(s)",
-					nn, pn, forward, pn, backward))
+					nn, pn, forward, pn, backward)
 			}
 			// A child recomputes after its parents, which is what the heights are for.
 			// Equal or inverted heights mean a pass can compute a node from a stale
 			// input.
 			if nn.height <= pn.height {
-				problems = append(problems, fmt.Errorf(
+				violate(InvariantHeightOrder, node, parent,
 					"height inversion: %v at height %d does not sit above its parent %v at height %d",
-					nn, nn.height, pn, pn.height))
+					nn, nn.height, pn, pn.height)
 			}
 		}
 		for _, child := range nn.children {
@@ -106,9 +130,9 @@ func (graph *Graph) checkInvariants() error {
 			forward := occurrences(nn.children, cn.id)
 			backward := occurrences(cn.parents, nn.id)
 			if forward != backward {
-				problems = append(problems, fmt.Errorf(
+				violate(InvariantEdgeSymmetry, node, child,
 					"edge asymmetry: %v lists %v as a child %d time(s), but %v lists it as a parent %d time(s)",
-					nn, cn, forward, cn, backward))
+					nn, cn, forward, cn, backward)
 			}
 
 			// A dependent edge exists only while the dependent is necessary. This is the
@@ -121,18 +145,18 @@ func (graph *Graph) checkInvariants() error {
 			// parents are dependents, their children are inputs): "[p] is in [c]'s parents
 			// iff ([c] is in [p]'s children && [p] is necessary)".
 			if !cn.isNecessary() {
-				problems = append(problems, fmt.Errorf(
+				violate(InvariantNecessaryDependents, node, child,
 					"%v keeps a dependent edge to %v, which is not necessary",
-					nn, cn))
+					nn, cn)
 			}
 		}
 
 		// A node's recorded position in the recompute heap has to match its height, or
 		// it will be pulled out in the wrong order.
 		if nn.heightInRecomputeHeap != HeightUnset && nn.heightInRecomputeHeap != nn.height {
-			problems = append(problems, fmt.Errorf(
+			violate(InvariantRecomputeHeap, node, nil,
 				"%v is queued at height %d but has height %d",
-				nn, nn.heightInRecomputeHeap, nn.height))
+				nn, nn.heightInRecomputeHeap, nn.height)
 		}
 	}
 
@@ -140,8 +164,23 @@ func (graph *Graph) checkInvariants() error {
 	heapErr := graph.recomputeHeap.sanityCheck()
 	graph.recomputeHeap.mu.Unlock()
 	if heapErr != nil {
-		problems = append(problems, heapErr)
+		violate(InvariantRecomputeHeap, nil, nil, "%s", heapErr.Error())
 	}
+	return
+}
 
-	return errors.Join(problems...)
+// scopeChain returns the binds a node was created in, innermost first, by their main
+// nodes.
+func scopeChain(nn *Node) (chain []Identifier) {
+	scope := nn.createdIn
+	for scope != nil {
+		bs, ok := scope.(bindScope)
+		if !ok {
+			return
+		}
+		main := bs.scopeMain().Node()
+		chain = append(chain, main.id)
+		scope = main.createdIn
+	}
+	return
 }
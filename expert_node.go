@@ -254,7 +254,12 @@ func (en *expertNode) SetOnAbortedHandlers(handlers []func(context.Context, erro
 }
 
 func (en *expertNode) Value() any {
-	rv := reflect.ValueOf(en.incr)
+	return nodeValue(en.incr)
+}
+
+// nodeValue returns a node's value, whatever its type, or nil for a node without one.
+func nodeValue(n INode) any {
+	rv := reflect.ValueOf(n)
 	valueMethod := rv.MethodByName("Value")
 	if !valueMethod.IsValid() {
 		return nil
@@ -0,0 +1,127 @@
+package incr
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// ErrFixpointNotConverged is returned by a [Fixpoint] node, and passed to its error
+// handlers, when the step function has not converged within the iteration cap.
+var ErrFixpointNotConverged = errors.New("incr: fixpoint did not converge")
+
+// DefaultFixpointMaxIterations is how many steps a [Fixpoint] node takes in one
+// stabilization before giving up, unless set otherwise with
+// [FixpointIncr.SetMaxIterations].
+const DefaultFixpointMaxIterations = 1000
+
+// Fixpoint returns a node that applies a step function to its own output until the
+// output stops changing, which is how an iterative computation -- PageRank, a
+// convergence solver, constraint propagation -- fits into a graph that otherwise
+// rejects cycles.
+//
+// The cycle lives inside the node rather than in the graph: each stabilization that
+// recomputes the node calls step with the input's value and the current state, over and
+// over, until converged reports that the previous state and the next one are close
+// enough, and the node takes the last state as its value. A small change to the input
+// usually moves the fixpoint only a little, so the iteration starts from where the last
+// one converged rather than from initial, which is only used the first time.
+//
+// step is handed the state it returned last time and must return a new one rather than
+// modify it, since converged is given both.
+//
+// If the iteration cap is reached first, the node fails with an error wrapping
+// [ErrFixpointNotConverged], which stops the pass and reaches the node's OnError
+// handlers like any other node error. The iteration is not thrown away: the node stays
+// queued, and the next pass continues from the state this one stopped at.
+func Fixpoint[A, B any](scope Scope, input Incr[A], initial B, step func(A, B) B, converged func(previous, next B) bool) FixpointIncr[B] {
+	return FixpointContext(scope, input, initial, func(_ context.Context, a A, b B) (B, error) {
+		return step(a, b), nil
+	}, converged)
+}
+
+// FixpointContext is [Fixpoint] with a step function that is passed the stabilization
+// context and can return an error, which stops the iteration and fails the node.
+func FixpointContext[A, B any](scope Scope, input Incr[A], initial B, step func(context.Context, A, B) (B, error), converged func(previous, next B) bool) FixpointIncr[B] {
+	f := &fixpointIncr[A, B]{
+		n:             scope.newNode(KindFixpoint),
+		input:         input,
+		state:         initial,
+		step:          step,
+		converged:     converged,
+		maxIterations: DefaultFixpointMaxIterations,
+	}
+	f.parents[0] = input
+	return WithinScope(scope, f)
+}
+
+// FixpointIncr is the node [Fixpoint] returns.
+type FixpointIncr[B any] interface {
+	Incr[B]
+	// SetMaxIterations sets how many steps one stabilization may take before the node
+	// fails with [ErrFixpointNotConverged]. Values below one are treated as one.
+	SetMaxIterations(int)
+	// Iterations returns how many steps the last stabilization that recomputed the node
+	// took, whether or not it converged.
+	Iterations() int
+}
+
+var (
+	_ FixpointIncr[int] = (*fixpointIncr[string, int])(nil)
+	_ IStabilize        = (*fixpointIncr[string, int])(nil)
+	_ IParents          = (*fixpointIncr[string, int])(nil)
+	_ fmt.Stringer      = (*fixpointIncr[string, int])(nil)
+)
+
+type fixpointIncr[A, B any] struct {
+	n         *Node
+	input     Incr[A]
+	step      func(context.Context, A, B) (B, error)
+	converged func(previous, next B) bool
+	// state is where the next iteration starts: the value after a converged pass, and
+	// the last state reached after one that stopped early.
+	state         B
+	value         B
+	maxIterations int
+	iterations    int
+	parents       [1]INode
+}
+
+func (f *fixpointIncr[A, B]) Parents() []INode { return f.parents[:] }
+
+func (f *fixpointIncr[A, B]) Node() *Node { return f.n }
+
+func (f *fixpointIncr[A, B]) Value() B { return f.value }
+
+func (f *fixpointIncr[A, B]) SetMaxIterations(maxIterations int) {
+	f.maxIterations = max(maxIterations, 1)
+}
+
+func (f *fixpointIncr[A, B]) Iterations() int { return f.iterations }
+
+func (f *fixpointIncr[A, B]) Stabilize(ctx context.Context) error {
+	input := f.input.Value()
+	done := ctx.Done()
+	f.iterations = 0
+	for f.iterations < f.maxIterations {
+		// a step can be arbitrarily expensive and there can be many of them, so the
+		// context is checked between steps rather than only between nodes
+		if err := contextCanceled(ctx, done); err != nil {
+			return err
+		}
+		next, err := f.step(ctx, input, f.state)
+		if err != nil {
+			return err
+		}
+		f.iterations++
+		previous := f.state
+		f.state = next
+		if f.converged(previous, next) {
+			f.value = next
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: %v after %d iterations", ErrFixpointNotConverged, f, f.iterations)
+}
+
+func (f *fixpointIncr[A, B]) String() string { return f.n.String() }
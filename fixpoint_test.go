@@ -0,0 +1,99 @@
+package incr
+
+import (
+	"context"
+	"errors"
+	"math"
+	"testing"
+
+	"github.com/wcharczuk/go-incr/testutil"
+)
+
+func Test_Fixpoint(t *testing.T) {
+	ctx := testContext()
+	g := New()
+	// Newton's method for a square root: the state is the current estimate
+	v := Var(g, 2.0)
+	root := Fixpoint(g, v, 1.0, func(x, estimate float64) float64 {
+		return (estimate + x/estimate) / 2
+	}, func(previous, next float64) bool {
+		return math.Abs(previous-next) < 1e-12
+	})
+	o := MustObserve(g, root)
+	testutil.Equal(t, KindFixpoint, root.Node().Kind())
+
+	testutil.NoError(t, g.Stabilize(ctx))
+	testutil.Equal(t, true, math.Abs(o.Value()-math.Sqrt2) < 1e-9)
+	cold := root.Iterations()
+
+	// a nearby input starts from the last fixpoint, which is already close
+	v.Set(2.0001)
+	testutil.NoError(t, g.Stabilize(ctx))
+	testutil.Equal(t, true, math.Abs(o.Value()-math.Sqrt(2.0001)) < 1e-9)
+	testutil.Equal(t, true, root.Iterations() < cold)
+
+	// and an unchanged input does not recompute at all
+	iterations := root.Iterations()
+	testutil.NoError(t, g.Stabilize(ctx))
+	testutil.Equal(t, iterations, root.Iterations())
+}
+
+func Test_Fixpoint_notConverged(t *testing.T) {
+	ctx := testContext()
+	g := New()
+	limit := Var(g, 25)
+	counter := Fixpoint(g, limit, 0, func(_ int, state int) int {
+		return state + 1
+	}, func(_, next int) bool {
+		return next >= 25
+	})
+	counter.SetMaxIterations(10)
+	var handled []error
+	counter.Node().OnError(func(_ context.Context, err error) {
+		handled = append(handled, err)
+	})
+	o := MustObserve(g, counter)
+
+	err := g.Stabilize(ctx)
+	testutil.Equal(t, true, errors.Is(err, ErrFixpointNotConverged))
+	testutil.Equal(t, 10, counter.Iterations())
+	testutil.Equal(t, 0, o.Value())
+	testutil.Equal(t, 1, len(handled))
+
+	// each pass carries on from where the last stopped
+	testutil.Error(t, g.Stabilize(ctx))
+	testutil.NoError(t, g.Stabilize(ctx))
+	testutil.Equal(t, 5, counter.Iterations())
+	testutil.Equal(t, 25, o.Value())
+	testutil.Equal(t, 2, len(handled))
+}
+
+func Test_FixpointContext_error(t *testing.T) {
+	ctx := testContext()
+	g := New()
+	v := Var(g, 0)
+	failure := errors.New("diverged")
+	f := FixpointContext(g, v, 1, func(_ context.Context, _ int, state int) (int, error) {
+		if state > 100 {
+			return 0, failure
+		}
+		return state * 2, nil
+	}, func(previous, next int) bool {
+		return previous == next
+	})
+	MustObserve(g, f)
+	testutil.Equal(t, true, errors.Is(g.Stabilize(ctx), failure))
+
+	// a context canceled part way through stops the iteration between steps
+	cancelled, cancel := context.WithCancel(ctx)
+	g = New()
+	f = FixpointContext(g, Var(g, 0), 0, func(_ context.Context, _ int, state int) (int, error) {
+		if state == 2 {
+			cancel()
+		}
+		return state + 1, nil
+	}, func(_, _ int) bool { return false })
+	MustObserve(g, f)
+	testutil.Equal(t, true, errors.Is(g.Stabilize(cancelled), context.Canceled))
+	testutil.Equal(t, 3, f.Iterations())
+}
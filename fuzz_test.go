@@ -30,6 +30,8 @@ func FuzzGraph(f *testing.F) {
 	f.Add([]byte{0, 0, 1, 0, 4, 0, 4, 0, 4, 0, 7, 0, 5, 0, 7, 0})
 	// a node that panics, observed and stabilized, then released
 	f.Add([]byte{0, 1, 8, 0, 4, 0, 7, 0, 7, 0, 5, 0, 7, 0})
+	// binds rebuilt side by side in parallel, by height blocks and by dataflow
+	f.Add([]byte{0, 0, 0, 1, 3, 0, 3, 1, 4, 2, 4, 3, 7, 1, 6, 1, 6, 2, 7, 1, 6, 3, 6, 4, 7, 2})
 
 	f.Fuzz(func(t *testing.T, program []byte) {
 		const (
@@ -109,8 +111,19 @@ func FuzzGraph(f *testing.F) {
 				}
 				vars[int(arg)%len(vars)].Set(int(arg))
 
-			case 7: // stabilize
-				if err := g.Stabilize(ctx); err != nil {
+			case 7: // stabilize, serially or with either parallel scheduler
+				var err error
+				switch arg % 3 {
+				case 0:
+					err = g.Stabilize(ctx)
+				case 1:
+					g.parallelScheduler = ParallelSchedulerHeightBlocks
+					err = g.ParallelStabilize(ctx)
+				case 2:
+					g.parallelScheduler = ParallelSchedulerDataflow
+					err = g.ParallelStabilize(ctx)
+				}
+				if err != nil {
 					continue
 				}
 
@@ -1,6 +1,7 @@
 package incr
 
 import (
+	"cmp"
 	"context"
 	"errors"
 	"fmt"
@@ -25,7 +26,6 @@ import (
 // an [Observer] before you can stabilize them.
 func New(opts ...GraphOption) *Graph {
 	options := GraphOptions{
-		MaxHeight:   DefaultMaxHeight,
 		Parallelism: runtime.NumCPU(),
 	}
 	for _, opt := range opts {
@@ -38,25 +38,48 @@ func New(opts ...GraphOption) *Graph {
 			options.IdentifierProvider = _defaultIdentifierProvider
 		}
 	}
+	heights := DefaultHeights
+	if options.MaxHeight > 0 {
+		heights = options.MaxHeight
+	}
 	return &Graph{
 		identiferProvider:         options.IdentifierProvider,
 		id:                        options.IdentifierProvider.NewIdentifier(),
 		parallelism:               options.Parallelism,
+		parallelScheduler:         options.ParallelScheduler,
+		concurrencyGroups:         newConcurrencyGroups(options.ConcurrencyGroups),
 		clearRecomputeHeapOnError: options.ClearRecomputeHeapOnError,
 		deterministic:             options.Deterministic,
+		views:                     options.Views,
+		recorder:                  options.Recorder,
+		replayVars:                replayBuilding.Load() > 0,
+		history:                   newHistory(options.HistoryDepth),
+		invariantCheck:            options.CheckInvariants,
+		onInvariantViolations:     options.OnInvariantViolations,
 		stabilizationNum:          1,
 		status:                    StatusNotStabilizing,
 		nodes:                     allocateSliceWithSize[INode](options.PreallocateNodesSize),
 		observers:                 allocateMapWithSize[Identifier, IObserver](options.PreallocateObserversSize),
 		sentinels:                 allocateMapWithSize[Identifier, ISentinel](options.PreallocateSentinelsSize),
-		recomputeHeap:             newRecomputeHeap(options.MaxHeight),
-		adjustHeightsHeap:         newAdjustHeightsHeap(options.MaxHeight),
+		recomputeHeap:             newRecomputeHeap(heights),
+		adjustHeightsHeap:         newAdjustHeightsHeap(heights, options.MaxHeight),
 		setDuringStabilization:    make(map[Identifier]INode),
 		handleAfterStabilization:  make(map[Identifier][]func(context.Context)),
 		propagateInvalidityQueue:  new(queue[INode]),
 	}
 }
 
+func newConcurrencyGroups(limits map[string]int) map[string]chan struct{} {
+	if len(limits) == 0 {
+		return nil
+	}
+	groups := make(map[string]chan struct{}, len(limits))
+	for name, limit := range limits {
+		groups[name] = make(chan struct{}, limit)
+	}
+	return groups
+}
+
 func allocateSliceWithSize[V any](size int) []V {
 	if size > 0 {
 		return make([]V, 0, size)
@@ -74,7 +97,13 @@ func allocateMapWithSize[K comparable, V any](size int) map[K]V {
 // GraphOption mutates GraphOptions.
 type GraphOption func(*GraphOptions)
 
-// OptGraphMaxHeight sets the graph max recompute height.
+// OptGraphMaxHeight caps the number of heights the graph will track, so that no node
+// sits higher than maxHeight-1; linking or binding in a node that would fails with a
+// [HeightExceededError].
+//
+// Without it the graph has no cap, and the recompute and adjust-heights heaps start
+// with room for [DefaultHeights] heights and grow as nodes are placed above them. With
+// it, they are sized for the cap up front and never grow past it.
 func OptGraphMaxHeight(maxHeight int) func(*GraphOptions) {
 	return func(g *GraphOptions) {
 		g.MaxHeight = maxHeight
@@ -91,6 +120,30 @@ func OptGraphParallelism(parallelism int) func(*GraphOptions) {
 	}
 }
 
+// OptGraphParallelScheduler sets how [Graph.ParallelStabilize] orders the nodes it
+// recomputes; see [ParallelScheduler].
+//
+// This will default to [ParallelSchedulerHeightBlocks] if unset.
+func OptGraphParallelScheduler(scheduler ParallelScheduler) func(*GraphOptions) {
+	return func(g *GraphOptions) {
+		g.ParallelScheduler = scheduler
+	}
+}
+
+// OptGraphConcurrencyGroup sets how many nodes of a named concurrency group
+// [Graph.ParallelStabilize] runs at once; see [Node.SetConcurrencyGroup].
+//
+// The limit applies within the graph's parallelism rather than on top of it. A limit
+// below one is treated as one.
+func OptGraphConcurrencyGroup(name string, limit int) func(*GraphOptions) {
+	return func(g *GraphOptions) {
+		if g.ConcurrencyGroups == nil {
+			g.ConcurrencyGroups = make(map[string]int)
+		}
+		g.ConcurrencyGroups[name] = max(limit, 1)
+	}
+}
+
 // OptGraphPreallocateNodesSize preallocates the node tracking slice within
 // the graph with a given size number of elements for items.
 //
@@ -138,6 +191,10 @@ func OptGraphClearRecomputeHeapOnError(shouldClear bool) func(*GraphOptions) {
 //
 // If not provided, by default some processes, like calling update handlers after stabilization, use
 // map iterators for which the execution order is undefined, though in practice it is pseudo-random.
+//
+// A deterministic graph can still be stabilized with [Graph.ParallelStabilize]; nodes run
+// concurrently, but error handlers, bind functions and the identifiers they hand out, and
+// the error returned, all follow the same order from run to run.
 func OptGraphDeterministic(deterministic bool) func(*GraphOptions) {
 	return func(g *GraphOptions) {
 		g.Deterministic = deterministic
@@ -157,22 +214,61 @@ func OptGraphIdentifierProvider(identifierProvider IdentifierProvider) func(*Gra
 	}
 }
 
+// OptGraphViews has the graph capture a [View] of its observers at the end of every
+// stabilization, for [Graph.View] to return.
+//
+// Capturing costs a pass over the observers per stabilization, plus a copy of the
+// captured values whenever one of them changed, so it is off unless asked for.
+func OptGraphViews(enabled bool) func(*GraphOptions) {
+	return func(g *GraphOptions) {
+		g.Views = enabled
+	}
+}
+
+// OptGraphRecorder has the graph write its inputs to a [Recorder] as they happen -- vars
+// set, nodes marked stale, clocks advanced -- along with the observed values at the end
+// of each stabilization, so that [Replay] can reproduce the sequence later.
+//
+// Replaying needs the rebuilt graph's nodes to have the same identifiers as the
+// recorded one, so a recorded graph should use a sequential identifier provider, as
+// [OptGraphDeterministic] does by default.
+func OptGraphRecorder(recorder *Recorder) func(*GraphOptions) {
+	return func(g *GraphOptions) {
+		g.Recorder = recorder
+	}
+}
+
 // GraphOptions are options for graphs.
 type GraphOptions struct {
 	MaxHeight                 int
 	Parallelism               int
+	ParallelScheduler         ParallelScheduler
+	ConcurrencyGroups         map[string]int
 	PreallocateNodesSize      int
 	PreallocateObserversSize  int
 	PreallocateSentinelsSize  int
 	ClearRecomputeHeapOnError bool
 	Deterministic             bool
 	IdentifierProvider        IdentifierProvider
+	Views                     bool
+	Recorder                  *Recorder
+	HistoryDepth              int
+	CheckInvariants           InvariantCheck
+	OnInvariantViolations     func(context.Context, *InvariantViolationsError)
 }
 
 const (
-	// DefaultMaxHeight is the default maximum height that can
-	// be tracked in the recompute heap.
-	DefaultMaxHeight = 256
+	// DefaultHeights is the number of heights the recompute heap is
+	// sized for when no [OptGraphMaxHeight] is given; it grows past
+	// this as the graph deepens.
+	DefaultHeights = 256
+
+	// DefaultMaxHeight is the number of heights graphs used to be
+	// limited to by default.
+	//
+	// Deprecated: graphs no longer have a maximum height unless one
+	// is set with [OptGraphMaxHeight]; see [DefaultHeights].
+	DefaultMaxHeight = DefaultHeights
 )
 
 var (
@@ -196,12 +292,23 @@ type Graph struct {
 	// parallelism is the degree of parallelism used when processing nodes
 	// with the [parallelBatch] iterator.
 	parallelism int
+	// parallelScheduler is how [Graph.ParallelStabilize] orders its work.
+	parallelScheduler ParallelScheduler
+	// concurrencyGroups are the semaphores limiting each named concurrency group, with
+	// the group's limit as their capacity.
+	concurrencyGroups map[string]chan struct{}
 
 	// clearRecomputeHeapOnError controls if we should clear the recomputeHeap on error.
 	clearRecomputeHeapOnError bool
 	// recomputingNode is the node currently being recomputed on the serial path, read only
 	// when recovering from a panic to report and retry the node responsible.
 	recomputingNode INode
+	// slogTracer is the tracer the current pass is traced with, if it is a [SlogTracer],
+	// which is the only kind that traces individual nodes.
+	slogTracer *SlogTracer
+	// traceEvents is what the recorder the current pass is traced with keeps for the
+	// graph, if it is a [TraceEventRecorder].
+	traceEvents *traceEventGraph
 	// nodeSlab is where nodes created directly on the graph get their metadata.
 	nodeSlab nodeSlab
 
@@ -225,6 +332,49 @@ type Graph struct {
 	// observers hold references to observers organized by node id.
 	observers map[Identifier]IObserver
 
+	// recorder, if set, is written the graph's inputs and observed values; see
+	// [OptGraphRecorder].
+	recorder *Recorder
+	// replayVars is whether the graph keeps its vars in vars, which is only so for a
+	// graph built by [Replay]; see replayBuilding.
+	replayVars bool
+	// varsMu guards vars.
+	varsMu sync.Mutex
+	// vars holds every var created in the graph and not since invalidated, by
+	// identifier, for [Replay] to set. Vars are registered whether or not they are
+	// necessary, since a var can be set before anything observes it, and that set is
+	// recorded like any other.
+	vars map[Identifier]recordedSetter
+	// bridgesMu guards bridgesTo.
+	bridgesMu sync.Mutex
+	// bridgesTo counts the active bridges from this graph into others, by destination;
+	// see [Bridge].
+	bridgesTo map[*Graph]int
+	// history, if set, holds what the last stabilizations changed; see [OptGraphHistory].
+	history *history
+	// invariantCheck is when the graph checks its own invariants; see
+	// [OptGraphCheckInvariants].
+	invariantCheck InvariantCheck
+	// onInvariantViolations, if set, is given the violations the checks find instead of
+	// the stabilization returning them.
+	onInvariantViolations func(context.Context, *InvariantViolationsError)
+	// invariantReports are the violations found after bind rebuilds during the pass,
+	// held until it ends.
+	invariantReports []*InvariantViolationsError
+	// clocksMu interlocks access to clocks.
+	clocksMu sync.Mutex
+	// clocks are the clocks nodes in the graph have registered with, in the order they
+	// first did, which is how a recording refers to them.
+	clocks []*Clock
+
+	// views is whether a [View] is captured at the end of each stabilization.
+	views bool
+	// view is the last view captured, published for readers on other goroutines.
+	view atomic.Pointer[View]
+	// viewObserversChanged records that an observer came or went since the last view
+	// was captured; guarded by observersMu.
+	viewObserversChanged bool
+
 	// sentinelsMu interlocks access to sentinels
 	sentinelsMu sync.Mutex
 	// sentinels hold references to sentinels organized by node id.
@@ -237,14 +387,25 @@ type Graph struct {
 	// adjustHeightsHeap is a list of nodes to adjust the heights for.
 	adjustHeightsHeap *adjustHeightsHeap
 
-	// recomputeMu serializes graph-structure mutation during
+	// recomputeMu serializes enqueuing recomputed nodes' children during
 	// [Graph.ParallelStabilize]. Within a height block the parallel workers
-	// run each node's user Stabilize concurrently, but the structural work
-	// (a bind swapping out its subgraph, and enqueuing recomputed nodes'
-	// children) must be serialized because it mutates shared node/height/heap
-	// state. It is only ever contended on the parallel path; serial
-	// stabilization is single-goroutine and never acquires it.
+	// run each node's user Stabilize concurrently, but enqueuing reads and writes
+	// shared node/height/heap state. It is only ever contended on the parallel
+	// path; serial stabilization is single-goroutine and never acquires it.
 	recomputeMu sync.Mutex
+	// deferNodeErrors is set for the length of a deterministic parallel pass, where a
+	// failing node's error handlers are not called from the worker that ran it but
+	// afterwards, in canonical order; see deferredNodeErrors.
+	deferNodeErrors bool
+	// deferredNodeErrorsMu interlocks access to deferredNodeErrors.
+	deferredNodeErrorsMu sync.Mutex
+	// deferredNodeErrors are the errors of the current batch waiting to be reported.
+	deferredNodeErrors []deferredNodeError
+	// rightHandSidesBuiltMu interlocks access to rightHandSidesBuilt.
+	rightHandSidesBuiltMu sync.Mutex
+	// rightHandSidesBuilt are the bind change nodes that built a new right-hand side
+	// during the current parallel batch, to be linked in once it is done.
+	rightHandSidesBuilt []builtRightHandSide
 
 	// setDuringStabilizationMu interlocks acces to setDuringStabilization
 	setDuringStabilizationMu sync.Mutex
@@ -252,6 +413,13 @@ type Graph struct {
 	// set during stabilization
 	setDuringStabilization map[Identifier]INode
 
+	// asyncMu guards asyncArrivals, the [MapAsync] nodes whose work finished since the
+	// last stabilization started, appended to from the goroutines running the work.
+	// hasAsyncArrivals lets a stabilization skip the lock when there are none.
+	asyncMu          sync.Mutex
+	asyncArrivals    []INode
+	hasAsyncArrivals atomic.Bool
+
 	// handleAfterStabilizationMu coordinates access to handleAfterStabilization
 	handleAfterStabilizationMu sync.Mutex
 	// handleAfterStabilization is a list of update
@@ -380,6 +548,15 @@ func (graph *Graph) OnStabilizationEnd(handler func(context.Context, time.Time,
 
 // SetStale sets a node as stale.
 func (graph *Graph) SetStale(gn INode) {
+	if graph.recorder != nil && atomic.LoadInt32(&graph.status) == StatusNotStabilizing {
+		graph.recorder.recordStale(graph, gn.Node().id)
+	}
+	graph.setStale(gn)
+}
+
+// setStale is [Graph.SetStale] for the graph's own callers, whose reasons for marking a
+// node stale are recorded, if at all, as the events that caused them.
+func (graph *Graph) setStale(gn INode) {
 	n := gn.Node()
 	n.setAt = graph.stabilizationNum
 	if gn.Node().heightInRecomputeHeap == HeightUnset {
@@ -422,6 +599,10 @@ func (graph *Graph) invalidateNode(node INode) {
 		handler()
 	}
 
+	if _, ok := node.(recordedSetter); ok && graph.replayVars {
+		graph.unregisterVar(node.Node().id)
+	}
+
 	nn := node.Node()
 	nn.changedAt = graph.stabilizationNum
 	nn.recomputedAt = graph.stabilizationNum
@@ -686,6 +867,7 @@ func (graph *Graph) addObserver(on IObserver) {
 	atomic.AddUint64(&graph.numNodes, 1)
 	onn.initializeFrom(on)
 	graph.observers[onn.id] = on
+	graph.viewObserversChanged = true
 }
 
 func (graph *Graph) addSentinel(sn ISentinel) {
@@ -705,6 +887,7 @@ func (graph *Graph) addSentinel(sn ISentinel) {
 func (graph *Graph) removeObserver(on IObserver) {
 	graph.observersMu.Lock()
 	delete(graph.observers, on.Node().id)
+	graph.viewObserversChanged = true
 	graph.observersMu.Unlock()
 	graph.zeroNode(on)
 }
@@ -809,6 +992,9 @@ func (graph *Graph) watchNode(sn ISentinel, input INode) error {
 }
 
 func (graph *Graph) unobserveNode(o IObserver, input INode) {
+	for _, handler := range o.Node().takeUnobservedHandlers() {
+		handler()
+	}
 	graph.removeObserver(o)
 	input.Node().removeObserver(o.Node().id)
 	graph.checkIfUnnecessary(input)
@@ -837,6 +1023,9 @@ func (graph *Graph) stabilizeStart(ctx context.Context) context.Context {
 	// cleared so that a panic raised before any node is recomputed does not blame whichever
 	// node happened to be last in the previous pass
 	graph.recomputingNode = nil
+	if graph.hasAsyncArrivals.Load() {
+		graph.takeAsyncArrivals()
+	}
 	for _, handler := range graph.onStabilizationStart {
 		handler(ctx)
 	}
@@ -853,6 +1042,18 @@ func (graph *Graph) stabilizeStart(ctx context.Context) context.Context {
 	// context.WithValue allocates on every call otherwise.
 	if tracing {
 		ctx = WithStabilizationNumber(ctx, graph.stabilizationNum)
+		switch t := GetTracer(ctx).(type) {
+		case *SlogTracer:
+			graph.slogTracer = t
+			ctx = context.WithValue(ctx, traceGraphKey{}, graph)
+		case *TraceEventRecorder:
+			graph.traceEvents = t.passStart(graph)
+			ctx = WithTracer(ctx, graph.traceEvents)
+		case *traceEventGraph:
+			// a graph stabilized from within another graph's pass
+			graph.traceEvents = t.r.passStart(graph)
+			ctx = WithTracer(ctx, graph.traceEvents)
+		}
 		TracePrintln(ctx, "stabilization starting")
 	}
 	return ctx
@@ -861,6 +1062,8 @@ func (graph *Graph) stabilizeStart(ctx context.Context) context.Context {
 func (graph *Graph) stabilizeEnd(ctx context.Context, err error) {
 	defer func() {
 		graph.stabilizationStarted = time.Time{}
+		graph.slogTracer = nil
+		graph.traceEvents = nil
 		atomic.StoreInt32(&graph.status, StatusNotStabilizing)
 	}()
 	for _, handler := range graph.onStabilizationEnd {
@@ -869,6 +1072,9 @@ func (graph *Graph) stabilizeEnd(ctx context.Context, err error) {
 	if err != nil {
 		TraceErrorf(ctx, "stabilization error: %v", err)
 	}
+	if graph.traceEvents != nil {
+		graph.traceEvents.passEnd(err)
+	}
 	// The elapsed time is computed only when there is a tracer to print it. Arguments are
 	// evaluated whether or not the call does anything with them, so leaving time.Since here
 	// unguarded read the clock on every stabilization to format a string that was then
@@ -881,6 +1087,18 @@ func (graph *Graph) stabilizeEnd(ctx context.Context, err error) {
 			TracePrintf(ctx, "stabilization complete (%v elapsed)", elapsed)
 		}
 	}
+	// captured before the update handlers run, so a handler that publishes the view
+	// somewhere publishes the one this pass produced; a failed pass left some nodes
+	// recomputed and others not, so it keeps the last view rather than capture that
+	if graph.views && err == nil {
+		graph.captureView()
+	}
+	if graph.history != nil {
+		graph.history.capture(graph.stabilizationNum)
+	}
+	if graph.recorder != nil {
+		graph.recorder.recordStabilize(graph, err)
+	}
 	graph.stabilizeEndRunUpdateHandlers(ctx)
 	graph.stabilizationNum++
 	graph.stabilizeEndHandleSetDuringStabilization(ctx)
@@ -973,15 +1191,11 @@ func (graph *Graph) recomputePanicked(ctx context.Context, n INode, recovered an
 	if n == nil {
 		return newPanicError(nil, recovered)
 	}
-	err := newPanicError(n, recovered)
 	if !graph.clearRecomputeHeapOnError {
 		n.Node().recomputedAt = 0
 		graph.recomputeHeap.addIfNotPresent(n)
 	}
-	for _, eh := range n.Node().errorHandlers() {
-		eh(ctx, err)
-	}
-	return err
+	return graph.nodeErrored(ctx, n, newPanicError(n, recovered))
 }
 
 // recomputeFailed returns a node that errored to the state it was in before the
@@ -1005,6 +1219,30 @@ func (graph *Graph) recomputeFailed(n INode, previousRecomputedAt uint64) {
 	graph.recomputeHeap.addIfNotPresent(n)
 }
 
+// nodeErrored calls a failed node's error handlers, or on a deterministic parallel
+// pass records the error for reportDeferredNodeErrors, and returns the error wrapped in
+// a [*NodeError] for the pass to return.
+func (graph *Graph) nodeErrored(ctx context.Context, n INode, err error) error {
+	if graph.slogTracer != nil {
+		graph.traceNodeError(ctx, n, err)
+	}
+	nodeErr := graph.newNodeError(n, err)
+	if graph.deferNodeErrors {
+		graph.deferredNodeErrorsMu.Lock()
+		graph.deferredNodeErrors = append(graph.deferredNodeErrors, deferredNodeError{
+			n:      n,
+			height: n.Node().height,
+			err:    nodeErr,
+		})
+		graph.deferredNodeErrorsMu.Unlock()
+		return nodeErr
+	}
+	for _, eh := range n.Node().errorHandlers() {
+		eh(ctx, err)
+	}
+	return nodeErr
+}
+
 // recompute starts the recompute cycle for the node
 // setting the recomputedAt field and possibly changing the value.
 //
@@ -1019,10 +1257,14 @@ func (graph *Graph) recompute(ctx context.Context, n INode, parallel bool) (err
 	// dependent to the heap so that other workers can take them, so there is nothing to
 	// loop on.
 	if parallel {
-		return graph.recomputeNodeParallel(ctx, n)
+		return graph.recomputeNodeParallel(ctx, n, traceEventCoordinator)
 	}
 	for n != nil {
-		n, err = graph.recomputeNodeSerial(ctx, n)
+		if graph.traceEvents != nil {
+			n, err = graph.recomputeNodeSerialTraced(ctx, n)
+		} else {
+			n, err = graph.recomputeNodeSerial(ctx, n)
+		}
 		if err != nil {
 			return
 		}
@@ -1033,6 +1275,24 @@ func (graph *Graph) recompute(ctx context.Context, n INode, parallel bool) (err
 	return
 }
 
+// recomputeNodeSerialTraced is recomputeNodeSerial recorded to the pass's
+// [TraceEventRecorder], kept apart so the untraced path does not pay for it.
+func (graph *Graph) recomputeNodeSerialTraced(ctx context.Context, n INode) (immediate INode, err error) {
+	span := graph.traceEvents.nodeStart(traceEventCoordinator)
+	immediate, err = graph.recomputeNodeSerial(ctx, n)
+	graph.traceEvents.nodeEnd(span, n, n.Node().changedAt == graph.stabilizationNum, err)
+	return
+}
+
+// recomputeNodeParallelValueTraced is recomputeNodeParallelValue recorded to the pass's
+// [TraceEventRecorder], on the track of the worker running it.
+func (graph *Graph) recomputeNodeParallelValueTraced(ctx context.Context, n INode, worker int) (changed bool, err error) {
+	span := graph.traceEvents.nodeStart(worker)
+	changed, err = graph.recomputeNodeParallelValue(ctx, n)
+	graph.traceEvents.nodeEnd(span, n, changed, err)
+	return
+}
+
 // recomputeNodeSerial is [Graph.recomputeNode] with the parallel path resolved away.
 //
 // The two are deliberately duplicated rather than sharing a body with a flag. This is the
@@ -1051,6 +1311,9 @@ func (graph *Graph) recomputeNodeSerial(ctx context.Context, n INode) (immediate
 
 	nn := n.Node()
 	nn.numRecomputes++
+	if graph.slogTracer != nil {
+		ctx = graph.traceNodeStart(ctx, n)
+	}
 	// the stamp is applied before the attempt, so that anything this node's own
 	// stabilization consults sees the current pass; recomputeFailed puts it back if
 	// the attempt does not succeed.
@@ -1061,9 +1324,7 @@ func (graph *Graph) recomputeNodeSerial(ctx context.Context, n INode) (immediate
 	shouldCutoff, err = nn.maybeCutoff(ctx)
 	if err != nil {
 		graph.recomputeFailed(n, previousRecomputedAt)
-		for _, eh := range nn.errorHandlers() {
-			eh(ctx, err)
-		}
+		err = graph.nodeErrored(ctx, n, err)
 		return
 	}
 	if shouldCutoff {
@@ -1076,13 +1337,14 @@ func (graph *Graph) recomputeNodeSerial(ctx context.Context, n INode) (immediate
 	err = nn.maybeStabilize(ctx)
 	if err != nil {
 		graph.recomputeFailed(n, previousRecomputedAt)
-		for _, eh := range nn.errorHandlers() {
-			eh(ctx, err)
-		}
+		err = graph.nodeErrored(ctx, n, err)
 		return
 	}
 
 	nn.changedAt = graph.stabilizationNum
+	if graph.history != nil {
+		graph.history.changed = append(graph.history.changed, n)
+	}
 	if handlers := nn.updateHandlers(); len(handlers) > 0 {
 		graph.queueUpdateHandlers(false, nn.id, handlers)
 	}
@@ -1137,8 +1399,42 @@ func (graph *Graph) recomputeNodeSerial(ctx context.Context, n INode) (immediate
 // dependent goes to the recompute heap so that any worker can take it.
 //
 // See recomputeNodeSerial for the twin, and for why the two are duplicated rather than
-// sharing a body with a flag. Keep them in step.
-func (graph *Graph) recomputeNodeParallel(ctx context.Context, n INode) (err error) {
+// sharing a body with a flag. Keep them in step. The worker is the one running the
+// node, as parallelBatch numbers them, and is only used for tracing.
+func (graph *Graph) recomputeNodeParallel(ctx context.Context, n INode, worker int) (err error) {
+	var changed bool
+	if graph.traceEvents != nil {
+		changed, err = graph.recomputeNodeParallelValueTraced(ctx, n, worker)
+	} else {
+		changed, err = graph.recomputeNodeParallelValue(ctx, n)
+	}
+	if err != nil || !changed {
+		return
+	}
+
+	// note we lock recomputeMu rather than the recompute heap's own mutex;
+	// two workers can share a child, and notifying it, checking its staleness
+	// and queuing it have to happen as one step.
+	nn := n.Node()
+	graph.recomputeMu.Lock()
+	for _, c := range nn.children {
+		cn := c.Node()
+		if cn.childChangedNotifier != nil {
+			cn.childChangedNotifier.ChildChanged(n)
+		}
+		if shouldRecomputeChild(cn, graph.stabilizationNum) {
+			graph.recomputeHeap.addNodeUnsafe(c)
+		}
+	}
+	graph.recomputeMu.Unlock()
+	return
+}
+
+// recomputeNodeParallelValue recomputes a node on the parallel path without touching its
+// children, and reports whether its value changed; what to do with the children is up
+// to the scheduler. The height block scheduler queues them to the heap, where the
+// dataflow scheduler counts them down instead.
+func (graph *Graph) recomputeNodeParallelValue(ctx context.Context, n INode) (changed bool, err error) {
 	// the counters are shared between workers here, unlike on the serial path
 	atomic.AddUint64(&graph.numNodesRecomputed, 1)
 
@@ -1147,6 +1443,9 @@ func (graph *Graph) recomputeNodeParallel(ctx context.Context, n INode) (err err
 
 	nn := n.Node()
 	nn.numRecomputes++
+	if graph.slogTracer != nil {
+		ctx = graph.traceNodeStart(ctx, n)
+	}
 	// the stamp is applied before the attempt, so that anything this node's own
 	// stabilization consults sees the current pass; recomputeFailed puts it back if
 	// the attempt does not succeed.
@@ -1157,9 +1456,7 @@ func (graph *Graph) recomputeNodeParallel(ctx context.Context, n INode) (err err
 	shouldCutoff, err = nn.maybeCutoff(ctx)
 	if err != nil {
 		graph.recomputeFailed(n, previousRecomputedAt)
-		for _, eh := range nn.errorHandlers() {
-			eh(ctx, err)
-		}
+		err = graph.nodeErrored(ctx, n, err)
 		return
 	}
 	if shouldCutoff {
@@ -1169,48 +1466,32 @@ func (graph *Graph) recomputeNodeParallel(ctx context.Context, n INode) (err err
 	atomic.AddUint64(&graph.numNodesChanged, 1)
 	nn.numChanges++
 
-	// a node whose Stabilize mutates graph structure (i.e. a bind swapping out
-	// its right-hand-side subgraph) cannot run concurrently with another
-	// worker's structural work, so on the parallel path it is serialized under
-	// recomputeMu. leaf nodes' Stabilize is left lock-free, which is the whole
-	// point of stabilizing in parallel.
-	mutatesStructure := nodeMutatesStructure(n)
-	if mutatesStructure {
-		graph.recomputeMu.Lock()
-	}
-	err = nn.maybeStabilize(ctx)
-	if mutatesStructure {
-		graph.recomputeMu.Unlock()
+	// a bind's left-hand side change node only builds its new right-hand side here,
+	// alongside the other workers; linking it in mutates structure the other workers
+	// read, so it waits until they are idle. See linkRightHandSides.
+	if builder, ok := n.(rightHandSideBuilder); ok {
+		if err = builder.buildRightHandSide(ctx); err == nil {
+			graph.rightHandSidesBuiltMu.Lock()
+			graph.rightHandSidesBuilt = append(graph.rightHandSidesBuilt, builtRightHandSide{n, previousRecomputedAt})
+			graph.rightHandSidesBuiltMu.Unlock()
+		}
+	} else {
+		err = nn.maybeStabilize(ctx)
 	}
 	if err != nil {
 		graph.recomputeFailed(n, previousRecomputedAt)
-		for _, eh := range nn.errorHandlers() {
-			eh(ctx, err)
-		}
+		err = graph.nodeErrored(ctx, n, err)
 		return
 	}
 
 	nn.changedAt = graph.stabilizationNum
+	if graph.history != nil {
+		graph.history.noteChangedParallel(n)
+	}
 	if handlers := nn.updateHandlers(); len(handlers) > 0 {
 		graph.queueUpdateHandlers(true, nn.id, handlers)
 	}
 
-	// note we lock recomputeMu rather than the recompute heap's own mutex;
-	// it is the lock that also guards bind structural mutation, so that
-	// reading children's heights/staleness here is mutually exclusive with
-	// a concurrent bind rewriting that same state.
-	graph.recomputeMu.Lock()
-	for _, c := range nn.children {
-		cn := c.Node()
-		if cn.childChangedNotifier != nil {
-			cn.childChangedNotifier.ChildChanged(n)
-		}
-		if shouldRecomputeChild(cn, graph.stabilizationNum) {
-			graph.recomputeHeap.addNodeUnsafe(c)
-		}
-	}
-	graph.recomputeMu.Unlock()
-
 	// recompute observers immediately because logically they're
 	// children of this node but will not have any children themselves.
 	for _, o := range nn.observers {
@@ -1218,6 +1499,7 @@ func (graph *Graph) recomputeNodeParallel(ctx context.Context, n INode) (err err
 			graph.queueUpdateHandlers(true, o.Node().id, handlers)
 		}
 	}
+	changed = true
 	return
 }
 
@@ -1324,13 +1606,70 @@ func (graph *Graph) queueUpdateHandlers(parallel bool, id Identifier, handlers [
 	graph.handleAfterStabilization[id] = handlers
 }
 
-// nodeMutatesStructure reports whether a node's Stabilize can mutate shared
-// graph structure (links, heights, node membership) rather than just computing
-// a value. Today this is exactly the bind left-hand-side change node, which
-// swaps out a bind's right-hand-side subgraph; the invalidation that mutation
-// triggers nests within the same Stabilize call. Such nodes must be serialized
-// against each other (and against the child-enqueue step) on the parallel path.
-func nodeMutatesStructure(n INode) bool {
-	_, ok := n.(IBindChange)
-	return ok
+// rightHandSideBuilder is implemented by the bind left-hand side change node, the one
+// node whose Stabilize mutates shared graph structure -- links, heights, node membership
+// -- rather than just computing a value. Its Stabilize is split in two so that the
+// parallel path can run the expensive half, the bind function, concurrently, and the
+// structural half with no workers running.
+type rightHandSideBuilder interface {
+	buildRightHandSide(context.Context) error
+	linkRightHandSide(context.Context) error
+	// bindMain returns the bind's main node, the one its right-hand side is linked to.
+	bindMain() INode
+}
+
+// builtRightHandSide is a bind change node waiting for linkRightHandSides, with the
+// stamp it had before the pass, which a failure to link puts back.
+type builtRightHandSide struct {
+	n                    INode
+	previousRecomputedAt uint64
+}
+
+// linkRightHandSides links the right-hand sides built by the binds of a parallel batch
+// into the graph, one at a time. It must be called with no workers running.
+//
+// The binds of a batch have nothing to do with each other's scopes, since a bind nested
+// in another is above it and waits for it, but a bind can still return another bind
+// built outside it. Linking the outer one first would leave the inner one unnecessary
+// before it is linked, so they are linked from the lowest main node up, which puts any
+// bind ahead of the binds whose right-hand sides it is under; that is the order a serial
+// pass would reach them in.
+//
+// A bind that fails to link is retried on the next pass, like a node that failed to
+// recompute. All of them are linked regardless, since a bind that has built its
+// right-hand side but not linked it is half way through a swap.
+func (graph *Graph) linkRightHandSides(ctx context.Context) (err error) {
+	built := graph.rightHandSidesBuilt
+	slices.SortStableFunc(built, func(a, b builtRightHandSide) int {
+		return cmp.Compare(
+			a.n.(rightHandSideBuilder).bindMain().Node().height,
+			b.n.(rightHandSideBuilder).bindMain().Node().height,
+		)
+	})
+	rebuilt := make([]INode, 0, len(built))
+	for _, b := range built {
+		if linkErr := graph.linkRightHandSide(ctx, b.n, b.previousRecomputedAt); linkErr != nil && err == nil {
+			err = linkErr
+		}
+		rebuilt = append(rebuilt, b.n)
+	}
+	if len(rebuilt) > 0 {
+		graph.checkInvariantsAfterBindRebuild(rebuilt...)
+	}
+	clear(built)
+	graph.rightHandSidesBuilt = built[:0]
+	return
+}
+
+func (graph *Graph) linkRightHandSide(ctx context.Context, n INode, previousRecomputedAt uint64) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = graph.recomputePanicked(ctx, n, r)
+		}
+	}()
+	if err = n.(rightHandSideBuilder).linkRightHandSide(ctx); err != nil {
+		graph.recomputeFailed(n, previousRecomputedAt)
+		err = graph.nodeErrored(ctx, n, err)
+	}
+	return
 }
@@ -2,6 +2,7 @@ package incr
 
 import (
 	"context"
+	"math"
 	"runtime"
 	"testing"
 
@@ -25,6 +26,65 @@ func Test_New_options_MaxHeight(t *testing.T) {
 	testutil.NotEqual(t, 1024, DefaultMaxHeight)
 	testutil.Equal(t, 1024, len(g.recomputeHeap.heights))
 	testutil.Equal(t, 1024, len(g.adjustHeightsHeap.nodesByHeight))
+	testutil.Equal(t, 1023, g.adjustHeightsHeap.maxHeightAllowed())
+}
+
+func Test_New_options_MaxHeight_unset(t *testing.T) {
+	g := New()
+	testutil.Equal(t, DefaultHeights, len(g.recomputeHeap.heights))
+	testutil.Equal(t, DefaultHeights, len(g.adjustHeightsHeap.nodesByHeight))
+	testutil.Equal(t, math.MaxInt, g.adjustHeightsHeap.maxHeightAllowed())
+}
+
+func Test_Graph_deepChain_growsHeaps(t *testing.T) {
+	ctx := testContext()
+	g := New()
+	v := Var(g, 1)
+	var n Incr[int] = v
+	for range 2 * DefaultHeights {
+		n = Map(g, n, func(x int) int { return x + 1 })
+	}
+	o := MustObserve(g, n)
+	testutil.Equal(t, true, n.Node().height > DefaultHeights)
+
+	err := g.Stabilize(ctx)
+	testutil.NoError(t, err)
+	testutil.Equal(t, 2*DefaultHeights+1, o.Value())
+	testutil.Equal(t, true, len(g.recomputeHeap.heights) > DefaultHeights)
+
+	v.Set(2)
+	err = g.Stabilize(ctx)
+	testutil.NoError(t, err)
+	testutil.Equal(t, 2*DefaultHeights+2, o.Value())
+
+	v.Set(3)
+	err = g.ParallelStabilize(ctx)
+	testutil.NoError(t, err)
+	testutil.Equal(t, 2*DefaultHeights+3, o.Value())
+}
+
+func Test_Graph_deepBindChain_growsHeaps(t *testing.T) {
+	ctx := testContext()
+	g := New()
+	v := Var(g, 1)
+	var n Incr[int] = v
+	for range DefaultHeights {
+		input := n
+		n = Bind(g, input, func(bs Scope, x int) Incr[int] {
+			return Return(bs, x+1)
+		})
+	}
+	o := MustObserve(g, n)
+
+	err := g.Stabilize(ctx)
+	testutil.NoError(t, err)
+	testutil.Equal(t, DefaultHeights+1, o.Value())
+	testutil.Equal(t, true, n.Node().height > DefaultHeights)
+
+	v.Set(10)
+	err = g.Stabilize(ctx)
+	testutil.NoError(t, err)
+	testutil.Equal(t, DefaultHeights+10, o.Value())
 }
 
 func Test_New_options_Parallelism(t *testing.T) {
@@ -0,0 +1,145 @@
+package incr
+
+import (
+	"slices"
+	"sync"
+)
+
+// OptGraphHistory has the graph remember the values of the nodes that changed in each
+// of its last depth stabilizations, so that [Graph.At] can show the graph as any of
+// those stabilizations left it.
+//
+// Each pass costs a read of every node that changed in it, through reflection, and the
+// memory to hold those values until the pass falls out of the history. A depth of zero,
+// the default, keeps no history.
+func OptGraphHistory(depth int) func(*GraphOptions) {
+	return func(g *GraphOptions) {
+		g.HistoryDepth = depth
+	}
+}
+
+// history is the graph's record of its recent stabilizations; see [OptGraphHistory].
+type history struct {
+	// changedMu interlocks access to changed, which parallel workers append to.
+	changedMu sync.Mutex
+	// changed are the nodes that have changed in the pass so far.
+	changed []INode
+	// passes is a ring of the last passes, the oldest at start.
+	passes []historyPass
+	start  int
+}
+
+// historyPass is what a single stabilization changed: the values it left each node that
+// changed in it, and each observer of those nodes, by identifier.
+type historyPass struct {
+	stabilizationNum uint64
+	values           map[Identifier]any
+}
+
+func newHistory(depth int) *history {
+	if depth <= 0 {
+		return nil
+	}
+	return &history{
+		passes: make([]historyPass, 0, depth),
+	}
+}
+
+func (h *history) noteChangedParallel(n INode) {
+	h.changedMu.Lock()
+	h.changed = append(h.changed, n)
+	h.changedMu.Unlock()
+}
+
+// capture ends a pass, reading the values it left the nodes that changed in it into the
+// ring, over the oldest pass if the ring is full.
+func (h *history) capture(stabilizationNum uint64) {
+	pass := historyPass{stabilizationNum: stabilizationNum}
+	if len(h.changed) > 0 {
+		pass.values = make(map[Identifier]any, len(h.changed))
+		for _, n := range h.changed {
+			value := nodeValue(n)
+			nn := n.Node()
+			pass.values[nn.id] = value
+			for _, o := range nn.observers {
+				pass.values[o.Node().id] = value
+			}
+		}
+		clear(h.changed)
+		h.changed = h.changed[:0]
+	}
+	if len(h.passes) < cap(h.passes) {
+		h.passes = append(h.passes, pass)
+		return
+	}
+	h.passes[h.start] = pass
+	h.start = (h.start + 1) % len(h.passes)
+}
+
+// At returns a view of the graph as a past stabilization left it, from the history kept
+// with [OptGraphHistory]: every observer's value, for [ViewValue], and every node's, for
+// [ViewNodeValue]. It reports false if the graph keeps no history, or the stabilization
+// has not happened yet or has fallen out of the history.
+//
+// A node that has not changed since the stabilization asked for is given its value now,
+// which is its value then too. A node that changed since, but not in any stabilization
+// still in the history up to and including the one asked for, last changed before the
+// history begins, and is left out of the view.
+//
+// At reads the graph's nodes, so unlike [Graph.View] it must not be called while the
+// graph is stabilizing. The view it returns can be read from anywhere, with the same
+// caveat about values mutated in place.
+func (graph *Graph) At(stabilizationNum uint64) (*View, bool) {
+	h := graph.history
+	if h == nil || len(h.passes) == 0 {
+		return nil, false
+	}
+	ordered := make([]historyPass, 0, len(h.passes))
+	ordered = append(ordered, h.passes[h.start:]...)
+	ordered = append(ordered, h.passes[:h.start]...)
+	if stabilizationNum < ordered[0].stabilizationNum || stabilizationNum > ordered[len(ordered)-1].stabilizationNum {
+		return nil, false
+	}
+	split, _ := slices.BinarySearchFunc(ordered, stabilizationNum+1, func(p historyPass, num uint64) int {
+		switch {
+		case p.stabilizationNum < num:
+			return -1
+		case p.stabilizationNum > num:
+			return 1
+		}
+		return 0
+	})
+	before, after := ordered[:split], ordered[split:]
+
+	changedSince := make(map[Identifier]struct{})
+	for _, pass := range after {
+		for id := range pass.values {
+			changedSince[id] = struct{}{}
+		}
+	}
+	values := make(map[Identifier]any)
+	graph.nodesMu.Lock()
+	for _, n := range graph.nodes {
+		id := n.Node().id
+		if _, ok := changedSince[id]; !ok {
+			values[id] = nodeValue(n)
+		}
+	}
+	graph.nodesMu.Unlock()
+	graph.observersMu.Lock()
+	for id, o := range graph.observers {
+		if _, ok := changedSince[id]; ok {
+			continue
+		}
+		if vo, ok := o.(viewObserver); ok {
+			values[id], _ = vo.viewValue()
+		}
+	}
+	graph.observersMu.Unlock()
+	for _, pass := range before {
+		for id, value := range pass.values {
+			values[id] = value
+		}
+	}
+	return &View{stabilizationNum: stabilizationNum, values: values}, true
+}
@@ -0,0 +1,118 @@
+package incr
+
+import (
+	"testing"
+
+	"github.com/wcharczuk/go-incr/testutil"
+)
+
+func Test_Graph_At(t *testing.T) {
+	ctx := testContext()
+	g := New(OptGraphHistory(3))
+
+	v := Var(g, 1)
+	m := Map(g, v, func(x int) int { return x * 10 })
+	o := MustObserve(g, m)
+	// never changes after the first pass
+	w := Var(g, "fixed")
+	ow := MustObserve(g, w)
+
+	for i := 1; i <= 4; i++ {
+		v.Set(i)
+		testutil.NoError(t, g.Stabilize(ctx))
+	}
+	testutil.Equal(t, 40, o.Value())
+
+	for num, expected := range map[uint64]int{2: 20, 3: 30, 4: 40} {
+		view, ok := g.At(num)
+		testutil.Equal(t, true, ok)
+		testutil.Equal(t, num, view.StabilizationNum())
+
+		value, ok := ViewValue(view, o)
+		testutil.Equal(t, true, ok)
+		testutil.Equal(t, expected, value)
+
+		value, ok = ViewNodeValue(view, m)
+		testutil.Equal(t, true, ok)
+		testutil.Equal(t, expected, value)
+
+		value, ok = ViewNodeValue(view, v)
+		testutil.Equal(t, true, ok)
+		testutil.Equal(t, expected/10, value)
+
+		fixed, ok := ViewValue(view, ow)
+		testutil.Equal(t, true, ok)
+		testutil.Equal(t, "fixed", fixed)
+	}
+
+	_, ok := g.At(1)
+	testutil.Equal(t, false, ok, "the first pass has fallen out of the history")
+	_, ok = g.At(5)
+	testutil.Equal(t, false, ok, "the fifth pass has not happened")
+}
+
+func Test_Graph_At_changedBeforeHistory(t *testing.T) {
+	ctx := testContext()
+	g := New(OptGraphHistory(2))
+
+	v := Var(g, "first")
+	o := MustObserve(g, v)
+	tick := Var(g, 0)
+	_ = MustObserve(g, tick)
+
+	// v changes in the first pass and the fourth; the history holds the third and fourth
+	testutil.NoError(t, g.Stabilize(ctx))
+	for i := 1; i <= 2; i++ {
+		tick.Set(i)
+		testutil.NoError(t, g.Stabilize(ctx))
+	}
+	v.Set("fourth")
+	testutil.NoError(t, g.Stabilize(ctx))
+
+	view, ok := g.At(3)
+	testutil.Equal(t, true, ok)
+	_, ok = ViewValue(view, o)
+	testutil.Equal(t, false, ok, "the value v had at the third pass was set before the history begins")
+	value, ok := ViewNodeValue(view, tick)
+	testutil.Equal(t, true, ok)
+	testutil.Equal(t, 2, value)
+
+	view, ok = g.At(4)
+	testutil.Equal(t, true, ok)
+	current, ok := ViewValue(view, o)
+	testutil.Equal(t, true, ok)
+	testutil.Equal(t, "fourth", current)
+}
+
+func Test_Graph_At_parallel(t *testing.T) {
+	ctx := testContext()
+	g := New(OptGraphHistory(4), OptGraphParallelism(4))
+
+	v := Var(g, 1)
+	var observers []ObserveIncr[int]
+	for i := range 8 {
+		observers = append(observers, MustObserve(g, Map(g, v, func(x int) int { return x + i })))
+	}
+	for i := 1; i <= 3; i++ {
+		v.Set(i * 100)
+		testutil.NoError(t, g.ParallelStabilize(ctx))
+	}
+
+	view, ok := g.At(2)
+	testutil.Equal(t, true, ok)
+	for i, o := range observers {
+		value, ok := ViewValue(view, o)
+		testutil.Equal(t, true, ok)
+		testutil.Equal(t, 200+i, value)
+	}
+}
+
+func Test_Graph_At_noHistory(t *testing.T) {
+	ctx := testContext()
+	g := New()
+	_ = MustObserve(g, Var(g, 1))
+	testutil.NoError(t, g.Stabilize(ctx))
+
+	_, ok := g.At(1)
+	testutil.Equal(t, false, ok)
+}
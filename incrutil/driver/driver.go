@@ -0,0 +1,319 @@
+// Package driver runs a graph on a goroutine of its own and accepts changes to it from
+// any other.
+//
+// A [incr.Graph] is not safe to change from one goroutine while another stabilizes it,
+// and [incr.VarIncr.Set] from a second goroutine races with the pass reading the var.
+// Programs fed by several goroutines therefore end up writing the same wrapper: a
+// mutex or a channel in front of the graph, one loop that stabilizes, and some way of
+// telling callers what came out. A [Driver] is that wrapper.
+//
+// The driver owns the graph while [Driver.Run] is running. Other goroutines hand it
+// changes with [Set], [Update] and [Driver.Do], which queue and return immediately.
+// Changes to the same var coalesce while queued, so a var set a thousand times between
+// two stabilizations costs one set; the driver stabilizes once per batch, no later than
+// the configured latency after the first change in it; and [Watch] delivers an
+// observer's new value after each stabilization that changed it, on the driver's
+// goroutine, once the pass is over and the graph can be changed again.
+package driver
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/wcharczuk/go-incr"
+)
+
+// ErrStopped is returned by the functions queueing work for a driver whose Run has
+// returned.
+var ErrStopped = errors.New("driver: stopped")
+
+// Driver serializes changes to a graph onto a single goroutine.
+//
+// Create one with [New] and start it with [Driver.Run].
+type Driver struct {
+	graph   *incr.Graph
+	options Options
+
+	mu      sync.Mutex
+	stopped bool
+	// queue is the pending batch in arrival order. A change to a var already queued
+	// replaces or extends its entry, found through queued, and moves it to the end,
+	// leaving behind an entry with no work that counts towards moved.
+	queue   []queued
+	queued  map[incr.Identifier]int
+	moved   int
+	waiters []chan error
+	// wake is signalled when the first change of a batch arrives, and full when the
+	// batch reaches the configured size.
+	wake chan struct{}
+	full chan struct{}
+
+	// deliveries are the watch callbacks the current pass has triggered; only the
+	// driver's goroutine touches them.
+	deliveries []func()
+}
+
+// queued is one entry in a batch: a change to a var, keyed by the var's identifier, or
+// arbitrary work from [Driver.Do], which is never coalesced.
+type queued struct {
+	id incr.Identifier
+	// apply is nil once the entry has moved to the end of the queue.
+	apply func()
+}
+
+// Options are the settings of a [Driver].
+type Options struct {
+	// MaxLatency is how long the driver waits, after the first change of a batch
+	// arrives, for more to join it before stabilizing. Zero stabilizes as soon as the
+	// driver is free, which still batches whatever arrived while it was busy.
+	MaxLatency time.Duration
+	// MaxBatch stabilizes early once this many distinct changes are queued, whatever
+	// the latency. Zero means no limit.
+	MaxBatch int
+	// Parallel stabilizes with [incr.Graph.ParallelStabilize].
+	Parallel bool
+	// OnError is called with each error a stabilization returns. The driver carries on
+	// either way; the error is also returned to whoever waits on the pass with
+	// [Driver.Sync].
+	OnError func(context.Context, error)
+}
+
+// Option mutates [Options].
+type Option func(*Options)
+
+// OptMaxLatency sets [Options.MaxLatency].
+func OptMaxLatency(latency time.Duration) Option {
+	return func(o *Options) { o.MaxLatency = latency }
+}
+
+// OptMaxBatch sets [Options.MaxBatch].
+func OptMaxBatch(size int) Option {
+	return func(o *Options) { o.MaxBatch = size }
+}
+
+// OptParallel sets [Options.Parallel].
+func OptParallel(parallel bool) Option {
+	return func(o *Options) { o.Parallel = parallel }
+}
+
+// OptOnError sets [Options.OnError].
+func OptOnError(handler func(context.Context, error)) Option {
+	return func(o *Options) { o.OnError = handler }
+}
+
+// New returns a driver for a graph. Nothing else may stabilize or change the graph
+// while the driver's Run is running.
+func New(graph *incr.Graph, opts ...Option) *Driver {
+	d := &Driver{
+		graph:  graph,
+		queued: make(map[incr.Identifier]int),
+		wake:   make(chan struct{}, 1),
+		full:   make(chan struct{}, 1),
+	}
+	for _, opt := range opts {
+		opt(&d.options)
+	}
+	return d
+}
+
+// Graph returns the driven graph. It must only be touched from the driver's goroutine,
+// which is to say from within [Driver.Do] or a [Watch] callback.
+func (d *Driver) Graph() *incr.Graph { return d.graph }
+
+// Set queues setting a var. It replaces any set of the same var still queued, and
+// takes its place after everything queued since.
+func Set[T any](d *Driver, v incr.VarIncr[T], value T) error {
+	return d.enqueue(v.Node().ID(), func(func()) func() {
+		return func() { v.Set(value) }
+	})
+}
+
+// Update queues updating a var from its current value. Updates queued for the same var
+// compose in order, and an update queued after a set applies to the value set. As with
+// [Set], the composed change runs after everything queued in between.
+func Update[T any](d *Driver, v incr.VarIncr[T], fn func(T) T) error {
+	return d.enqueue(v.Node().ID(), func(previous func()) func() {
+		if previous == nil {
+			return func() { v.Update(fn) }
+		}
+		return func() {
+			previous()
+			v.Update(fn)
+		}
+	})
+}
+
+// Do queues arbitrary work to run on the driver's goroutine before the next
+// stabilization, in order with the other changes queued. This is how to build or
+// observe nodes on a graph the driver is running.
+func (d *Driver) Do(fn func(*incr.Graph)) error {
+	return d.enqueue(incr.Identifier{}, func(func()) func() {
+		return func() { fn(d.graph) }
+	})
+}
+
+// Watch calls fn with an observer's value after each stabilization that changed it.
+//
+// The callback runs on the driver's goroutine once the pass is over, so it may queue
+// further changes -- they go into the next batch -- and may read the graph, but must not
+// block on the driver, for example with [Driver.Sync].
+func Watch[T any](d *Driver, o incr.ObserveIncr[T], fn func(T)) error {
+	return d.Do(func(*incr.Graph) {
+		o.OnUpdate(func(_ context.Context, value T) {
+			d.deliveries = append(d.deliveries, func() { fn(value) })
+		})
+	})
+}
+
+// Sync waits until everything queued before it has been applied and stabilized, and
+// returns the stabilization's error.
+func (d *Driver) Sync(ctx context.Context) error {
+	done := make(chan error, 1)
+	d.mu.Lock()
+	if d.stopped {
+		d.mu.Unlock()
+		return ErrStopped
+	}
+	d.waiters = append(d.waiters, done)
+	d.mu.Unlock()
+	d.signal(d.wake)
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Run drives the graph until the context is cancelled, returning the context's error.
+//
+// Changes still queued when it returns are discarded, and everything that queues
+// afterwards returns [ErrStopped].
+func (d *Driver) Run(ctx context.Context) error {
+	defer d.stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-d.wake:
+		}
+		if err := d.wait(ctx); err != nil {
+			return err
+		}
+		d.pass(ctx)
+	}
+}
+
+// wait holds a batch open for up to the configured latency, or until it fills.
+func (d *Driver) wait(ctx context.Context) error {
+	if d.options.MaxLatency <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(d.options.MaxLatency)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+	case <-d.full:
+	}
+	return nil
+}
+
+// pass applies one batch, stabilizes, and delivers the results.
+func (d *Driver) pass(ctx context.Context) {
+	d.mu.Lock()
+	batch, waiters := d.queue, d.waiters
+	d.queue, d.waiters = nil, nil
+	clear(d.queued)
+	d.moved = 0
+	d.mu.Unlock()
+	if len(batch) == 0 && len(waiters) == 0 {
+		// a wake left over from changes an earlier pass already took
+		return
+	}
+	// a full signal left over from this batch would cut the next one short
+	select {
+	case <-d.full:
+	default:
+	}
+
+	for _, entry := range batch {
+		if entry.apply != nil {
+			entry.apply()
+		}
+	}
+	var err error
+	if d.options.Parallel {
+		err = d.graph.ParallelStabilize(ctx)
+	} else {
+		err = d.graph.Stabilize(ctx)
+	}
+	if err != nil && d.options.OnError != nil {
+		d.options.OnError(ctx, err)
+	}
+	deliveries := d.deliveries
+	d.deliveries = nil
+	for _, deliver := range deliveries {
+		deliver()
+	}
+	for _, waiter := range waiters {
+		waiter <- err
+	}
+}
+
+// enqueue adds to the pending batch. next builds the entry's work from the work already
+// queued for the same var, which is nil if there is none.
+func (d *Driver) enqueue(id incr.Identifier, next func(previous func()) func()) error {
+	d.mu.Lock()
+	if d.stopped {
+		d.mu.Unlock()
+		return ErrStopped
+	}
+	var previous func()
+	if index, ok := d.queued[id]; ok && !id.IsZero() {
+		previous = d.queue[index].apply
+		if index == len(d.queue)-1 {
+			d.queue = d.queue[:index]
+		} else {
+			// moving to the end keeps the entry in order with work queued since, which
+			// may read the var
+			d.queue[index].apply = nil
+			d.moved++
+		}
+	}
+	if !id.IsZero() {
+		d.queued[id] = len(d.queue)
+	}
+	d.queue = append(d.queue, queued{id: id, apply: next(previous)})
+	filled := d.options.MaxBatch > 0 && len(d.queue)-d.moved >= d.options.MaxBatch
+	d.mu.Unlock()
+
+	d.signal(d.wake)
+	if filled {
+		d.signal(d.full)
+	}
+	return nil
+}
+
+func (d *Driver) signal(c chan struct{}) {
+	select {
+	case c <- struct{}{}:
+	default:
+	}
+}
+
+func (d *Driver) stop() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.stopped = true
+	d.queue = nil
+	clear(d.queued)
+	d.moved = 0
+	for _, waiter := range d.waiters {
+		waiter <- ErrStopped
+	}
+	d.waiters = nil
+}
@@ -0,0 +1,194 @@
+package driver
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/wcharczuk/go-incr"
+	"github.com/wcharczuk/go-incr/testutil"
+)
+
+// start runs a driver for the length of a test.
+func start(t *testing.T, d *Driver) {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- d.Run(ctx) }()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+}
+
+func Test_Driver(t *testing.T) {
+	ctx := context.Background()
+	g := incr.New()
+	a, b := incr.Var(g, 0), incr.Var(g, 0)
+	sum := incr.MustObserve(g, incr.Map2(g, a, b, func(x, y int) int { return x + y }))
+
+	d := New(g)
+	start(t, d)
+
+	// many goroutines at once, which would race on the vars without the driver
+	var wg sync.WaitGroup
+	for range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range 100 {
+				testutil.NoError(t, Update(d, a, func(v int) int { return v + 1 }))
+				testutil.NoError(t, Update(d, b, func(v int) int { return v + 2 }))
+			}
+		}()
+	}
+	wg.Wait()
+	testutil.NoError(t, d.Sync(ctx))
+
+	var got int
+	testutil.NoError(t, d.Do(func(*incr.Graph) { got = sum.Value() }))
+	testutil.NoError(t, d.Sync(ctx))
+	testutil.Equal(t, 8*100*3, got)
+}
+
+func Test_Driver_coalesces(t *testing.T) {
+	ctx := context.Background()
+	g := incr.New()
+	v := incr.Var(g, 0)
+	var recomputes int
+	o := incr.MustObserve(g, incr.Map(g, v, func(x int) int {
+		recomputes++
+		return x
+	}))
+
+	d := New(g, OptMaxLatency(50*time.Millisecond))
+	start(t, d)
+	for value := range 1000 {
+		testutil.NoError(t, Set(d, v, value))
+	}
+	testutil.NoError(t, d.Sync(ctx))
+
+	var got int
+	testutil.NoError(t, d.Do(func(*incr.Graph) { got = o.Value() }))
+	testutil.NoError(t, d.Sync(ctx))
+	testutil.Equal(t, 999, got)
+	if recomputes > 3 {
+		t.Fatalf("1000 sets inside one latency window recomputed %d times", recomputes)
+	}
+}
+
+func Test_Driver_coalescedOrder(t *testing.T) {
+	ctx := context.Background()
+	g := incr.New()
+	v := incr.Var(g, 0)
+	incr.MustObserve(g, v)
+
+	d := New(g)
+	start(t, d)
+
+	// hold the driver in a pass so that what follows lands in one batch
+	running, release := make(chan struct{}), make(chan struct{})
+	testutil.NoError(t, d.Do(func(*incr.Graph) {
+		close(running)
+		<-release
+	}))
+	<-running
+
+	// a set coalesced into an earlier one still runs after the work queued in between
+	var seen int
+	testutil.NoError(t, Set(d, v, 1))
+	testutil.NoError(t, d.Do(func(*incr.Graph) { seen = v.Value() }))
+	testutil.NoError(t, Set(d, v, 2))
+	close(release)
+	testutil.NoError(t, d.Sync(ctx))
+	testutil.Equal(t, 0, seen)
+
+	testutil.NoError(t, d.Do(func(*incr.Graph) { seen = v.Value() }))
+	testutil.NoError(t, d.Sync(ctx))
+	testutil.Equal(t, 2, seen)
+}
+
+func Test_Driver_maxBatch(t *testing.T) {
+	g := incr.New()
+	vars := make([]incr.VarIncr[int], 10)
+	for index := range vars {
+		vars[index] = incr.Var(g, 0)
+		incr.MustObserve(g, vars[index])
+	}
+	var passes int
+	g.OnStabilizationEnd(func(context.Context, time.Time, error) { passes++ })
+
+	// a latency long enough that only the batch size can trigger the pass
+	d := New(g, OptMaxLatency(time.Hour), OptMaxBatch(len(vars)))
+	start(t, d)
+
+	delivered := make(chan int, 1)
+	o := incr.MustObserve(g, vars[len(vars)-1])
+	testutil.NoError(t, Watch(d, o, func(value int) { delivered <- value }))
+	for index, v := range vars {
+		testutil.NoError(t, Set(d, v, index+1))
+	}
+	select {
+	case value := <-delivered:
+		testutil.Equal(t, len(vars), value)
+	case <-time.After(5 * time.Second):
+		t.Fatal("a full batch did not stabilize")
+	}
+}
+
+func Test_Driver_watch(t *testing.T) {
+	ctx := context.Background()
+	g := incr.New()
+	v := incr.Var(g, 1)
+	doubled := incr.MustObserve(g, incr.Map(g, v, func(x int) int { return x * 2 }))
+
+	d := New(g)
+	start(t, d)
+
+	var seen []int
+	testutil.NoError(t, Watch(d, doubled, func(value int) {
+		seen = append(seen, value)
+		// a watcher may queue more work; it lands in the next batch
+		if value < 8 {
+			testutil.NoError(t, Set(d, v, value))
+		}
+	}))
+	testutil.NoError(t, d.Sync(ctx))
+	for range 3 {
+		testutil.NoError(t, d.Sync(ctx))
+	}
+	testutil.NoError(t, d.Do(func(*incr.Graph) {}))
+	testutil.NoError(t, d.Sync(ctx))
+	testutil.Equal(t, []int{2, 4, 8}, seen)
+}
+
+func Test_Driver_errors(t *testing.T) {
+	ctx := context.Background()
+	g := incr.New()
+	v := incr.Var(g, 0)
+	incr.MustObserve(g, incr.MapContext(g, v, func(_ context.Context, x int) (int, error) {
+		if x < 0 {
+			return 0, errors.New("negative")
+		}
+		return x, nil
+	}))
+
+	var reported []error
+	d := New(g, OptOnError(func(_ context.Context, err error) { reported = append(reported, err) }))
+	ctx, cancel := context.WithCancel(ctx)
+	done := make(chan error, 1)
+	go func() { done <- d.Run(ctx) }()
+
+	testutil.NoError(t, Set(d, v, -1))
+	testutil.Error(t, d.Sync(context.Background()))
+	testutil.NoError(t, Set(d, v, 1))
+	testutil.NoError(t, d.Sync(context.Background()))
+
+	cancel()
+	testutil.Equal(t, true, errors.Is(<-done, context.Canceled))
+	testutil.Equal(t, 1, len(reported))
+	testutil.Equal(t, true, errors.Is(Set(d, v, 2), ErrStopped))
+	testutil.Equal(t, true, errors.Is(d.Sync(context.Background()), ErrStopped))
+}
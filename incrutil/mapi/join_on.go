@@ -0,0 +1,207 @@
+package mapi
+
+import (
+	"cmp"
+	"context"
+	"slices"
+
+	"github.com/wcharczuk/go-incr"
+	"github.com/wcharczuk/go-incr/incrutil/pmap"
+)
+
+// JoinKind selects which left keys a [JoinOn] keeps.
+type JoinKind uint8
+
+const (
+	// JoinInner keeps a left key only when it is present and at least one right entry
+	// refers to it.
+	JoinInner JoinKind = iota
+	// JoinLeft keeps every present left key, with or without right entries referring
+	// to it.
+	JoinLeft
+	// JoinOuter keeps every left key that is present or referred to, so that right
+	// entries whose foreign key has no left entry still appear.
+	JoinOuter
+)
+
+func (k JoinKind) String() string {
+	switch k {
+	case JoinInner:
+		return "inner"
+	case JoinLeft:
+		return "left"
+	case JoinOuter:
+		return "outer"
+	default:
+		return "unknown"
+	}
+}
+
+// JoinRow is what a left key looks like after a join: the left entry, if present, and
+// every right entry whose foreign key refers to it.
+type JoinRow[L any, RK cmp.Ordered, R any] struct {
+	Left    L
+	HasLeft bool
+	Right   pmap.Map[RK, R]
+}
+
+// JoinOn joins two incremental maps where the right map refers to the left one through
+// a foreign key, recomputing only the rows either side's changes reach.
+//
+// [Merge] lines two maps up by identical keys; this is the relational case, where the
+// right map is keyed differently -- orders keyed by order, referring to customers keyed
+// by customer -- and foreignKey reads the left key out of a right entry. A right entry
+// refers to at most one left key, so every joined row has a well defined owner, and the
+// result is keyed by the left key with the referring right entries held in a
+// [pmap.Map] of their own. kind decides which left keys appear; fn builds the output
+// value for each.
+//
+// The node keeps an index from each left key to the right entries referring to it, so
+// neither side is rescanned:
+//
+//   - a left entry changing recomputes its own row, O(log n).
+//   - a right entry changing recomputes the row it refers to, and the row it used to
+//     refer to if its foreign key moved, O(log n) each.
+//
+// equalRight must report a change whenever foreignKey would; passing nil treats every
+// right entry present on both sides of a pass as unchanged, which is only correct when
+// right entries are never rebound.
+func JoinOn[LK, RK cmp.Ordered, L, R, C any](
+	scope incr.Scope,
+	left incr.Incr[pmap.Map[LK, L]],
+	right incr.Incr[pmap.Map[RK, R]],
+	foreignKey func(RK, R) LK,
+	kind JoinKind,
+	equalLeft func(a, b L) bool,
+	equalRight func(a, b R) bool,
+	fn func(LK, JoinRow[L, RK, R]) C,
+) incr.Incr[pmap.Map[LK, C]] {
+	j := &joinOnIncr[LK, RK, L, R, C]{
+		n:          incr.NewNode("mapi_join_on"),
+		left:       left,
+		right:      right,
+		foreignKey: foreignKey,
+		kind:       kind,
+		equalLeft:  equalLeft,
+		equalRight: equalRight,
+		fn:         fn,
+	}
+	j.parents[0] = left
+	j.parents[1] = right
+	return incr.WithinScope(scope, j)
+}
+
+var (
+	_ incr.Incr[pmap.Map[string, int]] = (*joinOnIncr[string, int, int, int, int])(nil)
+	_ incr.IStabilize                  = (*joinOnIncr[string, int, int, int, int])(nil)
+	_ incr.IParents                    = (*joinOnIncr[string, int, int, int, int])(nil)
+)
+
+type joinOnIncr[LK, RK cmp.Ordered, L, R, C any] struct {
+	n          *incr.Node
+	left       incr.Incr[pmap.Map[LK, L]]
+	right      incr.Incr[pmap.Map[RK, R]]
+	foreignKey func(RK, R) LK
+	kind       JoinKind
+	equalLeft  func(a, b L) bool
+	equalRight func(a, b R) bool
+	fn         func(LK, JoinRow[L, RK, R]) C
+	lastLeft   pmap.Map[LK, L]
+	lastRight  pmap.Map[RK, R]
+	// byLeft is the index: for each left key, the right entries currently referring to
+	// it. A key with no referring entries is absent rather than bound to an empty map.
+	byLeft  pmap.Map[LK, pmap.Map[RK, R]]
+	value   pmap.Map[LK, C]
+	parents [2]incr.INode
+	// touched is reused between passes to collect the rows needing recomputation; see
+	// the field of the same name on [Merge]'s node.
+	touched []LK
+}
+
+func (j *joinOnIncr[LK, RK, L, R, C]) Parents() []incr.INode { return j.parents[:] }
+
+func (j *joinOnIncr[LK, RK, L, R, C]) Node() *incr.Node { return j.n }
+
+func (j *joinOnIncr[LK, RK, L, R, C]) Value() pmap.Map[LK, C] { return j.value }
+
+func (j *joinOnIncr[LK, RK, L, R, C]) Stabilize(_ context.Context) error {
+	currentLeft, currentRight := j.left.Value(), j.right.Value()
+
+	j.touched = j.touched[:0]
+	for change := range j.lastLeft.SymmetricDiff(currentLeft, j.equalLeft) {
+		j.touched = append(j.touched, change.Key)
+	}
+	// The index is maintained from the right diff alone. A removed or rebound entry
+	// carries its old value, which is enough to find the row it used to refer to, so no
+	// reverse index from right key to left key is needed.
+	for change := range j.lastRight.SymmetricDiff(currentRight, j.equalRight) {
+		if change.Kind != pmap.ChangeAdded {
+			previous := j.foreignKey(change.Key, change.Old)
+			j.unindex(previous, change.Key)
+			j.touched = append(j.touched, previous)
+		}
+		if change.Kind != pmap.ChangeRemoved {
+			next := j.foreignKey(change.Key, change.New)
+			j.index(next, change.Key, change.New)
+			j.touched = append(j.touched, next)
+		}
+	}
+
+	out := j.value
+	// a row can be reached from both sides, or twice from the right when an entry
+	// moved between two rows and back; see [Merge] for why this sorts and skips.
+	slices.Sort(j.touched)
+	var previous LK
+	for index, key := range j.touched {
+		if index > 0 && key == previous {
+			continue
+		}
+		previous = key
+
+		var row JoinRow[L, RK, R]
+		row.Left, row.HasLeft = currentLeft.Get(key)
+		row.Right, _ = j.byLeft.Get(key)
+		if !j.keep(row) {
+			out = out.Delete(key)
+			continue
+		}
+		out = out.Set(key, j.fn(key, row))
+	}
+
+	j.value = out
+	j.lastLeft = currentLeft
+	j.lastRight = currentRight
+	return nil
+}
+
+// keep reports whether a row belongs in the output for the join's kind.
+func (j *joinOnIncr[LK, RK, L, R, C]) keep(row JoinRow[L, RK, R]) bool {
+	switch j.kind {
+	case JoinInner:
+		return row.HasLeft && row.Right.Len() > 0
+	case JoinLeft:
+		return row.HasLeft
+	default:
+		return row.HasLeft || row.Right.Len() > 0
+	}
+}
+
+func (j *joinOnIncr[LK, RK, L, R, C]) index(leftKey LK, rightKey RK, value R) {
+	group, _ := j.byLeft.Get(leftKey)
+	j.byLeft = j.byLeft.Set(leftKey, group.Set(rightKey, value))
+}
+
+func (j *joinOnIncr[LK, RK, L, R, C]) unindex(leftKey LK, rightKey RK) {
+	group, ok := j.byLeft.Get(leftKey)
+	if !ok {
+		return
+	}
+	group = group.Delete(rightKey)
+	if group.Len() == 0 {
+		j.byLeft = j.byLeft.Delete(leftKey)
+		return
+	}
+	j.byLeft = j.byLeft.Set(leftKey, group)
+}
+
+func (j *joinOnIncr[LK, RK, L, R, C]) String() string { return j.n.String() }
@@ -0,0 +1,224 @@
+package mapi
+
+import (
+	"context"
+	"math/rand"
+	"testing"
+
+	"github.com/wcharczuk/go-incr"
+	"github.com/wcharczuk/go-incr/incrutil/pmap"
+)
+
+// order is a right-hand entry referring to a customer by id.
+type order struct {
+	customer string
+	size     int
+}
+
+func ordersEqual(a, b order) bool { return a == b }
+
+func orderCustomer(_ int, o order) string { return o.customer }
+
+// joinTotal sums the sizes of a row's orders, tagging rows with no customer as negative
+// so the tests can tell the three kinds of row apart.
+func joinTotal(_ string, row JoinRow[int, int, order]) int {
+	total := 0
+	for _, o := range row.Right.All() {
+		total += o.size
+	}
+	if !row.HasLeft {
+		return -total
+	}
+	return row.Left*1000 + total
+}
+
+func Test_JoinOn(t *testing.T) {
+	ctx := context.Background()
+	g := incr.New()
+
+	customers := pmap.FromGoMap(map[string]int{"ann": 1, "bob": 2, "cat": 3})
+	orders := pmap.FromGoMap(map[int]order{
+		1: {customer: "ann", size: 10},
+		2: {customer: "ann", size: 5},
+		3: {customer: "bob", size: 7},
+		4: {customer: "dan", size: 4},
+	})
+	lv := incr.Var(g, customers)
+	rv := incr.Var(g, orders)
+
+	inner := incr.MustObserve(g, JoinOn(g, lv, rv, orderCustomer, JoinInner, intsEqual, ordersEqual, joinTotal))
+	left := incr.MustObserve(g, JoinOn(g, lv, rv, orderCustomer, JoinLeft, intsEqual, ordersEqual, joinTotal))
+	outer := incr.MustObserve(g, JoinOn(g, lv, rv, orderCustomer, JoinOuter, intsEqual, ordersEqual, joinTotal))
+
+	check := func(label string, o incr.ObserveIncr[pmap.Map[string, int]], want map[string]int) {
+		t.Helper()
+		got := pmap.ToGoMap(o.Value())
+		if len(got) != len(want) {
+			t.Fatalf("%s: got %v, want %v", label, got, want)
+		}
+		for key, wantValue := range want {
+			if got[key] != wantValue {
+				t.Fatalf("%s: %q = %d, want %d (got %v)", label, key, got[key], wantValue, got)
+			}
+		}
+	}
+
+	if err := g.Stabilize(ctx); err != nil {
+		t.Fatal(err)
+	}
+	check("inner", inner, map[string]int{"ann": 1015, "bob": 2007})
+	check("left", left, map[string]int{"ann": 1015, "bob": 2007, "cat": 3000})
+	check("outer", outer, map[string]int{"ann": 1015, "bob": 2007, "cat": 3000, "dan": -4})
+
+	// an order moving between customers leaves one row and joins another
+	orders = orders.Set(3, order{customer: "cat", size: 7})
+	rv.Set(orders)
+	if err := g.Stabilize(ctx); err != nil {
+		t.Fatal(err)
+	}
+	check("inner after move", inner, map[string]int{"ann": 1015, "cat": 3007})
+	check("left after move", left, map[string]int{"ann": 1015, "bob": 2000, "cat": 3007})
+
+	// a customer arriving adopts the orders already referring to it
+	customers = customers.Set("dan", 4)
+	lv.Set(customers)
+	if err := g.Stabilize(ctx); err != nil {
+		t.Fatal(err)
+	}
+	check("inner after arrival", inner, map[string]int{"ann": 1015, "cat": 3007, "dan": 4004})
+	check("outer after arrival", outer, map[string]int{"ann": 1015, "bob": 2000, "cat": 3007, "dan": 4004})
+
+	// and a customer leaving drops out of the inner join but keeps its orders in the outer
+	customers = customers.Delete("ann")
+	lv.Set(customers)
+	if err := g.Stabilize(ctx); err != nil {
+		t.Fatal(err)
+	}
+	check("inner after departure", inner, map[string]int{"cat": 3007, "dan": 4004})
+	check("outer after departure", outer, map[string]int{"ann": -15, "bob": 2000, "cat": 3007, "dan": 4004})
+}
+
+// Test_JoinOn_matchesReference cross-checks every kind against joining from scratch
+// while both inputs are mutated independently.
+func Test_JoinOn_matchesReference(t *testing.T) {
+	ctx := context.Background()
+	g := incr.New()
+	rng := rand.New(rand.NewSource(26))
+
+	customerKey := func(index int) string { return string(rune('a' + index)) }
+
+	customers, orders := pmap.New[string, int](), pmap.New[int, order]()
+	lv, rv := incr.Var(g, customers), incr.Var(g, orders)
+	kinds := []JoinKind{JoinInner, JoinLeft, JoinOuter}
+	observers := make([]incr.ObserveIncr[pmap.Map[string, int]], len(kinds))
+	for index, kind := range kinds {
+		observers[index] = incr.MustObserve(g, JoinOn(g, lv, rv, orderCustomer, kind, intsEqual, ordersEqual, joinTotal))
+	}
+	if err := g.Stabilize(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	for step := range 400 {
+		switch rng.Intn(4) {
+		case 0:
+			customers = customers.Delete(customerKey(rng.Intn(8)))
+		case 1:
+			customers = customers.Set(customerKey(rng.Intn(8)), rng.Intn(9)+1)
+		case 2:
+			orders = orders.Delete(rng.Intn(40))
+		default:
+			orders = orders.Set(rng.Intn(40), order{customer: customerKey(rng.Intn(10)), size: rng.Intn(9) + 1})
+		}
+		lv.Set(customers)
+		rv.Set(orders)
+		if err := g.Stabilize(ctx); err != nil {
+			t.Fatal(err)
+		}
+
+		for index, kind := range kinds {
+			want := map[string]int{}
+			groups := map[string]pmap.Map[int, order]{}
+			for id, o := range orders.All() {
+				groups[o.customer] = groups[o.customer].Set(id, o)
+			}
+			rows := map[string]JoinRow[int, int, order]{}
+			for key, value := range customers.All() {
+				rows[key] = JoinRow[int, int, order]{Left: value, HasLeft: true, Right: groups[key]}
+			}
+			for key, group := range groups {
+				if _, ok := rows[key]; !ok {
+					rows[key] = JoinRow[int, int, order]{Right: group}
+				}
+			}
+			for key, row := range rows {
+				keep := row.HasLeft || row.Right.Len() > 0
+				switch kind {
+				case JoinInner:
+					keep = row.HasLeft && row.Right.Len() > 0
+				case JoinLeft:
+					keep = row.HasLeft
+				}
+				if keep {
+					want[key] = joinTotal(key, row)
+				}
+			}
+
+			got := pmap.ToGoMap(observers[index].Value())
+			if len(got) != len(want) {
+				t.Fatalf("step %d %v: got %v, want %v", step, kind, got, want)
+			}
+			for key, wantValue := range want {
+				if got[key] != wantValue {
+					t.Fatalf("step %d %v: %q = %d, want %d", step, kind, key, got[key], wantValue)
+				}
+			}
+		}
+	}
+}
+
+// Test_JoinOn_work asserts that one changed entry on either side recomputes a fixed
+// number of rows, whatever the size of the inputs.
+func Test_JoinOn_work(t *testing.T) {
+	ctx := context.Background()
+	for _, size := range []int{1024, 65536} {
+		g := incr.New()
+		var customers pmap.Map[int, int]
+		var orders pmap.Map[int, int]
+		for k := range size {
+			customers = customers.Set(k, k)
+			orders = orders.Set(k, k/4)
+		}
+		lv, rv := incr.Var(g, customers), incr.Var(g, orders)
+
+		var calls int
+		incr.MustObserve(g, JoinOn(g, lv, rv,
+			func(_ int, customer int) int { return customer },
+			JoinOuter, intsEqual, intsEqual,
+			func(_ int, row JoinRow[int, int, int]) int {
+				calls++
+				return row.Right.Len()
+			}))
+		if err := g.Stabilize(ctx); err != nil {
+			t.Fatal(err)
+		}
+
+		calls = 0
+		lv.Set(customers.Set(size/2, -1))
+		if err := g.Stabilize(ctx); err != nil {
+			t.Fatal(err)
+		}
+		if calls != 1 {
+			t.Errorf("size %d: a left change called fn %d times, want 1", size, calls)
+		}
+
+		// an order moving to another customer touches both rows and nothing else
+		calls = 0
+		rv.Set(orders.Set(size/2, size/8+1))
+		if err := g.Stabilize(ctx); err != nil {
+			t.Fatal(err)
+		}
+		if calls != 2 {
+			t.Errorf("size %d: a moved right entry called fn %d times, want 2", size, calls)
+		}
+	}
+}
@@ -0,0 +1,226 @@
+package mapi
+
+import (
+	"cmp"
+	"context"
+	"math"
+
+	"github.com/wcharczuk/go-incr"
+	"github.com/wcharczuk/go-incr/incrutil/pmap"
+)
+
+// Entry is a key and its value, as reported by the operators that order a map by value.
+type Entry[K cmp.Ordered, V any] struct {
+	Key   K
+	Value V
+}
+
+// rankIndex keeps a [rankTree] in step with an incremental map.
+//
+// It is the part shared by the operators ordering a map by value: each diffs its input
+// against the version it saw last, and moves only the changed entries in the index.
+type rankIndex[K cmp.Ordered, V any] struct {
+	less  rankLess[V]
+	equal func(a, b V) bool
+	last  pmap.Map[K, V]
+	tree  *rankTree[K, V]
+}
+
+// apply brings the index up to date with current, calling moved for every entry it
+// repositions with the entry's position before the change in the index as it was, and
+// its position after in the index as it became. A position is -1 on the side where the
+// entry is absent.
+//
+// Positions are reported as each change is applied rather than against the index as a
+// whole before and after, which is what lets a caller watching a window of positions
+// decide whether the window moved: if no single step reached it, the steps together did
+// not either.
+func (r *rankIndex[K, V]) apply(current pmap.Map[K, V], moved func(before, beforeSize, after, afterSize int)) {
+	for change := range r.last.SymmetricDiff(current, r.equal) {
+		before, after := -1, -1
+		size := r.tree.treeSize()
+		if change.Kind != pmap.ChangeAdded {
+			before, _ = r.tree.rank(r.less, change.Key, change.Old)
+			r.tree = rankRemove(r.tree, r.less, change.Key, change.Old)
+		}
+		if change.Kind != pmap.ChangeRemoved {
+			r.tree = rankInsert(r.tree, r.less, change.Key, change.New)
+			after, _ = r.tree.rank(r.less, change.Key, change.New)
+		}
+		if moved != nil {
+			moved(before, size, after, r.tree.treeSize())
+		}
+	}
+	r.last = current
+}
+
+// TopK returns the k entries of an incremental map with the largest values, largest
+// first.
+//
+// This is the leaderboard: [MaxValue] is the case k = 1 without the key, and anything
+// wider cannot be maintained as a fold, since withdrawing an entry from the top k says
+// nothing about which entry replaces it. The node instead keeps every entry in a
+// secondary index ordered by value, so one changed entry costs O(log n) to reposition
+// and the top k are read from the end of the index in O(k + log n).
+//
+// The output is only rebuilt when a change reaches the top k. A change further down
+// repositions the entry in the index and cuts off, so nothing reading the leaderboard
+// recomputes.
+//
+// less orders values. Entries whose values are equal under less are ordered by key, so
+// the result is deterministic; for the same reason a value rebound to one less considers
+// equal is not treated as a change. Values carrying more than their ordering -- a score
+// together with a display name -- should be ordered on everything that matters to the
+// reader.
+//
+// A k of zero or less is treated as zero, and the result is always empty.
+func TopK[K cmp.Ordered, V any](
+	scope incr.Scope,
+	input incr.Incr[pmap.Map[K, V]],
+	k int,
+	less func(a, b V) bool,
+) incr.Incr[[]Entry[K, V]] {
+	t := &topKIncr[K, V]{
+		n: incr.NewNode("mapi_top_k"),
+		i: input,
+		k: max(k, 0),
+		index: rankIndex[K, V]{
+			less:  less,
+			equal: func(a, b V) bool { return !less(a, b) && !less(b, a) },
+		},
+	}
+	t.parents[0] = input
+	return incr.WithinScope(scope, t)
+}
+
+var (
+	_ incr.Incr[[]Entry[string, int]] = (*topKIncr[string, int])(nil)
+	_ incr.IStabilize                 = (*topKIncr[string, int])(nil)
+	_ incr.ICutoff                    = (*topKIncr[string, int])(nil)
+	_ incr.IParents                   = (*topKIncr[string, int])(nil)
+)
+
+type topKIncr[K cmp.Ordered, V any] struct {
+	n       *incr.Node
+	i       incr.Incr[pmap.Map[K, V]]
+	k       int
+	index   rankIndex[K, V]
+	seeded  bool
+	value   []Entry[K, V]
+	parents [1]incr.INode
+}
+
+func (t *topKIncr[K, V]) Parents() []incr.INode { return t.parents[:] }
+
+func (t *topKIncr[K, V]) Node() *incr.Node { return t.n }
+
+func (t *topKIncr[K, V]) Value() []Entry[K, V] { return t.value }
+
+// Cutoff brings the index up to date and reports whether the top k are unchanged.
+//
+// The index is maintained here rather than in Stabilize because this is where the
+// decision is made: whether the output moved is a property of where the changed entries
+// landed, and that is only known while they are being repositioned.
+func (t *topKIncr[K, V]) Cutoff(_ context.Context) (bool, error) {
+	reached := !t.seeded
+	t.index.apply(t.i.Value(), func(before, beforeSize, after, afterSize int) {
+		// a position counted from the top is the distance from the end of the index
+		if before >= 0 && beforeSize-1-before < t.k {
+			reached = true
+		}
+		if after >= 0 && afterSize-1-after < t.k {
+			reached = true
+		}
+	})
+	return !reached, nil
+}
+
+func (t *topKIncr[K, V]) Stabilize(_ context.Context) error {
+	out := make([]Entry[K, V], 0, min(t.k, t.index.tree.treeSize()))
+	t.index.tree.eachBackward(0, func(key K, value V) bool {
+		if len(out) == t.k {
+			return false
+		}
+		out = append(out, Entry[K, V]{Key: key, Value: value})
+		return true
+	})
+	t.value = out
+	t.seeded = true
+	return nil
+}
+
+func (t *topKIncr[K, V]) String() string { return t.n.String() }
+
+// Quantile returns the value at a quantile of an incremental map's values, absent when
+// the map is empty.
+//
+// q is clamped to [0, 1], and the result is the value at the lower nearest rank: for n
+// values, the one at position floor(q x (n-1)) in sorted order, so 0 is the minimum, 1
+// the maximum and 0.5 the lower median. No interpolation is done, so the result is
+// always one of the values.
+//
+// Like [TopK] the node keeps a secondary index by value, so a changed entry costs
+// O(log n) and finding the quantile another O(log n); a latency dashboard over a large
+// window of samples pays for the samples that arrived, not for the window. The node
+// cuts off when the value at the quantile is unchanged.
+func Quantile[K cmp.Ordered, V cmp.Ordered](
+	scope incr.Scope,
+	input incr.Incr[pmap.Map[K, V]],
+	q float64,
+) incr.Incr[Optional[V]] {
+	qn := &quantileIncr[K, V]{
+		n: incr.NewNode("mapi_quantile"),
+		i: input,
+		q: math.Max(0, math.Min(1, q)),
+		index: rankIndex[K, V]{
+			less:  cmp.Less[V],
+			equal: func(a, b V) bool { return a == b },
+		},
+	}
+	qn.parents[0] = input
+	return incr.WithinScope(scope, qn)
+}
+
+var (
+	_ incr.Incr[Optional[int]] = (*quantileIncr[string, int])(nil)
+	_ incr.IStabilize          = (*quantileIncr[string, int])(nil)
+	_ incr.ICutoff             = (*quantileIncr[string, int])(nil)
+	_ incr.IParents            = (*quantileIncr[string, int])(nil)
+)
+
+type quantileIncr[K cmp.Ordered, V cmp.Ordered] struct {
+	n       *incr.Node
+	i       incr.Incr[pmap.Map[K, V]]
+	q       float64
+	index   rankIndex[K, V]
+	seeded  bool
+	next    Optional[V]
+	value   Optional[V]
+	parents [1]incr.INode
+}
+
+func (qn *quantileIncr[K, V]) Parents() []incr.INode { return qn.parents[:] }
+
+func (qn *quantileIncr[K, V]) Node() *incr.Node { return qn.n }
+
+func (qn *quantileIncr[K, V]) Value() Optional[V] { return qn.value }
+
+// Cutoff brings the index up to date and reports whether the value at the quantile is
+// unchanged; see [topKIncr.Cutoff] for why the index is maintained here.
+func (qn *quantileIncr[K, V]) Cutoff(_ context.Context) (bool, error) {
+	qn.index.apply(qn.i.Value(), nil)
+	qn.next = Optional[V]{}
+	if size := qn.index.tree.treeSize(); size > 0 {
+		position := int(math.Floor(qn.q * float64(size-1)))
+		_, qn.next.Value, qn.next.Present = qn.index.tree.nth(position)
+	}
+	return qn.seeded && qn.next == qn.value, nil
+}
+
+func (qn *quantileIncr[K, V]) Stabilize(_ context.Context) error {
+	qn.value = qn.next
+	qn.seeded = true
+	return nil
+}
+
+func (qn *quantileIncr[K, V]) String() string { return qn.n.String() }
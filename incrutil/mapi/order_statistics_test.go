@@ -0,0 +1,225 @@
+package mapi
+
+import (
+	"cmp"
+	"context"
+	"math"
+	"math/rand"
+	"slices"
+	"testing"
+
+	"github.com/wcharczuk/go-incr"
+	"github.com/wcharczuk/go-incr/incrutil/pmap"
+)
+
+func intsLess(a, b int) bool { return a < b }
+
+// referenceTopK sorts the whole map, largest value first and ties by key.
+func referenceTopK(m pmap.Map[int, int], k int) []Entry[int, int] {
+	var all []Entry[int, int]
+	for key, value := range m.All() {
+		all = append(all, Entry[int, int]{Key: key, Value: value})
+	}
+	slices.SortFunc(all, func(a, b Entry[int, int]) int {
+		if c := cmp.Compare(b.Value, a.Value); c != 0 {
+			return c
+		}
+		return cmp.Compare(b.Key, a.Key)
+	})
+	return all[:min(k, len(all))]
+}
+
+func Test_TopK(t *testing.T) {
+	ctx := context.Background()
+	g := incr.New()
+
+	scores := pmap.FromGoMap(map[string]int{"ann": 40, "bob": 75, "cat": 60, "dan": 10, "eve": 90})
+	v := incr.Var(g, scores)
+	top := TopK(g, v, 3, intsLess)
+	var downstream int
+	o := incr.MustObserve(g, incr.Map(g, top, func(entries []Entry[string, int]) []Entry[string, int] {
+		downstream++
+		return entries
+	}))
+
+	if err := g.Stabilize(ctx); err != nil {
+		t.Fatal(err)
+	}
+	want := []Entry[string, int]{{"eve", 90}, {"bob", 75}, {"cat", 60}}
+	if !slices.Equal(o.Value(), want) {
+		t.Fatalf("initial top 3 %v, want %v", o.Value(), want)
+	}
+
+	// a change below the top 3 repositions the entry and cuts off
+	downstream = 0
+	scores = scores.Set("dan", 20)
+	v.Set(scores)
+	if err := g.Stabilize(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if downstream != 0 {
+		t.Fatalf("a change below the top 3 recomputed the leaderboard's reader %d times", downstream)
+	}
+
+	// one climbing into the top 3 pushes another out
+	scores = scores.Set("ann", 80)
+	v.Set(scores)
+	if err := g.Stabilize(ctx); err != nil {
+		t.Fatal(err)
+	}
+	want = []Entry[string, int]{{"eve", 90}, {"ann", 80}, {"bob", 75}}
+	if !slices.Equal(o.Value(), want) {
+		t.Fatalf("after ann climbed, top 3 %v, want %v", o.Value(), want)
+	}
+
+	// and a removal from the top 3 lets the next one in
+	scores = scores.Delete("eve")
+	v.Set(scores)
+	if err := g.Stabilize(ctx); err != nil {
+		t.Fatal(err)
+	}
+	want = []Entry[string, int]{{"ann", 80}, {"bob", 75}, {"cat", 60}}
+	if !slices.Equal(o.Value(), want) {
+		t.Fatalf("after eve left, top 3 %v, want %v", o.Value(), want)
+	}
+}
+
+func Test_TopK_nonPositive(t *testing.T) {
+	ctx := context.Background()
+	g := incr.New()
+
+	v := incr.Var(g, pmap.FromGoMap(map[string]int{"ann": 40, "bob": 75}))
+	for _, k := range []int{0, -1} {
+		o := incr.MustObserve(g, TopK(g, v, k, intsLess))
+		if err := g.Stabilize(ctx); err != nil {
+			t.Fatal(err)
+		}
+		if len(o.Value()) != 0 {
+			t.Fatalf("top %d is %v, want it empty", k, o.Value())
+		}
+	}
+
+	v.Set(v.Value().Set("cat", 90))
+	if err := g.Stabilize(ctx); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// Test_TopK_matchesReference cross-checks against sorting from scratch, including
+// ties and maps smaller than k.
+func Test_TopK_matchesReference(t *testing.T) {
+	ctx := context.Background()
+	g := incr.New()
+	rng := rand.New(rand.NewSource(27))
+
+	var m pmap.Map[int, int]
+	v := incr.Var(g, m)
+	o := incr.MustObserve(g, TopK(g, v, 5, intsLess))
+	if err := g.Stabilize(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	for step := range 600 {
+		key := rng.Intn(30)
+		if rng.Intn(3) == 0 {
+			m = m.Delete(key)
+		} else {
+			m = m.Set(key, rng.Intn(12))
+		}
+		v.Set(m)
+		if err := g.Stabilize(ctx); err != nil {
+			t.Fatal(err)
+		}
+		if want := referenceTopK(m, 5); !slices.Equal(o.Value(), want) {
+			t.Fatalf("step %d: top 5 %v, want %v", step, o.Value(), want)
+		}
+	}
+}
+
+func Test_Quantile(t *testing.T) {
+	ctx := context.Background()
+	g := incr.New()
+	rng := rand.New(rand.NewSource(28))
+
+	var samples pmap.Map[int, int]
+	v := incr.Var(g, samples)
+	quantiles := []float64{0, 0.5, 0.9, 0.99, 1}
+	observers := make([]incr.ObserveIncr[Optional[int]], len(quantiles))
+	for index, q := range quantiles {
+		observers[index] = incr.MustObserve(g, Quantile(g, v, q))
+	}
+	if err := g.Stabilize(ctx); err != nil {
+		t.Fatal(err)
+	}
+	for index := range quantiles {
+		if observers[index].Value().Present {
+			t.Fatalf("quantile %v of an empty map is present", quantiles[index])
+		}
+	}
+
+	for step := range 500 {
+		key := rng.Intn(200)
+		if rng.Intn(4) == 0 {
+			samples = samples.Delete(key)
+		} else {
+			samples = samples.Set(key, rng.Intn(1000))
+		}
+		v.Set(samples)
+		if err := g.Stabilize(ctx); err != nil {
+			t.Fatal(err)
+		}
+
+		var sorted []int
+		for _, value := range samples.All() {
+			sorted = append(sorted, value)
+		}
+		slices.Sort(sorted)
+		for index, q := range quantiles {
+			got := observers[index].Value()
+			if len(sorted) == 0 {
+				if got.Present {
+					t.Fatalf("step %d: quantile %v of an empty map is present", step, q)
+				}
+				continue
+			}
+			want := sorted[int(math.Floor(q*float64(len(sorted)-1)))]
+			if !got.Present || got.Value != want {
+				t.Fatalf("step %d: quantile %v = %v, want %d", step, q, got, want)
+			}
+		}
+	}
+}
+
+// Test_orderStatistics_work asserts that repositioning one changed entry costs a number
+// of comparisons logarithmic in the map's size rather than linear.
+func Test_orderStatistics_work(t *testing.T) {
+	ctx := context.Background()
+	comparisons := func(size int) int {
+		g := incr.New()
+		var base pmap.Map[int, int]
+		for k := range size {
+			base = base.Set(k, k)
+		}
+		v := incr.Var(g, base)
+		var calls int
+		incr.MustObserve(g, TopK(g, v, 10, func(a, b int) bool {
+			calls++
+			return a < b
+		}))
+		if err := g.Stabilize(ctx); err != nil {
+			t.Fatal(err)
+		}
+		calls = 0
+		v.Set(base.Set(size/2, size/2+7))
+		if err := g.Stabilize(ctx); err != nil {
+			t.Fatal(err)
+		}
+		return calls
+	}
+	small, large := comparisons(1024), comparisons(65536)
+	// 64x the entries is 6 more levels in a balanced tree, against a base of 10; a linear
+	// rescan would be 64x
+	if large > 3*small {
+		t.Fatalf("one change cost %d comparisons at 1024 entries and %d at 65536", small, large)
+	}
+}
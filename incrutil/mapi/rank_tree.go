@@ -0,0 +1,238 @@
+package mapi
+
+import "cmp"
+
+// rankTree is a persistent balanced tree of map entries ordered by value, which is the
+// secondary index the order-statistics operators maintain alongside their input.
+//
+// A [pmap.Map] is ordered by key and cannot be reordered, since its keys must be
+// [cmp.Ordered] and the order is the keys' own. Ordering by value needs a comparator,
+// and a comparator over values alone is not enough to locate an entry when two keys
+// hold equal values, so entries are ordered by value and then by key. That makes every
+// entry's position unique and lets a removal find exactly the entry it removes from the
+// old value the diff reports.
+//
+// Like [pmap.Map] it records subtree sizes, so a position is found in O(log n), and it
+// is never mutated once built, so a version can be handed out as a value while the
+// operator goes on editing its successor.
+type rankTree[K cmp.Ordered, V any] struct {
+	key         K
+	value       V
+	left, right *rankTree[K, V]
+	height      int
+	size        int
+}
+
+// rankLess orders values. It takes the same shape as the less function the exported
+// operators accept, so a caller's function is used as it is.
+type rankLess[V any] func(a, b V) bool
+
+// compareEntries orders two entries by value and then by key.
+func compareEntries[K cmp.Ordered, V any](less rankLess[V], aKey K, aValue V, bKey K, bValue V) int {
+	switch {
+	case less(aValue, bValue):
+		return -1
+	case less(bValue, aValue):
+		return 1
+	default:
+		return cmp.Compare(aKey, bKey)
+	}
+}
+
+func (n *rankTree[K, V]) treeSize() int {
+	if n == nil {
+		return 0
+	}
+	return n.size
+}
+
+func (n *rankTree[K, V]) treeHeight() int {
+	if n == nil {
+		return 0
+	}
+	return n.height
+}
+
+// nth returns the entry at a position in value order, counting from zero.
+func (n *rankTree[K, V]) nth(index int) (key K, value V, ok bool) {
+	if index < 0 {
+		return
+	}
+	cursor := n
+	for cursor != nil {
+		leftSize := cursor.left.treeSize()
+		switch {
+		case index < leftSize:
+			cursor = cursor.left
+		case index == leftSize:
+			return cursor.key, cursor.value, true
+		default:
+			index -= leftSize + 1
+			cursor = cursor.right
+		}
+	}
+	return
+}
+
+// rank returns how many entries sort before an entry, and whether it is present.
+func (n *rankTree[K, V]) rank(less rankLess[V], key K, value V) (rank int, present bool) {
+	cursor := n
+	for cursor != nil {
+		switch c := compareEntries(less, key, value, cursor.key, cursor.value); {
+		case c < 0:
+			cursor = cursor.left
+		case c > 0:
+			rank += cursor.left.treeSize() + 1
+			cursor = cursor.right
+		default:
+			return rank + cursor.left.treeSize(), true
+		}
+	}
+	return rank, false
+}
+
+// each visits entries in value order from a position, stopping when yield returns false.
+// Subtrees wholly before the position are skipped.
+func (n *rankTree[K, V]) each(from int, yield func(K, V) bool) bool {
+	if n == nil {
+		return true
+	}
+	leftSize := n.left.treeSize()
+	if from < leftSize {
+		if !n.left.each(from, yield) {
+			return false
+		}
+	}
+	if from <= leftSize {
+		if !yield(n.key, n.value) {
+			return false
+		}
+	}
+	return n.right.each(max(0, from-leftSize-1), yield)
+}
+
+// eachBackward visits entries in reverse value order from a position counted from the
+// end, stopping when yield returns false. Subtrees wholly past the position are skipped.
+func (n *rankTree[K, V]) eachBackward(from int, yield func(K, V) bool) bool {
+	if n == nil {
+		return true
+	}
+	rightSize := n.right.treeSize()
+	if from < rightSize {
+		if !n.right.eachBackward(from, yield) {
+			return false
+		}
+	}
+	if from <= rightSize {
+		if !yield(n.key, n.value) {
+			return false
+		}
+	}
+	leftFrom := from - rightSize - 1
+	if leftFrom < 0 {
+		leftFrom = 0
+	}
+	return n.left.eachBackward(leftFrom, yield)
+}
+
+//
+// balancing; this mirrors pmap's tree, ordered by a comparator rather than by key
+//
+
+func rankJoin[K cmp.Ordered, V any](key K, value V, left, right *rankTree[K, V]) *rankTree[K, V] {
+	height := left.treeHeight()
+	if rh := right.treeHeight(); rh > height {
+		height = rh
+	}
+	return &rankTree[K, V]{
+		key:    key,
+		value:  value,
+		left:   left,
+		right:  right,
+		height: height + 1,
+		size:   left.treeSize() + right.treeSize() + 1,
+	}
+}
+
+func rankBalance[K cmp.Ordered, V any](key K, value V, left, right *rankTree[K, V]) *rankTree[K, V] {
+	lh, rh := left.treeHeight(), right.treeHeight()
+	switch {
+	case lh > rh+1:
+		if left.left.treeHeight() >= left.right.treeHeight() {
+			return rankJoin(left.key, left.value, left.left, rankJoin(key, value, left.right, right))
+		}
+		lr := left.right
+		return rankJoin(lr.key, lr.value,
+			rankJoin(left.key, left.value, left.left, lr.left),
+			rankJoin(key, value, lr.right, right))
+	case rh > lh+1:
+		if right.right.treeHeight() >= right.left.treeHeight() {
+			return rankJoin(right.key, right.value, rankJoin(key, value, left, right.left), right.right)
+		}
+		rl := right.left
+		return rankJoin(rl.key, rl.value,
+			rankJoin(key, value, left, rl.left),
+			rankJoin(right.key, right.value, rl.right, right.right))
+	default:
+		return rankJoin(key, value, left, right)
+	}
+}
+
+func rankInsert[K cmp.Ordered, V any](n *rankTree[K, V], less rankLess[V], key K, value V) *rankTree[K, V] {
+	if n == nil {
+		return rankJoin(key, value, nil, nil)
+	}
+	switch c := compareEntries(less, key, value, n.key, n.value); {
+	case c < 0:
+		return rankBalance(n.key, n.value, rankInsert(n.left, less, key, value), n.right)
+	case c > 0:
+		return rankBalance(n.key, n.value, n.left, rankInsert(n.right, less, key, value))
+	default:
+		return rankJoin(key, value, n.left, n.right)
+	}
+}
+
+func rankRemove[K cmp.Ordered, V any](n *rankTree[K, V], less rankLess[V], key K, value V) *rankTree[K, V] {
+	if n == nil {
+		return nil
+	}
+	switch c := compareEntries(less, key, value, n.key, n.value); {
+	case c < 0:
+		return rankBalance(n.key, n.value, rankRemove(n.left, less, key, value), n.right)
+	case c > 0:
+		return rankBalance(n.key, n.value, n.left, rankRemove(n.right, less, key, value))
+	default:
+		return rankGlue(n.left, n.right)
+	}
+}
+
+func rankGlue[K cmp.Ordered, V any](left, right *rankTree[K, V]) *rankTree[K, V] {
+	if left == nil {
+		return right
+	}
+	if right == nil {
+		return left
+	}
+	if left.treeHeight() > right.treeHeight() {
+		key, value, rest := rankRemoveMax(left)
+		return rankBalance(key, value, rest, right)
+	}
+	key, value, rest := rankRemoveMin(right)
+	return rankBalance(key, value, left, rest)
+}
+
+func rankRemoveMin[K cmp.Ordered, V any](n *rankTree[K, V]) (K, V, *rankTree[K, V]) {
+	if n.left == nil {
+		return n.key, n.value, n.right
+	}
+	key, value, rest := rankRemoveMin(n.left)
+	return key, value, rankBalance(n.key, n.value, rest, n.right)
+}
+
+func rankRemoveMax[K cmp.Ordered, V any](n *rankTree[K, V]) (K, V, *rankTree[K, V]) {
+	if n.right == nil {
+		return n.key, n.value, n.left
+	}
+	key, value, rest := rankRemoveMax(n.right)
+	return key, value, rankBalance(n.key, n.value, n.left, rest)
+}
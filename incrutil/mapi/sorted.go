@@ -0,0 +1,271 @@
+package mapi
+
+import (
+	"cmp"
+	"context"
+	"iter"
+
+	"github.com/wcharczuk/go-incr"
+	"github.com/wcharczuk/go-incr/incrutil/pmap"
+)
+
+// Ranking is a map's entries in value order, as produced by [Sorted].
+//
+// It is immutable: a Ranking handed out by one pass is unaffected by later passes, which
+// share its structure rather than editing it. The zero Ranking is empty.
+type Ranking[K cmp.Ordered, V any] struct {
+	tree   *rankTree[K, V]
+	less   rankLess[V]
+	values pmap.Map[K, V]
+	// generation numbers each version a [Sorted] node produces, and moves records how
+	// this version differs from the one numbered previous. That is what lets a [Page]
+	// tell whether the rows it shows moved without comparing them: a page that saw the
+	// previous version only has to look at the moves.
+	generation uint64
+	previous   uint64
+	moves      []rankMove
+}
+
+// rankMove is one entry repositioned between two versions of a ranking; see
+// [rankIndex.apply] for what the positions mean.
+type rankMove struct {
+	before, after int
+}
+
+// Len returns the number of entries.
+func (r Ranking[K, V]) Len() int { return r.tree.treeSize() }
+
+// Nth returns the entry at a position in value order, counting from zero, in O(log n).
+func (r Ranking[K, V]) Nth(index int) (key K, value V, ok bool) {
+	return r.tree.nth(index)
+}
+
+// Rank returns the position of a key in value order, and whether the key is present.
+//
+// The key's value is found in the map the ranking was built from, and its position from
+// that, so this costs O(log n) twice over.
+func (r Ranking[K, V]) Rank(key K) (rank int, present bool) {
+	value, ok := r.values.Get(key)
+	if !ok {
+		return
+	}
+	return r.tree.rank(r.less, key, value)
+}
+
+// Map returns the map the ranking was built from.
+func (r Ranking[K, V]) Map() pmap.Map[K, V] { return r.values }
+
+// All iterates entries in value order.
+func (r Ranking[K, V]) All() iter.Seq2[K, V] {
+	return r.Window(0, r.Len())
+}
+
+// Window iterates up to limit entries in value order starting from a position, skipping
+// every subtree before it, so this costs O(log n + limit).
+func (r Ranking[K, V]) Window(offset, limit int) iter.Seq2[K, V] {
+	return func(yield func(K, V) bool) {
+		if limit <= 0 {
+			return
+		}
+		if offset < 0 {
+			offset = 0
+		}
+		remaining := limit
+		r.tree.each(offset, func(key K, value V) bool {
+			if !yield(key, value) {
+				return false
+			}
+			remaining--
+			return remaining > 0
+		})
+	}
+}
+
+// Sorted orders an incremental map's entries by value.
+//
+// [pmap.Map] is ordered by key, and [Subrange] windows it by key; a table sorted on a
+// column needs the same over values. This node keeps the entries in a secondary index
+// ordered by less, then by key so that equal values have a stable order, and reports it
+// as a [Ranking] with the same positional access [pmap.Map.Nth] and [pmap.Map.Rank]
+// give over keys.
+//
+// Each pass repositions only the entries that changed, O(log n) each. Paging over the
+// result is what [Page] is for: a page recomputes only when a change reaches it.
+//
+// equal decides whether a rebound value has changed, as for [MapValues]; a value that
+// changes without moving still has to reach the pages showing it.
+func Sorted[K cmp.Ordered, V any](
+	scope incr.Scope,
+	input incr.Incr[pmap.Map[K, V]],
+	equal func(a, b V) bool,
+	less func(a, b V) bool,
+) incr.Incr[Ranking[K, V]] {
+	s := &sortedIncr[K, V]{
+		n: incr.NewNode("mapi_sorted"),
+		i: input,
+		index: rankIndex[K, V]{
+			less:  less,
+			equal: equal,
+		},
+	}
+	s.parents[0] = input
+	return incr.WithinScope(scope, s)
+}
+
+var (
+	_ incr.Incr[Ranking[string, int]] = (*sortedIncr[string, int])(nil)
+	_ incr.IStabilize                 = (*sortedIncr[string, int])(nil)
+	_ incr.ICutoff                    = (*sortedIncr[string, int])(nil)
+	_ incr.IParents                   = (*sortedIncr[string, int])(nil)
+)
+
+type sortedIncr[K cmp.Ordered, V any] struct {
+	n       *incr.Node
+	i       incr.Incr[pmap.Map[K, V]]
+	index   rankIndex[K, V]
+	seeded  bool
+	moves   []rankMove
+	value   Ranking[K, V]
+	parents [1]incr.INode
+}
+
+func (s *sortedIncr[K, V]) Parents() []incr.INode { return s.parents[:] }
+
+func (s *sortedIncr[K, V]) Node() *incr.Node { return s.n }
+
+func (s *sortedIncr[K, V]) Value() Ranking[K, V] { return s.value }
+
+// Cutoff brings the index up to date, recording the moves, and cuts off when nothing
+// moved; see [topKIncr.Cutoff] for why the index is maintained here.
+func (s *sortedIncr[K, V]) Cutoff(_ context.Context) (bool, error) {
+	// a fresh slice per pass, since the previous one belongs to a Ranking already
+	// handed out
+	s.moves = nil
+	s.index.apply(s.i.Value(), func(before, _, after, _ int) {
+		s.moves = append(s.moves, rankMove{before: before, after: after})
+	})
+	return s.seeded && len(s.moves) == 0, nil
+}
+
+func (s *sortedIncr[K, V]) Stabilize(_ context.Context) error {
+	s.value = Ranking[K, V]{
+		tree:       s.index.tree,
+		less:       s.index.less,
+		values:     s.index.last,
+		generation: s.value.generation + 1,
+		previous:   s.value.generation,
+		moves:      s.moves,
+	}
+	s.seeded = true
+	return nil
+}
+
+func (s *sortedIncr[K, V]) String() string { return s.n.String() }
+
+// Window is a range of positions: up to Limit entries starting at Offset.
+type Window struct {
+	Offset int
+	Limit  int
+}
+
+// Page returns one window of a [Ranking], where the window is itself incremental.
+//
+// This is the paging counterpart to [Subrange]. A table showing twenty rows of a
+// hundred thousand should not recompute when a row three pages away changes, and
+// nothing reading the page should either, so the node cuts off unless a change
+// actually reaches its window:
+//
+//   - an entry entering or leaving the window, or changing inside it.
+//   - an entry entering or leaving before the window, which shifts every row in it.
+//
+// An entry moving from one position before the window to another before it shifts
+// nothing, and is ignored like any change after it. Deciding costs O(1) per change.
+//
+// When the window itself moves the page is rebuilt, which costs O(log n + limit).
+func Page[K cmp.Ordered, V any](
+	scope incr.Scope,
+	sorted incr.Incr[Ranking[K, V]],
+	window incr.Incr[Window],
+) incr.Incr[[]Entry[K, V]] {
+	p := &pageIncr[K, V]{
+		n:      incr.NewNode("mapi_page"),
+		sorted: sorted,
+		window: window,
+	}
+	p.parents[0] = sorted
+	p.parents[1] = window
+	return incr.WithinScope(scope, p)
+}
+
+var (
+	_ incr.Incr[[]Entry[string, int]] = (*pageIncr[string, int])(nil)
+	_ incr.IStabilize                 = (*pageIncr[string, int])(nil)
+	_ incr.ICutoff                    = (*pageIncr[string, int])(nil)
+	_ incr.IParents                   = (*pageIncr[string, int])(nil)
+)
+
+type pageIncr[K cmp.Ordered, V any] struct {
+	n      *incr.Node
+	sorted incr.Incr[Ranking[K, V]]
+	window incr.Incr[Window]
+	// seen is the generation of the ranking the current page was read from, and
+	// lastWindow the window it was read for.
+	seen       uint64
+	seeded     bool
+	lastWindow Window
+	value      []Entry[K, V]
+	parents    [2]incr.INode
+}
+
+func (p *pageIncr[K, V]) Parents() []incr.INode { return p.parents[:] }
+
+func (p *pageIncr[K, V]) Node() *incr.Node { return p.n }
+
+func (p *pageIncr[K, V]) Value() []Entry[K, V] { return p.value }
+
+func (p *pageIncr[K, V]) Cutoff(_ context.Context) (bool, error) {
+	ranking, window := p.sorted.Value(), p.window.Value()
+	if !p.seeded || window != p.lastWindow {
+		return false, nil
+	}
+	if ranking.generation == p.seen {
+		return true, nil
+	}
+	// the page missed a version -- it was unnecessary for a while, say -- so the moves
+	// on this one do not describe the difference from what it last read
+	if ranking.previous != p.seen {
+		return false, nil
+	}
+	// Positions are compared in the order the moves were applied, so each is measured
+	// against the index as it stood at that step; see rankIndex.apply.
+	start, end := window.Offset, window.Offset+window.Limit
+	for _, move := range ranking.moves {
+		beforeOutside := move.before < 0 || move.before >= end
+		afterOutside := move.after < 0 || move.after >= end
+		if beforeOutside && afterOutside {
+			continue
+		}
+		// both ends sit before the window, so it shifted down and back up again
+		if move.before >= 0 && move.before < start && move.after >= 0 && move.after < start {
+			continue
+		}
+		return false, nil
+	}
+	p.seen = ranking.generation
+	return true, nil
+}
+
+func (p *pageIncr[K, V]) Stabilize(_ context.Context) error {
+	ranking, window := p.sorted.Value(), p.window.Value()
+	out := make([]Entry[K, V], 0, max(0, min(window.Limit, ranking.Len()-window.Offset)))
+	for key, value := range ranking.Window(window.Offset, window.Limit) {
+		out = append(out, Entry[K, V]{Key: key, Value: value})
+	}
+	p.value = out
+	p.seen = ranking.generation
+	p.lastWindow = window
+	p.seeded = true
+	return nil
+}
+
+func (p *pageIncr[K, V]) String() string { return p.n.String() }
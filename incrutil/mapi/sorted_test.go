@@ -0,0 +1,224 @@
+package mapi
+
+import (
+	"cmp"
+	"context"
+	"math/rand"
+	"slices"
+	"testing"
+
+	"github.com/wcharczuk/go-incr"
+	"github.com/wcharczuk/go-incr/incrutil/pmap"
+)
+
+// referenceSorted sorts the whole map, smallest value first and ties by key.
+func referenceSorted(m pmap.Map[int, int]) []Entry[int, int] {
+	var all []Entry[int, int]
+	for key, value := range m.All() {
+		all = append(all, Entry[int, int]{Key: key, Value: value})
+	}
+	slices.SortFunc(all, func(a, b Entry[int, int]) int {
+		if c := cmp.Compare(a.Value, b.Value); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.Key, b.Key)
+	})
+	return all
+}
+
+func Test_Sorted(t *testing.T) {
+	ctx := context.Background()
+	g := incr.New()
+
+	prices := pmap.FromGoMap(map[string]int{"apple": 3, "bread": 5, "cheese": 9, "dates": 7, "eggs": 4})
+	v := incr.Var(g, prices)
+	sorted := incr.MustObserve(g, Sorted(g, v, intsEqual, intsLess))
+	if err := g.Stabilize(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	ranking := sorted.Value()
+	var keys []string
+	for key := range ranking.All() {
+		keys = append(keys, key)
+	}
+	if want := []string{"apple", "eggs", "bread", "dates", "cheese"}; !slices.Equal(keys, want) {
+		t.Fatalf("sorted keys %v, want %v", keys, want)
+	}
+	if rank, ok := ranking.Rank("dates"); !ok || rank != 3 {
+		t.Fatalf("rank of dates = %d, %v, want 3", rank, ok)
+	}
+	if _, ok := ranking.Rank("figs"); ok {
+		t.Fatal("an absent key has a rank")
+	}
+	if key, value, ok := ranking.Nth(1); !ok || key != "eggs" || value != 4 {
+		t.Fatalf("nth 1 = %q, %d, %v", key, value, ok)
+	}
+
+	// a ranking handed out is unaffected by later passes
+	v.Set(prices.Set("apple", 10))
+	if err := g.Stabilize(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if key, _, _ := ranking.Nth(0); key != "apple" {
+		t.Fatalf("an earlier ranking changed: first is now %q", key)
+	}
+	if key, _, _ := sorted.Value().Nth(4); key != "apple" {
+		t.Fatalf("the new ranking's last entry is %q, want apple", key)
+	}
+}
+
+func Test_Page(t *testing.T) {
+	ctx := context.Background()
+	g := incr.New()
+
+	var m pmap.Map[int, int]
+	for k := range 100 {
+		m = m.Set(k, k*10)
+	}
+	v := incr.Var(g, m)
+	window := incr.Var(g, Window{Offset: 20, Limit: 10})
+	page := Page(g, Sorted(g, v, intsEqual, intsLess), window)
+	var downstream int
+	o := incr.MustObserve(g, incr.Map(g, page, func(entries []Entry[int, int]) []Entry[int, int] {
+		downstream++
+		return entries
+	}))
+	if err := g.Stabilize(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if want := referenceSorted(m)[20:30]; !slices.Equal(o.Value(), want) {
+		t.Fatalf("initial page %v, want %v", o.Value(), want)
+	}
+
+	step := func(label string, wantRecompute bool) {
+		t.Helper()
+		downstream = 0
+		v.Set(m)
+		if err := g.Stabilize(ctx); err != nil {
+			t.Fatal(err)
+		}
+		if recomputed := downstream > 0; recomputed != wantRecompute {
+			t.Fatalf("%s: page recomputed %v, want %v", label, recomputed, wantRecompute)
+		}
+		w := window.Value()
+		want := referenceSorted(m)
+		want = want[min(w.Offset, len(want)):min(w.Offset+w.Limit, len(want))]
+		if !slices.Equal(o.Value(), want) {
+			t.Fatalf("%s: page %v, want %v", label, o.Value(), want)
+		}
+	}
+
+	m = m.Set(80, 805)
+	step("a change after the window", false)
+	m = m.Set(3, 45)
+	step("a move within the entries before the window", false)
+	m = m.Set(25, 251)
+	step("a change inside the window", true)
+	m = m.Delete(1)
+	step("a removal before the window", true)
+	m = m.Set(90, 5)
+	step("an entry moving from after the window to before it", true)
+	m = m.Set(1000, 2000)
+	step("an addition after the window", false)
+
+	downstream = 0
+	window.Set(Window{Offset: 95, Limit: 10})
+	if err := g.Stabilize(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if downstream == 0 || len(o.Value()) != 5 {
+		t.Fatalf("moving the window past the end gave %v", o.Value())
+	}
+}
+
+// Test_Page_matchesReference cross-checks several windows against sorting from scratch,
+// including windows that are empty or run past the end.
+func Test_Page_matchesReference(t *testing.T) {
+	ctx := context.Background()
+	g := incr.New()
+	rng := rand.New(rand.NewSource(28))
+
+	var m pmap.Map[int, int]
+	v := incr.Var(g, m)
+	sorted := Sorted(g, v, intsEqual, intsLess)
+	windows := []Window{{0, 5}, {3, 4}, {10, 10}, {25, 10}, {0, 0}}
+	observers := make([]incr.ObserveIncr[[]Entry[int, int]], len(windows))
+	for index, w := range windows {
+		observers[index] = incr.MustObserve(g, Page(g, sorted, incr.Return(g, w)))
+	}
+	if err := g.Stabilize(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	for step := range 600 {
+		for range rng.Intn(3) + 1 {
+			key := rng.Intn(40)
+			if rng.Intn(3) == 0 {
+				m = m.Delete(key)
+			} else {
+				m = m.Set(key, rng.Intn(15))
+			}
+		}
+		v.Set(m)
+		if err := g.Stabilize(ctx); err != nil {
+			t.Fatal(err)
+		}
+		all := referenceSorted(m)
+		for index, w := range windows {
+			want := all[min(w.Offset, len(all)):min(w.Offset+w.Limit, len(all))]
+			if got := observers[index].Value(); !slices.Equal(got, want) {
+				t.Fatalf("step %d: window %v = %v, want %v", step, w, got, want)
+			}
+		}
+	}
+}
+
+// Test_Page_work asserts that a page far from a change does not recompute, and that
+// one reaching it rebuilds only the page.
+func Test_Page_work(t *testing.T) {
+	ctx := context.Background()
+	for _, size := range []int{1024, 65536} {
+		g := incr.New()
+		var m pmap.Map[int, int]
+		for k := range size {
+			m = m.Set(k, k)
+		}
+		v := incr.Var(g, m)
+		sorted := Sorted(g, v, intsEqual, intsLess)
+		var calls int
+		pages := make([]incr.Incr[[]Entry[int, int]], 0, 16)
+		for offset := 0; offset < size; offset += size / 16 {
+			pages = append(pages, Page(g, sorted, incr.Return(g, Window{Offset: offset, Limit: 20})))
+		}
+		for _, page := range pages {
+			incr.MustObserve(g, incr.Map(g, page, func(entries []Entry[int, int]) int {
+				calls++
+				return len(entries)
+			}))
+		}
+		if err := g.Stabilize(ctx); err != nil {
+			t.Fatal(err)
+		}
+
+		// the last entry keeps its place after every page
+		calls = 0
+		v.Set(m.Set(size-1, size+1))
+		if err := g.Stabilize(ctx); err != nil {
+			t.Fatal(err)
+		}
+		if calls != 0 {
+			t.Errorf("size %d: a change outside every page recomputed %d readers", size, calls)
+		}
+
+		calls = 0
+		v.Set(m.Set(size/16, -1))
+		if err := g.Stabilize(ctx); err != nil {
+			t.Fatal(err)
+		}
+		// the entry leaves the second page for the first, shifting only the pages between
+		if calls != 2 {
+			t.Errorf("size %d: a move into the first page recomputed %d readers, want 2", size, calls)
+		}
+	}
+}
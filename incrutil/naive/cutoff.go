@@ -0,0 +1,23 @@
+package naive
+
+func Cutoff[A any](input Node[A], fn CutoffFn[A]) Node[A] {
+	return &cutoffNodeImpl[A]{
+		input:  input,
+		action: fn,
+	}
+}
+
+type CutoffFn[A any] func(A, A) bool
+
+type cutoffNodeImpl[A any] struct {
+	input  Node[A]
+	action CutoffFn[A]
+	value  A
+}
+
+func (n *cutoffNodeImpl[A]) Value() A {
+	if newValue := n.input.Value(); !n.action(n.value, newValue) {
+		n.value = newValue
+	}
+	return n.value
+}
@@ -0,0 +1,257 @@
+package pvec
+
+import "iter"
+
+// ChangeKind describes how a position differs between two vectors.
+type ChangeKind uint8
+
+const (
+	// ChangeInserted means an element was inserted at the position.
+	ChangeInserted ChangeKind = iota
+	// ChangeRemoved means the element at the position was removed.
+	ChangeRemoved
+	// ChangeUpdated means the element at the position was replaced by an unequal one.
+	ChangeUpdated
+)
+
+func (c ChangeKind) String() string {
+	switch c {
+	case ChangeInserted:
+		return "inserted"
+	case ChangeRemoved:
+		return "removed"
+	case ChangeUpdated:
+		return "updated"
+	default:
+		return "unknown"
+	}
+}
+
+// Change is one difference between two vectors.
+//
+// Index is a position in the vector as it stands with every earlier change applied, so
+// replaying the changes in order onto the older vector gives the newer one. Old is
+// meaningful for [ChangeRemoved] and [ChangeUpdated]; New for [ChangeInserted] and
+// [ChangeUpdated].
+type Change[A any] struct {
+	Kind  ChangeKind
+	Index int
+	Old   A
+	New   A
+}
+
+// Diff iterates the changes that turn v into other, in position order.
+//
+// Cost is proportional to the size of the regions that differ rather than to the length
+// of either vector, provided the two are related by edits rather than built separately:
+// subtrees and chunks the two hold in common are recognized by pointer and skipped whole.
+// An edit rebuilds only the chunk it lands in and the path above it, so each edit costs
+// the diff about one chunk. Two independently built vectors share nothing and cost O(n)
+// to compare.
+//
+// Within a region that differs, equal decides which elements are unchanged, and the
+// report is the positions that changed plus whatever was inserted or removed. Pass nil to
+// treat elements at corresponding positions as unchanged, which reports only insertions
+// and removals.
+func (v Vector[A]) Diff(other Vector[A], equal func(a, b A) bool) iter.Seq[Change[A]] {
+	return func(yield func(Change[A]) bool) {
+		d := differ[A]{
+			older: cursor[A]{stack: pushed(nil, v.root)},
+			newer: cursor[A]{stack: pushed(nil, other.root)},
+			equal: equal,
+			yield: yield,
+		}
+		d.run()
+	}
+}
+
+// cursor walks a tree in order as a frontier of subtrees: the top of the stack is the
+// next unvisited subtree, which can be skipped whole or opened into its children.
+type cursor[A any] struct {
+	stack    []*node[A]
+	position int
+}
+
+func pushed[A any](stack []*node[A], n *node[A]) []*node[A] {
+	if n == nil {
+		return stack
+	}
+	return append(stack, n)
+}
+
+func (c *cursor[A]) top() *node[A] {
+	if len(c.stack) == 0 {
+		return nil
+	}
+	return c.stack[len(c.stack)-1]
+}
+
+// pop consumes the top subtree.
+func (c *cursor[A]) pop() *node[A] {
+	n := c.top()
+	c.stack = c.stack[:len(c.stack)-1]
+	c.position += n.size
+	return n
+}
+
+// open replaces the top subtree with its children.
+func (c *cursor[A]) open() {
+	n := c.top()
+	c.stack = append(c.stack[:len(c.stack)-1], n.right, n.left)
+}
+
+// differ aligns two trees chunk by chunk.
+//
+// Both cursors advance together while their tops are the same subtree. When they are not,
+// the taller top is opened until both are chunks, and the chunk that starts earlier is
+// gathered into the current region of difference -- both when they start together, as
+// they do when an edit rewrote a chunk in place. A region ends as soon as the tops agree
+// again; chunks after an edit are shared even when the tree above them was rebalanced, so
+// that is normally one chunk or two later.
+type differ[A any] struct {
+	older, newer cursor[A]
+	equal        func(a, b A) bool
+	yield        func(Change[A]) bool
+	// the current region of difference, and where it starts in the newer vector
+	regionStart int
+	regionOlder []A
+	regionNewer []A
+	inRegion    bool
+	stopped     bool
+}
+
+func (d *differ[A]) run() {
+	for !d.stopped {
+		older, newer := d.older.top(), d.newer.top()
+		switch {
+		case older == nil && newer == nil:
+			d.flush()
+			return
+		case older == newer:
+			d.flush()
+			d.older.pop()
+			d.newer.pop()
+		case older != nil && !older.leaf() && (newer == nil || older.height >= newer.height):
+			d.older.open()
+		case newer != nil && !newer.leaf():
+			d.newer.open()
+		default:
+			d.gather(older, newer)
+		}
+	}
+}
+
+// gather moves the chunk that starts earlier into the region, or both if they start at
+// the same position.
+func (d *differ[A]) gather(older, newer *node[A]) {
+	if !d.inRegion {
+		d.inRegion = true
+		d.regionStart = d.newer.position
+	}
+	takeOlder := newer == nil || (older != nil && d.older.position <= d.newer.position)
+	takeNewer := newer != nil && (older == nil || d.newer.position <= d.older.position)
+	if takeOlder {
+		d.regionOlder = append(d.regionOlder, d.older.pop().items...)
+	}
+	if takeNewer {
+		d.regionNewer = append(d.regionNewer, d.newer.pop().items...)
+	}
+}
+
+// alignLimit bounds the regions that are aligned element by element; see flush.
+const alignLimit = 1 << 12
+
+// flush reports the changes within the current region.
+//
+// The common prefix and suffix are trimmed first, which is all a single insertion or
+// removal needs. What remains is aligned by longest common subsequence when it is small
+// -- as it is whenever the region is a chunk or two -- so that several edits landing in
+// one chunk are still reported as themselves. A larger region, which only arises between
+// vectors that share little, is compared position by position instead, since aligning
+// it costs the product of its sides.
+func (d *differ[A]) flush() {
+	if !d.inRegion {
+		return
+	}
+	older, newer := d.regionOlder, d.regionNewer
+	d.regionOlder, d.regionNewer, d.inRegion = d.regionOlder[:0], d.regionNewer[:0], false
+	index := d.regionStart
+	if d.equal != nil {
+		for len(older) > 0 && len(newer) > 0 && d.equal(older[0], newer[0]) {
+			older, newer = older[1:], newer[1:]
+			index++
+		}
+		for len(older) > 0 && len(newer) > 0 && d.equal(older[len(older)-1], newer[len(newer)-1]) {
+			older, newer = older[:len(older)-1], newer[:len(newer)-1]
+		}
+	}
+	if d.equal == nil || len(older)*len(newer) > alignLimit {
+		d.emitRun(index, older, newer)
+		return
+	}
+
+	// common[i][j] is the length of the longest common subsequence of older[i:] and
+	// newer[j:]; walking it forward keeps the common elements and collects the runs
+	// between them
+	width := len(newer) + 1
+	common := make([]int, (len(older)+1)*width)
+	for i := len(older) - 1; i >= 0; i-- {
+		for j := len(newer) - 1; j >= 0; j-- {
+			if d.equal(older[i], newer[j]) {
+				common[i*width+j] = common[(i+1)*width+j+1] + 1
+			} else {
+				common[i*width+j] = max(common[(i+1)*width+j], common[i*width+j+1])
+			}
+		}
+	}
+	i, j := 0, 0
+	runOlder, runNewer := 0, 0
+	for i < len(older) || j < len(newer) {
+		switch {
+		case i < len(older) && j < len(newer) && d.equal(older[i], newer[j]):
+			if index = d.emitRun(index, older[runOlder:i], newer[runNewer:j]); d.stopped {
+				return
+			}
+			i, j = i+1, j+1
+			runOlder, runNewer = i, j
+			index++
+		case j == len(newer) || (i < len(older) && common[(i+1)*width+j] >= common[i*width+j+1]):
+			i++
+		default:
+			j++
+		}
+	}
+	d.emitRun(index, older[runOlder:], newer[runNewer:])
+}
+
+// emitRun reports a run of elements replaced by another starting at a position: the
+// positions both runs hold are updates, and the rest an insertion or a removal. It
+// returns the position after the run.
+func (d *differ[A]) emitRun(index int, older, newer []A) int {
+	common := min(len(older), len(newer))
+	for offset := range common {
+		if d.equal == nil || d.equal(older[offset], newer[offset]) {
+			index++
+			continue
+		}
+		if !d.yield(Change[A]{Kind: ChangeUpdated, Index: index, Old: older[offset], New: newer[offset]}) {
+			d.stopped = true
+			return index
+		}
+		index++
+	}
+	for _, value := range older[common:] {
+		if !d.yield(Change[A]{Kind: ChangeRemoved, Index: index, Old: value}) {
+			d.stopped = true
+			return index
+		}
+	}
+	for _, value := range newer[common:] {
+		if !d.yield(Change[A]{Kind: ChangeInserted, Index: index, New: value}) {
+			d.stopped = true
+			return index
+		}
+		index++
+	}
+	return index
+}
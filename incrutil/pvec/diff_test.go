@@ -0,0 +1,112 @@
+package pvec
+
+import (
+	"math/rand"
+	"slices"
+	"testing"
+)
+
+func intsEqual(a, b int) bool { return a == b }
+
+// replay applies changes to a copy of a slice, checking that each removal and update
+// names the element that was actually there.
+func replay(t *testing.T, values []int, changes []Change[int]) []int {
+	t.Helper()
+	values = slices.Clone(values)
+	for _, change := range changes {
+		switch change.Kind {
+		case ChangeInserted:
+			values = slices.Insert(values, change.Index, change.New)
+		case ChangeRemoved:
+			if values[change.Index] != change.Old {
+				t.Fatalf("removal at %d reports %d, but %d is there", change.Index, change.Old, values[change.Index])
+			}
+			values = slices.Delete(values, change.Index, change.Index+1)
+		case ChangeUpdated:
+			if values[change.Index] != change.Old {
+				t.Fatalf("update at %d reports %d, but %d is there", change.Index, change.Old, values[change.Index])
+			}
+			values[change.Index] = change.New
+		}
+	}
+	return values
+}
+
+func Test_Diff(t *testing.T) {
+	base := FromSlice([]int{0, 1, 2, 3, 4})
+	if got := slices.Collect(base.Diff(base, intsEqual)); len(got) != 0 {
+		t.Fatalf("a vector differs from itself: %v", got)
+	}
+
+	changed := base.Delete(0).Set(1, 20).Append(5)
+	got := slices.Collect(base.Diff(changed, intsEqual))
+	want := []Change[int]{
+		{Kind: ChangeRemoved, Index: 0, Old: 0},
+		{Kind: ChangeUpdated, Index: 1, Old: 2, New: 20},
+		{Kind: ChangeInserted, Index: 4, New: 5},
+	}
+	if !slices.Equal(got, want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+
+	// two vectors built separately share nothing, and still diff correctly
+	other := FromSlice([]int{0, 1, 2, 3, 4})
+	if got := slices.Collect(base.Diff(other, intsEqual)); len(got) != 0 {
+		t.Fatalf("equal vectors built separately differ: %v", got)
+	}
+}
+
+// Test_Diff_matchesReference replays the diff between random versions onto the older
+// one and checks it gives the newer, across edits that split and merge chunks and
+// reshape the tree.
+func Test_Diff_matchesReference(t *testing.T) {
+	rng := rand.New(rand.NewSource(31))
+	v := FromSlice(make([]int, 300))
+	for step := range 2000 {
+		next := v
+		for range rng.Intn(6) + 1 {
+			switch op := rng.Intn(7); {
+			case op < 2:
+				next = next.Insert(rng.Intn(next.Len()+1), rng.Intn(10))
+			case op < 4 && next.Len() > 0:
+				next = next.Delete(rng.Intn(next.Len()))
+			case op < 6 && next.Len() > 0:
+				next = next.Set(rng.Intn(next.Len()), rng.Intn(10))
+			default:
+				cut := rng.Intn(next.Len() + 1)
+				next = next.Slice(cut, next.Len()).Concat(next.Slice(0, cut))
+			}
+		}
+		got := replay(t, v.Values(), slices.Collect(v.Diff(next, intsEqual)))
+		if !slices.Equal(got, next.Values()) {
+			t.Fatalf("step %d: replaying the diff does not give the newer vector", step)
+		}
+		v = next
+	}
+}
+
+// Test_Diff_work asserts that diffing two versions a few edits apart costs the same
+// however long the vectors are, which is what skipping shared chunks buys.
+func Test_Diff_work(t *testing.T) {
+	comparisons := func(size int) int {
+		values := make([]int, size)
+		for index := range values {
+			values[index] = index
+		}
+		older := FromSlice(values)
+		newer := older.Set(size/3, -1).Insert(size/2, -2).Delete(size - 10)
+		var calls int
+		changes := slices.Collect(older.Diff(newer, func(a, b int) bool {
+			calls++
+			return a == b
+		}))
+		if len(changes) != 3 {
+			t.Fatalf("size %d: got %d changes, want 3: %v", size, len(changes), changes)
+		}
+		return calls
+	}
+	small, large := comparisons(1024), comparisons(1<<20)
+	if large > 2*small {
+		t.Fatalf("three edits cost %d comparisons at 1024 elements and %d at 1M", small, large)
+	}
+}
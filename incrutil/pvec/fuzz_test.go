@@ -0,0 +1,42 @@
+package pvec
+
+import (
+	"slices"
+	"testing"
+)
+
+// FuzzDiff drives a vector through arbitrary edits and checks that replaying the diff
+// from the version before them gives the version after, and that the tree is still
+// balanced. The diff skips chunks by pointer, so a bug in where it resynchronizes shows
+// up as a change that is silently never reported.
+func FuzzDiff(f *testing.F) {
+	f.Add([]byte{0, 5, 1, 3, 2, 9, 3, 5})
+	f.Add([]byte{3, 0, 3, 1, 0, 200, 1, 7})
+	f.Fuzz(func(t *testing.T, data []byte) {
+		v := FromSlice(make([]int, 100))
+		older := v
+		for i := 0; i+1 < len(data); i += 2 {
+			position := int(data[i+1])
+			switch data[i] % 4 {
+			case 0:
+				v = v.Insert(position%(v.Len()+1), position)
+			case 1:
+				if v.Len() > 0 {
+					v = v.Delete(position % v.Len())
+				}
+			case 2:
+				if v.Len() > 0 {
+					v = v.Set(position%v.Len(), -position)
+				}
+			case 3:
+				cut := position % (v.Len() + 1)
+				v = v.Slice(cut, v.Len()).Concat(v.Slice(0, cut))
+			}
+		}
+		checkStructure(t, v.root)
+		got := replay(t, older.Values(), slices.Collect(older.Diff(v, intsEqual)))
+		if !slices.Equal(got, v.Values()) {
+			t.Fatalf("replaying the diff gives %v, want %v", got, v.Values())
+		}
+	})
+}
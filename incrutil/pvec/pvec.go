@@ -0,0 +1,336 @@
+// Package pvec provides an immutable vector with structural sharing.
+//
+// It is to slices what [pmap] is to maps. Updating a [Vector] returns a new vector that
+// shares every part it did not have to rebuild, so [Vector.Diff] can skip the shared
+// parts by comparing pointers and report the positional changes between two versions
+// in time proportional to the changes rather than to the length.
+//
+// A slice offers none of that: two slices share nothing a diff can recognize, so an
+// incremental computation over an Incr[[]A] has to compare or recompute the whole slice
+// every time. The incremental slice operators in slicei accept a vector's diff in place
+// of a slice for that reason.
+//
+// The vector is a balanced tree whose leaves hold chunks of up to 32 elements, with the
+// size of every subtree recorded. Positional access, Set, Insert and Delete cost
+// O(log n); Slice and Concat cost O(log n) as well, since they split and join trees
+// rather than copying elements. The zero Vector is a valid empty vector.
+//
+// [pmap]: github.com/wcharczuk/go-incr/incrutil/pmap
+package pvec
+
+import (
+	"fmt"
+	"iter"
+)
+
+// chunkSize is the most elements a leaf holds. Larger chunks make iteration and
+// building cheaper and every edit dearer, since an edit copies its chunk.
+const chunkSize = 32
+
+// Vector is an immutable sequence of A.
+//
+// A Vector value is a handle onto a shared tree; copying one is cheap and the copies are
+// independent, since no operation mutates a tree that is already reachable.
+type Vector[A any] struct {
+	root *node[A]
+}
+
+// node is either a leaf holding a chunk of elements, or a branch with two children,
+// neither of them nil. Once published a node is never mutated.
+type node[A any] struct {
+	items       []A
+	left, right *node[A]
+	// height is 1 for a leaf; size is the number of elements beneath.
+	height int
+	size   int
+}
+
+// New returns an empty vector. The zero value is equally valid.
+func New[A any]() Vector[A] {
+	return Vector[A]{}
+}
+
+// FromSlice returns a vector holding a copy of values, built in O(n).
+func FromSlice[A any](values []A) Vector[A] {
+	if len(values) == 0 {
+		return Vector[A]{}
+	}
+	leaves := make([]*node[A], 0, (len(values)+chunkSize-1)/chunkSize)
+	for start := 0; start < len(values); start += chunkSize {
+		end := min(start+chunkSize, len(values))
+		leaves = append(leaves, newLeaf(append([]A(nil), values[start:end]...)))
+	}
+	return Vector[A]{root: buildBalanced(leaves)}
+}
+
+func buildBalanced[A any](leaves []*node[A]) *node[A] {
+	if len(leaves) == 1 {
+		return leaves[0]
+	}
+	middle := len(leaves) / 2
+	return newBranch(buildBalanced(leaves[:middle]), buildBalanced(leaves[middle:]))
+}
+
+// Len returns the number of elements, in constant time.
+func (v Vector[A]) Len() int { return v.root.treeSize() }
+
+// At returns the element at a position. It panics if the position is out of range, as
+// indexing a slice would.
+func (v Vector[A]) At(index int) A {
+	v.checkIndex(index, v.Len())
+	cursor := v.root
+	for !cursor.leaf() {
+		if leftSize := cursor.left.size; index < leftSize {
+			cursor = cursor.left
+		} else {
+			index -= leftSize
+			cursor = cursor.right
+		}
+	}
+	return cursor.items[index]
+}
+
+// Set returns a vector with the element at a position replaced. Only the path to the
+// position is rebuilt. It panics if the position is out of range.
+func (v Vector[A]) Set(index int, value A) Vector[A] {
+	v.checkIndex(index, v.Len())
+	return Vector[A]{root: set(v.root, index, value)}
+}
+
+// Insert returns a vector with value placed at a position, shifting the element there
+// and everything after it up by one. Insert at Len appends. It panics if the position
+// is out of range.
+func (v Vector[A]) Insert(index int, value A) Vector[A] {
+	v.checkIndex(index, v.Len()+1)
+	return Vector[A]{root: insert(v.root, index, value)}
+}
+
+// Append returns a vector with values added to the end.
+func (v Vector[A]) Append(values ...A) Vector[A] {
+	if len(values) > chunkSize {
+		return v.Concat(FromSlice(values))
+	}
+	root := v.root
+	for _, value := range values {
+		root = insert(root, root.treeSize(), value)
+	}
+	return Vector[A]{root: root}
+}
+
+// Delete returns a vector without the element at a position. It panics if the position
+// is out of range.
+func (v Vector[A]) Delete(index int) Vector[A] {
+	v.checkIndex(index, v.Len())
+	return Vector[A]{root: remove(v.root, index)}
+}
+
+// Slice returns the elements from low up to but not including high, sharing everything
+// outside the two cut points with the receiver. It panics if the bounds are out of
+// range, as slicing a slice would.
+func (v Vector[A]) Slice(low, high int) Vector[A] {
+	if low < 0 || high < low || high > v.Len() {
+		panic(fmt.Sprintf("pvec: slice bounds [%d:%d] out of range with length %d", low, high, v.Len()))
+	}
+	_, rest := split(v.root, low)
+	kept, _ := split(rest, high-low)
+	return Vector[A]{root: kept}
+}
+
+// Concat returns the elements of the receiver followed by those of other.
+func (v Vector[A]) Concat(other Vector[A]) Vector[A] {
+	return Vector[A]{root: concat(v.root, other.root)}
+}
+
+// All iterates the elements with their positions.
+func (v Vector[A]) All() iter.Seq2[int, A] {
+	return func(yield func(int, A) bool) {
+		index := 0
+		v.root.eachLeaf(func(items []A) bool {
+			for _, value := range items {
+				if !yield(index, value) {
+					return false
+				}
+				index++
+			}
+			return true
+		})
+	}
+}
+
+// Values returns the elements as a slice.
+func (v Vector[A]) Values() []A {
+	output := make([]A, 0, v.Len())
+	v.root.eachLeaf(func(items []A) bool {
+		output = append(output, items...)
+		return true
+	})
+	return output
+}
+
+func (v Vector[A]) checkIndex(index, length int) {
+	if index < 0 || index >= length {
+		panic(fmt.Sprintf("pvec: index %d out of range with length %d", index, v.Len()))
+	}
+}
+
+//
+// tree internals
+//
+
+func (n *node[A]) treeSize() int {
+	if n == nil {
+		return 0
+	}
+	return n.size
+}
+
+func (n *node[A]) treeHeight() int {
+	if n == nil {
+		return 0
+	}
+	return n.height
+}
+
+func (n *node[A]) leaf() bool { return n.height == 1 }
+
+func (n *node[A]) eachLeaf(yield func([]A) bool) bool {
+	if n == nil {
+		return true
+	}
+	if n.leaf() {
+		return yield(n.items)
+	}
+	return n.left.eachLeaf(yield) && n.right.eachLeaf(yield)
+}
+
+func newLeaf[A any](items []A) *node[A] {
+	return &node[A]{items: items, height: 1, size: len(items)}
+}
+
+func newBranch[A any](left, right *node[A]) *node[A] {
+	return &node[A]{
+		left:   left,
+		right:  right,
+		height: max(left.height, right.height) + 1,
+		size:   left.size + right.size,
+	}
+}
+
+// balance joins two subtrees whose heights differ by at most two, rotating if they
+// differ by two; this is the AVL scheme pmap uses, over positions rather than keys.
+func balance[A any](left, right *node[A]) *node[A] {
+	switch {
+	case left == nil:
+		return right
+	case right == nil:
+		return left
+	case left.height > right.height+1:
+		if left.left.height >= left.right.height {
+			return newBranch(left.left, newBranch(left.right, right))
+		}
+		lr := left.right
+		return newBranch(newBranch(left.left, lr.left), newBranch(lr.right, right))
+	case right.height > left.height+1:
+		if right.right.height >= right.left.height {
+			return newBranch(newBranch(left, right.left), right.right)
+		}
+		rl := right.left
+		return newBranch(newBranch(left, rl.left), newBranch(rl.right, right.right))
+	default:
+		return newBranch(left, right)
+	}
+}
+
+// concat joins two trees of any heights, descending the taller one's near spine until
+// the heights meet. The cost is the difference in heights.
+func concat[A any](left, right *node[A]) *node[A] {
+	switch {
+	case left == nil:
+		return right
+	case right == nil:
+		return left
+	case left.height > right.height+1:
+		return balance(left.left, concat(left.right, right))
+	case right.height > left.height+1:
+		return balance(concat(left, right.left), right.right)
+	case left.leaf() && right.leaf() && left.size+right.size <= chunkSize:
+		// two small chunks meeting at a seam are merged, which keeps repeated slicing
+		// and joining from fragmenting the leaves
+		items := make([]A, 0, left.size+right.size)
+		return newLeaf(append(append(items, left.items...), right.items...))
+	default:
+		return newBranch(left, right)
+	}
+}
+
+// split cuts a tree into the elements before a position and those from it on.
+func split[A any](n *node[A], index int) (before, after *node[A]) {
+	switch {
+	case n == nil:
+		return nil, nil
+	case index <= 0:
+		return nil, n
+	case index >= n.size:
+		return n, nil
+	case n.leaf():
+		return newLeaf(append([]A(nil), n.items[:index]...)), newLeaf(append([]A(nil), n.items[index:]...))
+	case index <= n.left.size:
+		before, after = split(n.left, index)
+		return before, concat(after, n.right)
+	default:
+		before, after = split(n.right, index-n.left.size)
+		return concat(n.left, before), after
+	}
+}
+
+func set[A any](n *node[A], index int, value A) *node[A] {
+	if n.leaf() {
+		items := append([]A(nil), n.items...)
+		items[index] = value
+		return newLeaf(items)
+	}
+	if index < n.left.size {
+		return newBranch(set(n.left, index, value), n.right)
+	}
+	return newBranch(n.left, set(n.right, index-n.left.size, value))
+}
+
+func insert[A any](n *node[A], index int, value A) *node[A] {
+	switch {
+	case n == nil:
+		return newLeaf([]A{value})
+	case n.leaf() && n.size < chunkSize:
+		items := make([]A, 0, n.size+1)
+		items = append(items, n.items[:index]...)
+		items = append(items, value)
+		return newLeaf(append(items, n.items[index:]...))
+	case n.leaf():
+		// a full chunk splits in two, and the value goes into whichever half holds
+		// its position
+		half := n.size / 2
+		left, right := newLeaf(append([]A(nil), n.items[:half]...)), newLeaf(append([]A(nil), n.items[half:]...))
+		if index <= half {
+			return newBranch(insert(left, index, value), right)
+		}
+		return newBranch(left, insert(right, index-half, value))
+	case index <= n.left.size:
+		return balance(insert(n.left, index, value), n.right)
+	default:
+		return balance(n.left, insert(n.right, index-n.left.size, value))
+	}
+}
+
+func remove[A any](n *node[A], index int) *node[A] {
+	if n.leaf() {
+		if n.size == 1 {
+			return nil
+		}
+		items := make([]A, 0, n.size-1)
+		items = append(items, n.items[:index]...)
+		return newLeaf(append(items, n.items[index+1:]...))
+	}
+	if index < n.left.size {
+		return balance(remove(n.left, index), n.right)
+	}
+	return balance(n.left, remove(n.right, index-n.left.size))
+}
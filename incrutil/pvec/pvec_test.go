@@ -0,0 +1,127 @@
+package pvec
+
+import (
+	"math/rand"
+	"slices"
+	"testing"
+)
+
+// checkStructure verifies the invariants the tree relies on: that every branch has two
+// children, that cached sizes and heights agree with the subtrees beneath, that chunks
+// are neither empty nor oversized, and that the AVL balance condition holds.
+func checkStructure[A any](t *testing.T, n *node[A]) (size, height int) {
+	t.Helper()
+	if n == nil {
+		return 0, 0
+	}
+	if n.leaf() {
+		if len(n.items) == 0 || len(n.items) > chunkSize {
+			t.Fatalf("a chunk holds %d elements", len(n.items))
+		}
+		if n.size != len(n.items) {
+			t.Fatalf("a chunk caches size %d, holds %d", n.size, len(n.items))
+		}
+		return n.size, 1
+	}
+	if n.left == nil || n.right == nil {
+		t.Fatal("a branch is missing a child")
+	}
+	leftSize, leftHeight := checkStructure(t, n.left)
+	rightSize, rightHeight := checkStructure(t, n.right)
+	size, height = leftSize+rightSize, max(leftHeight, rightHeight)+1
+	if n.size != size {
+		t.Fatalf("a branch caches size %d, subtrees hold %d", n.size, size)
+	}
+	if n.height != height {
+		t.Fatalf("a branch caches height %d, subtrees are %d deep", n.height, height)
+	}
+	if d := leftHeight - rightHeight; d < -1 || d > 1 {
+		t.Fatalf("a branch is unbalanced: left %d, right %d", leftHeight, rightHeight)
+	}
+	return size, height
+}
+
+func Test_Vector(t *testing.T) {
+	var v Vector[int]
+	if v.Len() != 0 || len(v.Values()) != 0 {
+		t.Fatal("the zero vector is not empty")
+	}
+	v = v.Append(1, 2, 3).Insert(0, 0).Insert(4, 4).Set(2, 20).Delete(1)
+	if want := []int{0, 20, 3, 4}; !slices.Equal(v.Values(), want) {
+		t.Fatalf("got %v, want %v", v.Values(), want)
+	}
+	if v.At(1) != 20 {
+		t.Fatalf("At(1) = %d", v.At(1))
+	}
+
+	// the receiver is unchanged by every operation
+	before := v
+	_ = v.Set(0, 99).Delete(0).Insert(0, 5).Append(6)
+	if want := []int{0, 20, 3, 4}; !slices.Equal(before.Values(), want) {
+		t.Fatalf("an operation changed its receiver: %v", before.Values())
+	}
+}
+
+func Test_Vector_panics(t *testing.T) {
+	expectPanic := func(label string, fn func()) {
+		t.Helper()
+		defer func() {
+			if recover() == nil {
+				t.Fatalf("%s did not panic", label)
+			}
+		}()
+		fn()
+	}
+	v := FromSlice([]int{1, 2, 3})
+	expectPanic("At past the end", func() { v.At(3) })
+	expectPanic("Delete before the start", func() { v.Delete(-1) })
+	expectPanic("Insert past the end", func() { v.Insert(4, 0) })
+	expectPanic("Slice with crossed bounds", func() { v.Slice(2, 1) })
+}
+
+// Test_Vector_matchesReference drives a vector and a slice through the same random
+// operations, including slicing and concatenation, checking the structure as it goes.
+func Test_Vector_matchesReference(t *testing.T) {
+	rng := rand.New(rand.NewSource(30))
+	var v Vector[int]
+	var reference []int
+	for step := range 4000 {
+		switch op := rng.Intn(10); {
+		case op < 4:
+			index, value := rng.Intn(len(reference)+1), rng.Int()
+			v = v.Insert(index, value)
+			reference = slices.Insert(reference, index, value)
+		case op < 6 && len(reference) > 0:
+			index := rng.Intn(len(reference))
+			v = v.Delete(index)
+			reference = slices.Delete(reference, index, index+1)
+		case op < 8 && len(reference) > 0:
+			index, value := rng.Intn(len(reference)), rng.Int()
+			v = v.Set(index, value)
+			reference[index] = value
+		case op == 8:
+			low := rng.Intn(len(reference) + 1)
+			high := low + rng.Intn(len(reference)-low+1)
+			// keep the slice and splice the rest back on, so the length does not collapse
+			v = v.Slice(low, high).Concat(v.Slice(0, low)).Concat(v.Slice(high, v.Len()))
+			reference = slices.Concat(reference[low:high], reference[:low], reference[high:])
+		default:
+			extra := make([]int, rng.Intn(80))
+			for index := range extra {
+				extra[index] = rng.Int()
+			}
+			v = v.Append(extra...)
+			reference = append(reference, extra...)
+		}
+		checkStructure(t, v.root)
+		if !slices.Equal(v.Values(), reference) {
+			t.Fatalf("step %d: vector and reference disagree", step)
+		}
+		if len(reference) > 0 {
+			index := rng.Intn(len(reference))
+			if v.At(index) != reference[index] {
+				t.Fatalf("step %d: At(%d) = %d, want %d", step, index, v.At(index), reference[index])
+			}
+		}
+	}
+}
@@ -0,0 +1,247 @@
+package slicei
+
+import (
+	"fmt"
+	"iter"
+
+	"github.com/wcharczuk/go-incr"
+	"github.com/wcharczuk/go-incr/incrutil/pvec"
+)
+
+// EditKind is what an [Edit] does to a slice.
+type EditKind uint8
+
+// EditKind values.
+const (
+	EditInsert EditKind = iota
+	EditRemove
+	EditUpdate
+)
+
+// String implements [fmt.Stringer].
+func (k EditKind) String() string {
+	switch k {
+	case EditInsert:
+		return "insert"
+	case EditRemove:
+		return "remove"
+	case EditUpdate:
+		return "update"
+	default:
+		return fmt.Sprintf("EditKind(%d)", uint8(k))
+	}
+}
+
+// Edit is one positional change to a slice.
+//
+// An insert places Value so that it ends up at Index, shifting what was there and
+// everything after it up by one; a remove takes out the element at Index, which Value
+// holds; an update replaces the element at Index with Value.
+type Edit[A any] struct {
+	Kind  EditKind
+	Index int
+	Value A
+}
+
+// Changes is a version of a slice together with the edits that produced it.
+//
+// This is what the diff-aware operators consume and produce in place of whole slices.
+// The edits, applied in order to the previous version, give this one, so an operator
+// reading them does work proportional to the edits rather than to the slice: a sorted
+// and filtered view of a 100k element feed pays O(log n) per changed element.
+//
+// The contents are held in a [pvec.Vector], so a Changes is immutable and cheap to hand
+// out, and Len and At cost O(log n). Values copies them out, in O(n).
+type Changes[A any] struct {
+	Edits []Edit[A]
+
+	contents pvec.Vector[A]
+	// generation numbers each version an operator publishes, and the edits describe the
+	// difference from the one numbered previous. A reader that did not see that version
+	// -- because it was unnecessary at the time, or was created since -- cannot use the
+	// edits and reads the contents instead. A previous of zero means the version was not
+	// derived from an earlier one at all, so every reader has to do so.
+	generation uint64
+	previous   uint64
+}
+
+// Len returns the length of the slice.
+func (c Changes[A]) Len() int { return c.contents.Len() }
+
+// At returns the element at a position, in O(log n).
+//
+// At panics if the position is out of range, as indexing a slice would.
+func (c Changes[A]) At(index int) A {
+	return c.contents.At(index)
+}
+
+// All iterates the elements with their positions.
+func (c Changes[A]) All() iter.Seq2[int, A] { return c.contents.All() }
+
+// Values returns the elements as a slice.
+func (c Changes[A]) Values() []A { return c.contents.Values() }
+
+// Vector returns the elements as a vector, which shares its structure with the Changes
+// rather than copying it.
+func (c Changes[A]) Vector() pvec.Vector[A] { return c.contents }
+
+// Feed is a slice edited in place, published to the graph as [Changes].
+//
+// A [incr.Var] of a slice can only be replaced wholesale, at which point nothing
+// downstream knows what changed. A Feed instead records each edit, and the edits made
+// between two stabilizations reach the graph as one batch.
+//
+// Edits panic if the position is out of range, as indexing a slice would.
+type Feed[A any] struct {
+	graph    incr.IExpertGraph
+	v        incr.VarIncr[Changes[A]]
+	contents pvec.Vector[A]
+	pending  []Edit[A]
+	// batchAt is the stabilization the pending edits will be read by, and generation
+	// and previous number the batch as described on [Changes].
+	batchAt    uint64
+	generation uint64
+	previous   uint64
+}
+
+// NewFeed returns a new feed holding some initial values.
+func NewFeed[A any](scope incr.Scope, initial ...A) *Feed[A] {
+	f := &Feed[A]{
+		graph:      incr.ExpertGraph(incr.GraphForScope(scope)),
+		contents:   pvec.FromSlice(initial),
+		generation: 1,
+	}
+	f.batchAt = f.graph.StabilizationNum()
+	f.v = incr.Var(scope, f.changes())
+	f.v.Node().SetKind("slicei_feed")
+	return f
+}
+
+// Changes returns the incremental the feed publishes to.
+func (f *Feed[A]) Changes() incr.Incr[Changes[A]] { return f.v }
+
+// Len returns the length of the slice as edited so far.
+func (f *Feed[A]) Len() int { return f.contents.Len() }
+
+// Insert places a value at a position, shifting what was there up by one.
+func (f *Feed[A]) Insert(index int, value A) {
+	f.contents = f.contents.Insert(index, value)
+	f.publish(Edit[A]{Kind: EditInsert, Index: index, Value: value})
+}
+
+// Append adds values to the end.
+func (f *Feed[A]) Append(values ...A) {
+	for _, value := range values {
+		f.Insert(f.Len(), value)
+	}
+}
+
+// Remove takes out the value at a position.
+func (f *Feed[A]) Remove(index int) {
+	value := f.contents.At(index)
+	f.contents = f.contents.Delete(index)
+	f.publish(Edit[A]{Kind: EditRemove, Index: index, Value: value})
+}
+
+// Update replaces the value at a position.
+func (f *Feed[A]) Update(index int, value A) {
+	f.contents = f.contents.Set(index, value)
+	f.publish(Edit[A]{Kind: EditUpdate, Index: index, Value: value})
+}
+
+func (f *Feed[A]) publish(edit Edit[A]) {
+	// A batch ends when a stabilization reads it. During a stabilization the var defers
+	// the set until the end, so a batch started then is read by the next one; carrying
+	// on with the batch being read would hand out edits under a generation its readers
+	// have already seen.
+	readBy := f.graph.StabilizationNum()
+	if incr.GraphForNode(f.v).IsStabilizing() {
+		readBy++
+	}
+	if readBy != f.batchAt {
+		f.batchAt = readBy
+		f.pending = nil
+		f.previous = f.generation
+		f.generation++
+	}
+	f.pending = append(f.pending, edit)
+	f.v.Set(f.changes())
+}
+
+func (f *Feed[A]) changes() Changes[A] {
+	return Changes[A]{
+		Edits:      f.pending,
+		contents:   f.contents,
+		generation: f.generation,
+		previous:   f.previous,
+	}
+}
+
+// changesReader tracks which version of an input [Changes] an operator last read.
+type changesReader struct {
+	seen uint64
+}
+
+// follows reports whether the edits on a version describe the difference from the one
+// the reader last read, which is when they can be applied rather than starting over.
+// Reading it moves the reader on either way.
+func follows[A any](r *changesReader, input Changes[A]) (ok bool) {
+	ok = r.seen != 0 && input.previous == r.seen
+	r.seen = input.generation
+	return
+}
+
+// changesWriter accumulates an operator's output edits and the contents they produce.
+type changesWriter[A any] struct {
+	contents   pvec.Vector[A]
+	edits      []Edit[A]
+	generation uint64
+	rebuilt    bool
+}
+
+func (w *changesWriter[A]) insert(index int, value A) {
+	w.contents = w.contents.Insert(index, value)
+	w.edits = append(w.edits, Edit[A]{Kind: EditInsert, Index: index, Value: value})
+}
+
+func (w *changesWriter[A]) remove(index int) {
+	value := w.contents.At(index)
+	w.contents = w.contents.Delete(index)
+	w.edits = append(w.edits, Edit[A]{Kind: EditRemove, Index: index, Value: value})
+}
+
+func (w *changesWriter[A]) update(index int, value A) {
+	w.contents = w.contents.Set(index, value)
+	w.edits = append(w.edits, Edit[A]{Kind: EditUpdate, Index: index, Value: value})
+}
+
+// rebuild replaces the contents outright, which readers learn of by the chain of
+// generations breaking.
+func (w *changesWriter[A]) rebuild(values []A) {
+	w.contents = pvec.FromSlice(values)
+	w.edits = nil
+	w.rebuilt = true
+}
+
+// unchanged reports whether there is nothing to publish.
+func (w *changesWriter[A]) unchanged() bool {
+	return !w.rebuilt && len(w.edits) == 0
+}
+
+// publish returns the next version and starts collecting edits for the one after.
+func (w *changesWriter[A]) publish() Changes[A] {
+	previous := w.generation
+	if w.rebuilt {
+		previous = 0
+	}
+	w.generation++
+	out := Changes[A]{
+		Edits:      w.edits,
+		contents:   w.contents,
+		generation: w.generation,
+		previous:   previous,
+	}
+	w.edits = nil
+	w.rebuilt = false
+	return out
+}
@@ -0,0 +1,289 @@
+package slicei
+
+import (
+	"math/rand"
+	"slices"
+	"testing"
+
+	"github.com/wcharczuk/go-incr"
+	"github.com/wcharczuk/go-incr/incrutil/pvec"
+	"github.com/wcharczuk/go-incr/testutil"
+)
+
+// editMirror replays the edits an operator publishes onto a plain slice, which checks
+// the edits themselves rather than only the contents they arrive with.
+type editMirror struct {
+	seen   uint64
+	values []int
+}
+
+func (m *editMirror) check(t *testing.T, step int, label string, c Changes[int]) {
+	t.Helper()
+	switch {
+	case c.generation == m.seen:
+	case m.seen != 0 && c.previous == m.seen:
+		for _, edit := range c.Edits {
+			switch edit.Kind {
+			case EditInsert:
+				m.values = slices.Insert(m.values, edit.Index, edit.Value)
+			case EditRemove:
+				if m.values[edit.Index] != edit.Value {
+					t.Fatalf("step %d %s: removed %d at %d, but %d was there", step, label, edit.Value, edit.Index, m.values[edit.Index])
+				}
+				m.values = slices.Delete(m.values, edit.Index, edit.Index+1)
+			case EditUpdate:
+				m.values[edit.Index] = edit.Value
+			}
+		}
+	default:
+		m.values = c.Values()
+	}
+	m.seen = c.generation
+	if !slices.Equal(m.values, c.Values()) {
+		t.Fatalf("step %d %s: edits give %v, contents are %v", step, label, m.values, c.Values())
+	}
+}
+
+func Test_Feed(t *testing.T) {
+	ctx := testContext()
+	g := incr.New()
+
+	f := NewFeed(g, 1, 2, 3)
+	of := incr.MustObserve(g, f.Changes())
+	testutil.NoError(t, g.Stabilize(ctx))
+	testutil.Equal(t, []int{1, 2, 3}, of.Value().Values())
+
+	f.Insert(0, 0)
+	f.Remove(2)
+	f.Update(2, 30)
+	f.Append(4)
+	testutil.NoError(t, g.Stabilize(ctx))
+	testutil.Equal(t, []int{0, 1, 30, 4}, of.Value().Values())
+	testutil.Equal(t, 4, len(of.Value().Edits))
+
+	// the next batch starts afresh
+	f.Remove(0)
+	testutil.NoError(t, g.Stabilize(ctx))
+	testutil.Equal(t, []Edit[int]{{Kind: EditRemove, Index: 0, Value: 0}}, of.Value().Edits)
+	testutil.Equal(t, 30, of.Value().At(1))
+}
+
+func Test_Changes_operators(t *testing.T) {
+	ctx := testContext()
+	g := incr.New()
+
+	f := NewFeed(g, 5, 8, 1, 6, 3)
+	even := func(v int) bool { return v%2 == 0 }
+	filtered := incr.MustObserve(g, FilterChanges(g, f.Changes(), even))
+	sorted := SortChanges(g, f.Changes(), Asc)
+	first := incr.MustObserve(g, TakeFirstChanges(g, sorted, 3))
+	last := incr.MustObserve(g, TakeLastChanges(g, sorted, 2))
+	testutil.NoError(t, g.Stabilize(ctx))
+	testutil.Equal(t, []int{8, 6}, filtered.Value().Values())
+	testutil.Equal(t, []int{1, 3, 5}, first.Value().Values())
+	testutil.Equal(t, []int{6, 8}, last.Value().Values())
+
+	f.Append(2)
+	f.Update(0, 9)
+	testutil.NoError(t, g.Stabilize(ctx))
+	testutil.Equal(t, []int{8, 6, 2}, filtered.Value().Values())
+	testutil.Equal(t, []int{1, 2, 3}, first.Value().Values())
+	testutil.Equal(t, []int{8, 9}, last.Value().Values())
+}
+
+func Test_TakeLastChanges_editsBeforeWindow(t *testing.T) {
+	ctx := testContext()
+	g := incr.New()
+
+	f := NewFeed(g, 1, 2, 3, 4, 5, 6)
+	last := TakeLastChanges(g, f.Changes(), 3)
+	var recomputes int
+	_ = incr.MustObserve(g, incr.Map(g, last, func(c Changes[int]) int {
+		recomputes++
+		return c.Len()
+	}))
+	ol := incr.MustObserve(g, last)
+	testutil.NoError(t, g.Stabilize(ctx))
+	testutil.Equal(t, []int{4, 5, 6}, ol.Value().Values())
+	testutil.Equal(t, 1, recomputes)
+
+	// the window follows the tail, so edits ahead of it leave it as it was
+	f.Insert(0, 10)
+	f.Remove(2)
+	f.Update(1, 20)
+	testutil.NoError(t, g.Stabilize(ctx))
+	testutil.Equal(t, []int{4, 5, 6}, ol.Value().Values())
+	testutil.Equal(t, 1, recomputes)
+
+	// an insert within it pushes its first element out
+	f.Insert(f.Len()-1, 50)
+	testutil.NoError(t, g.Stabilize(ctx))
+	testutil.Equal(t, []int{5, 50, 6}, ol.Value().Values())
+	testutil.Equal(t, []Edit[int]{
+		{Kind: EditRemove, Index: 0, Value: 4},
+		{Kind: EditInsert, Index: 1, Value: 50},
+	}, ol.Value().Edits)
+	testutil.Equal(t, 2, recomputes)
+}
+
+// Test_Changes_matchesReference cross-checks a pipeline of the diff-aware operators
+// against the whole-slice ones while random edits arrive in batches, including nodes
+// that only become necessary part way through and so have to start from the contents.
+func Test_Changes_matchesReference(t *testing.T) {
+	ctx := testContext()
+	g := incr.New()
+	rng := rand.New(rand.NewSource(29))
+
+	f := NewFeed[int](g)
+	even := func(v int) bool { return v%2 == 0 }
+	filtered := FilterChanges(g, f.Changes(), even)
+	sorted := SortChanges(g, filtered, Asc)
+	stages := map[string]incr.Incr[Changes[int]]{
+		"filter":     filtered,
+		"sort":       sorted,
+		"take first": TakeFirstChanges(g, sorted, 5),
+		"take last":  TakeLastChanges(g, sorted, 5),
+		"unfiltered": TakeFirstChanges(g, SortChanges(g, f.Changes(), Desc), 7),
+	}
+	reference := func(values []int) map[string][]int {
+		var kept []int
+		for _, v := range values {
+			if even(v) {
+				kept = append(kept, v)
+			}
+		}
+		slices.Sort(kept)
+		all := slices.Clone(values)
+		slices.SortFunc(all, Desc)
+		return map[string][]int{
+			"filter":     filterValues(values, even),
+			"sort":       kept,
+			"take first": kept[:min(5, len(kept))],
+			"take last":  kept[max(0, len(kept)-5):],
+			"unfiltered": all[:min(7, len(all))],
+		}
+	}
+
+	observers := map[string]incr.ObserveIncr[Changes[int]]{}
+	mirrors := map[string]*editMirror{}
+	var values []int
+	for step := range 400 {
+		// stages are observed one at a time as the test goes, and dropped again
+		for label, stage := range stages {
+			if _, ok := observers[label]; !ok && rng.Intn(20) == 0 {
+				observers[label] = incr.MustObserve(g, stage)
+				mirrors[label] = new(editMirror)
+			} else if ok && rng.Intn(40) == 0 {
+				observers[label].Unobserve(ctx)
+				delete(observers, label)
+			}
+		}
+		for range rng.Intn(4) + 1 {
+			switch op := rng.Intn(4); {
+			case op == 0 && len(values) > 0:
+				index := rng.Intn(len(values))
+				f.Remove(index)
+				values = slices.Delete(values, index, index+1)
+			case op == 1 && len(values) > 0:
+				index, value := rng.Intn(len(values)), rng.Intn(20)
+				f.Update(index, value)
+				values[index] = value
+			default:
+				index, value := rng.Intn(len(values)+1), rng.Intn(20)
+				f.Insert(index, value)
+				values = slices.Insert(values, index, value)
+			}
+		}
+		testutil.NoError(t, g.Stabilize(ctx))
+
+		want := reference(values)
+		for label, o := range observers {
+			got := o.Value()
+			if !slices.Equal(got.Values(), want[label]) {
+				t.Fatalf("step %d %s: got %v, want %v", step, label, got.Values(), want[label])
+			}
+			mirrors[label].check(t, step, label, got)
+		}
+	}
+}
+
+func filterValues(values []int, pred func(int) bool) (out []int) {
+	for _, v := range values {
+		if pred(v) {
+			out = append(out, v)
+		}
+	}
+	return
+}
+
+// Test_Changes_work asserts that one edit to a long feed costs a number of comparisons
+// logarithmic in its length, through a filter, a sort and a take.
+func Test_Changes_work(t *testing.T) {
+	ctx := testContext()
+	comparisons := func(size int) int {
+		g := incr.New()
+		initial := make([]int, size)
+		for index := range initial {
+			initial[index] = (index * 7919) % size
+		}
+		f := NewFeed(g, initial...)
+		var calls int
+		pred := func(v int) bool {
+			calls++
+			return v%3 != 0
+		}
+		sorted := SortChanges(g, FilterChanges(g, f.Changes(), pred), func(a, b int) int {
+			calls++
+			return Asc(a, b)
+		})
+		incr.MustObserve(g, TakeFirstChanges(g, sorted, 20))
+		testutil.NoError(t, g.Stabilize(ctx))
+
+		calls = 0
+		f.Update(size/2, 1)
+		f.Insert(size/3, 2)
+		testutil.NoError(t, g.Stabilize(ctx))
+		return calls
+	}
+	small, large := comparisons(1024), comparisons(65536)
+	if large > 3*small {
+		t.Fatalf("two edits cost %d calls at 1024 elements and %d at 65536", small, large)
+	}
+}
+
+// Test_VectorChanges checks that a vector replaced wholesale reaches the diff-aware
+// operators as the edits between its versions.
+func Test_VectorChanges(t *testing.T) {
+	ctx := testContext()
+	g := incr.New()
+	rng := rand.New(rand.NewSource(30))
+
+	vector := pvec.FromSlice([]int{3, 1, 2})
+	v := incr.Var(g, vector)
+	changes := VectorChanges(g, v, func(a, b int) bool { return a == b })
+	direct := incr.MustObserve(g, changes)
+	sorted := incr.MustObserve(g, SortChanges(g, changes, Asc))
+	testutil.NoError(t, g.Stabilize(ctx))
+	testutil.Equal(t, []int{1, 2, 3}, sorted.Value().Values())
+
+	var directMirror, sortedMirror editMirror
+	for step := range 300 {
+		switch op := rng.Intn(3); {
+		case op == 0 && vector.Len() > 0:
+			vector = vector.Delete(rng.Intn(vector.Len()))
+		case op == 1 && vector.Len() > 0:
+			vector = vector.Set(rng.Intn(vector.Len()), rng.Intn(50))
+		default:
+			vector = vector.Insert(rng.Intn(vector.Len()+1), rng.Intn(50))
+		}
+		v.Set(vector)
+		testutil.NoError(t, g.Stabilize(ctx))
+
+		directMirror.check(t, step, "direct", direct.Value())
+		sortedMirror.check(t, step, "sorted", sorted.Value())
+		testutil.Equal(t, vector.Values(), direct.Value().Values())
+		want := vector.Values()
+		slices.Sort(want)
+		testutil.Equal(t, want, sorted.Value().Values())
+	}
+}
@@ -0,0 +1,117 @@
+package slicei
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/wcharczuk/go-incr"
+)
+
+// FilterChanges is the diff-aware counterpart to [Filter]: it keeps the elements of a
+// [Changes] a predicate accepts, in order.
+//
+// The node keeps one flag per input element recording whether it was kept, in a tree
+// that also counts the kept elements beneath each node. An input position maps to an
+// output position by counting the kept elements before it, in O(log n), so each input
+// edit costs O(log n) and calls pred at most once, however long the slice.
+//
+// The node cuts off when no edit reached the output.
+func FilterChanges[A any](scope incr.Scope, input incr.Incr[Changes[A]], pred func(A) bool) incr.Incr[Changes[A]] {
+	f := &filterChangesIncr[A]{
+		n:    incr.NewNode("slicei_filter_changes"),
+		i:    input,
+		pred: pred,
+	}
+	f.parents[0] = input
+	return incr.WithinScope(scope, f)
+}
+
+var (
+	_ incr.Incr[Changes[int]] = (*filterChangesIncr[int])(nil)
+	_ incr.IStabilize         = (*filterChangesIncr[int])(nil)
+	_ incr.ICutoff            = (*filterChangesIncr[int])(nil)
+	_ incr.IParents           = (*filterChangesIncr[int])(nil)
+	_ fmt.Stringer            = (*filterChangesIncr[int])(nil)
+)
+
+type filterChangesIncr[A any] struct {
+	n      *incr.Node
+	i      incr.Incr[Changes[A]]
+	pred   func(A) bool
+	reader changesReader
+	// mask holds a flag per input element, which is also its value.
+	mask    *seq[bool]
+	out     changesWriter[A]
+	value   Changes[A]
+	parents [1]incr.INode
+}
+
+func (f *filterChangesIncr[A]) Parents() []incr.INode { return f.parents[:] }
+
+func (f *filterChangesIncr[A]) Node() *incr.Node { return f.n }
+
+func (f *filterChangesIncr[A]) Value() Changes[A] { return f.value }
+
+// Cutoff applies the input's edits and cuts off if none of them reached the output.
+//
+// The edits are applied here rather than in Stabilize because whether the output moved
+// is only known once they have been.
+func (f *filterChangesIncr[A]) Cutoff(_ context.Context) (bool, error) {
+	input := f.i.Value()
+	if !follows(&f.reader, input) {
+		f.rebuild(input)
+		return false, nil
+	}
+	for _, edit := range input.Edits {
+		switch edit.Kind {
+		case EditInsert:
+			keep := f.pred(edit.Value)
+			f.mask = f.mask.insertAt(edit.Index, keep, keep)
+			if keep {
+				f.out.insert(f.mask.keptBefore(edit.Index), edit.Value)
+			}
+		case EditRemove:
+			_, kept := f.mask.at(edit.Index)
+			position := f.mask.keptBefore(edit.Index)
+			f.mask = f.mask.removeAt(edit.Index)
+			if kept {
+				f.out.remove(position)
+			}
+		case EditUpdate:
+			_, kept := f.mask.at(edit.Index)
+			keep := f.pred(edit.Value)
+			position := f.mask.keptBefore(edit.Index)
+			f.mask = f.mask.setAt(edit.Index, keep, keep)
+			switch {
+			case kept && keep:
+				f.out.update(position, edit.Value)
+			case kept:
+				f.out.remove(position)
+			case keep:
+				f.out.insert(position, edit.Value)
+			}
+		}
+	}
+	return f.out.unchanged(), nil
+}
+
+func (f *filterChangesIncr[A]) rebuild(input Changes[A]) {
+	keeps := make([]bool, 0, input.Len())
+	var kept []A
+	for _, value := range input.All() {
+		keep := f.pred(value)
+		keeps = append(keeps, keep)
+		if keep {
+			kept = append(kept, value)
+		}
+	}
+	f.mask = seqFromSlice(keeps, func(keep bool) bool { return keep })
+	f.out.rebuild(kept)
+}
+
+func (f *filterChangesIncr[A]) Stabilize(_ context.Context) error {
+	f.value = f.out.publish()
+	return nil
+}
+
+func (f *filterChangesIncr[A]) String() string { return f.n.String() }
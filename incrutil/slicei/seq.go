@@ -0,0 +1,255 @@
+package slicei
+
+// seq is a balanced tree addressed by position, which is the index the diff-aware
+// operators keep alongside their input.
+//
+// It is a plainer relative of [pvec.Vector], with one element per node rather than
+// chunks, which is what lets it carry per-element bookkeeping: each element has a keep
+// flag and each subtree counts the flagged elements beneath it, so that a position in
+// an input maps to the position of the same element in a filtered output in O(log n);
+// see [FilterChanges].
+//
+// The same tree doubles as a sorted index when its elements are inserted by a
+// comparator rather than by position; see [SortChanges].
+type seq[T any] struct {
+	value       T
+	keep        bool
+	left, right *seq[T]
+	height      int
+	size        int
+	kept        int
+}
+
+func (n *seq[T]) treeSize() int {
+	if n == nil {
+		return 0
+	}
+	return n.size
+}
+
+func (n *seq[T]) treeHeight() int {
+	if n == nil {
+		return 0
+	}
+	return n.height
+}
+
+func (n *seq[T]) treeKept() int {
+	if n == nil {
+		return 0
+	}
+	return n.kept
+}
+
+// seqFromSlice builds a balanced tree from values in O(n), flagging the elements keep
+// reports true for, or all of them when keep is nil.
+func seqFromSlice[T any](values []T, keep func(T) bool) *seq[T] {
+	if len(values) == 0 {
+		return nil
+	}
+	middle := len(values) / 2
+	return seqJoin(values[middle], keep == nil || keep(values[middle]),
+		seqFromSlice(values[:middle], keep),
+		seqFromSlice(values[middle+1:], keep))
+}
+
+// at returns the element at a position and its keep flag.
+func (n *seq[T]) at(index int) (value T, keep bool) {
+	cursor := n
+	for cursor != nil {
+		leftSize := cursor.left.treeSize()
+		switch {
+		case index < leftSize:
+			cursor = cursor.left
+		case index == leftSize:
+			return cursor.value, cursor.keep
+		default:
+			index -= leftSize + 1
+			cursor = cursor.right
+		}
+	}
+	return
+}
+
+// keptBefore returns the number of flagged elements before a position.
+func (n *seq[T]) keptBefore(index int) (kept int) {
+	cursor := n
+	for cursor != nil {
+		leftSize := cursor.left.treeSize()
+		if index <= leftSize {
+			cursor = cursor.left
+			continue
+		}
+		kept += cursor.left.treeKept()
+		if cursor.keep {
+			kept++
+		}
+		index -= leftSize + 1
+		cursor = cursor.right
+	}
+	return
+}
+
+// each visits elements in order, stopping when yield returns false.
+func (n *seq[T]) each(yield func(T) bool) bool {
+	if n == nil {
+		return true
+	}
+	return n.left.each(yield) && yield(n.value) && n.right.each(yield)
+}
+
+func (n *seq[T]) insertAt(index int, value T, keep bool) *seq[T] {
+	if n == nil {
+		return seqJoin(value, keep, nil, nil)
+	}
+	leftSize := n.left.treeSize()
+	if index <= leftSize {
+		return seqBalance(n.value, n.keep, n.left.insertAt(index, value, keep), n.right)
+	}
+	return seqBalance(n.value, n.keep, n.left, n.right.insertAt(index-leftSize-1, value, keep))
+}
+
+func (n *seq[T]) removeAt(index int) *seq[T] {
+	if n == nil {
+		return nil
+	}
+	switch leftSize := n.left.treeSize(); {
+	case index < leftSize:
+		return seqBalance(n.value, n.keep, n.left.removeAt(index), n.right)
+	case index > leftSize:
+		return seqBalance(n.value, n.keep, n.left, n.right.removeAt(index-leftSize-1))
+	default:
+		return seqGlue(n.left, n.right)
+	}
+}
+
+func (n *seq[T]) setAt(index int, value T, keep bool) *seq[T] {
+	if n == nil {
+		return nil
+	}
+	switch leftSize := n.left.treeSize(); {
+	case index < leftSize:
+		return seqJoin(n.value, n.keep, n.left.setAt(index, value, keep), n.right)
+	case index > leftSize:
+		return seqJoin(n.value, n.keep, n.left, n.right.setAt(index-leftSize-1, value, keep))
+	default:
+		return seqJoin(value, keep, n.left, n.right)
+	}
+}
+
+// rankOf returns the position of an element in a tree ordered by compare, which must
+// order every element uniquely.
+func (n *seq[T]) rankOf(compare func(a, b T) int, value T) (rank int) {
+	cursor := n
+	for cursor != nil {
+		switch c := compare(value, cursor.value); {
+		case c < 0:
+			cursor = cursor.left
+		case c > 0:
+			rank += cursor.left.treeSize() + 1
+			cursor = cursor.right
+		default:
+			return rank + cursor.left.treeSize()
+		}
+	}
+	return
+}
+
+func (n *seq[T]) insertOrdered(compare func(a, b T) int, value T) *seq[T] {
+	if n == nil {
+		return seqJoin(value, true, nil, nil)
+	}
+	if compare(value, n.value) < 0 {
+		return seqBalance(n.value, n.keep, n.left.insertOrdered(compare, value), n.right)
+	}
+	return seqBalance(n.value, n.keep, n.left, n.right.insertOrdered(compare, value))
+}
+
+func (n *seq[T]) removeOrdered(compare func(a, b T) int, value T) *seq[T] {
+	if n == nil {
+		return nil
+	}
+	switch c := compare(value, n.value); {
+	case c < 0:
+		return seqBalance(n.value, n.keep, n.left.removeOrdered(compare, value), n.right)
+	case c > 0:
+		return seqBalance(n.value, n.keep, n.left, n.right.removeOrdered(compare, value))
+	default:
+		return seqGlue(n.left, n.right)
+	}
+}
+
+//
+// balancing; the same scheme as pmap's tree, keeping the kept counts alongside sizes
+//
+
+func seqJoin[T any](value T, keep bool, left, right *seq[T]) *seq[T] {
+	kept := left.treeKept() + right.treeKept()
+	if keep {
+		kept++
+	}
+	return &seq[T]{
+		value:  value,
+		keep:   keep,
+		left:   left,
+		right:  right,
+		height: max(left.treeHeight(), right.treeHeight()) + 1,
+		size:   left.treeSize() + right.treeSize() + 1,
+		kept:   kept,
+	}
+}
+
+func seqBalance[T any](value T, keep bool, left, right *seq[T]) *seq[T] {
+	lh, rh := left.treeHeight(), right.treeHeight()
+	switch {
+	case lh > rh+1:
+		if left.left.treeHeight() >= left.right.treeHeight() {
+			return seqJoin(left.value, left.keep, left.left, seqJoin(value, keep, left.right, right))
+		}
+		lr := left.right
+		return seqJoin(lr.value, lr.keep,
+			seqJoin(left.value, left.keep, left.left, lr.left),
+			seqJoin(value, keep, lr.right, right))
+	case rh > lh+1:
+		if right.right.treeHeight() >= right.left.treeHeight() {
+			return seqJoin(right.value, right.keep, seqJoin(value, keep, left, right.left), right.right)
+		}
+		rl := right.left
+		return seqJoin(rl.value, rl.keep,
+			seqJoin(value, keep, left, rl.left),
+			seqJoin(right.value, right.keep, rl.right, right.right))
+	default:
+		return seqJoin(value, keep, left, right)
+	}
+}
+
+func seqGlue[T any](left, right *seq[T]) *seq[T] {
+	if left == nil {
+		return right
+	}
+	if right == nil {
+		return left
+	}
+	if left.treeHeight() > right.treeHeight() {
+		rest, last := left.removeLast()
+		return seqBalance(last.value, last.keep, rest, right)
+	}
+	rest, first := right.removeFirst()
+	return seqBalance(first.value, first.keep, left, rest)
+}
+
+func (n *seq[T]) removeFirst() (rest, first *seq[T]) {
+	if n.left == nil {
+		return n.right, n
+	}
+	rest, first = n.left.removeFirst()
+	return seqBalance(n.value, n.keep, rest, n.right), first
+}
+
+func (n *seq[T]) removeLast() (rest, last *seq[T]) {
+	if n.right == nil {
+		return n.left, n
+	}
+	rest, last = n.right.removeLast()
+	return seqBalance(n.value, n.keep, n.left, rest), last
+}
@@ -0,0 +1,135 @@
+package slicei
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"slices"
+
+	"github.com/wcharczuk/go-incr"
+)
+
+// SortChanges is the diff-aware counterpart to [Sort]: it orders the elements of a
+// [Changes] by a sort func.
+//
+// The node keeps the elements twice: once in input order, so an edit at an input
+// position can find the element it touches, and once sorted, so that element's output
+// position is its rank. Both are trees, so each input edit costs O(log n) comparisons
+// and produces at most two output edits.
+//
+// Positions in the input shift as elements come and go, so they cannot break ties
+// between elements fn considers equal. Those are ordered instead by when they arrived,
+// which keeps the order stable across passes; like [Sort], it is not the order
+// [slices.SortStableFunc] would give.
+func SortChanges[A any](scope incr.Scope, input incr.Incr[Changes[A]], fn SortFunc[A]) incr.Incr[Changes[A]] {
+	s := &sortChangesIncr[A]{
+		n: incr.NewNode("slicei_sort_changes"),
+		i: input,
+	}
+	s.compare = func(a, b sortItem[A]) int {
+		if c := fn(a.value, b.value); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.id, b.id)
+	}
+	s.parents[0] = input
+	return incr.WithinScope(scope, s)
+}
+
+var (
+	_ incr.Incr[Changes[int]] = (*sortChangesIncr[int])(nil)
+	_ incr.IStabilize         = (*sortChangesIncr[int])(nil)
+	_ incr.ICutoff            = (*sortChangesIncr[int])(nil)
+	_ incr.IParents           = (*sortChangesIncr[int])(nil)
+	_ fmt.Stringer            = (*sortChangesIncr[int])(nil)
+)
+
+// sortItem is an element tagged with an arrival number, which makes every element's
+// place in the sorted tree unique.
+type sortItem[A any] struct {
+	id    uint64
+	value A
+}
+
+type sortChangesIncr[A any] struct {
+	n       *incr.Node
+	i       incr.Incr[Changes[A]]
+	compare func(a, b sortItem[A]) int
+	reader  changesReader
+	nextID  uint64
+	byInput *seq[sortItem[A]]
+	sorted  *seq[sortItem[A]]
+	out     changesWriter[A]
+	value   Changes[A]
+	parents [1]incr.INode
+}
+
+func (s *sortChangesIncr[A]) Parents() []incr.INode { return s.parents[:] }
+
+func (s *sortChangesIncr[A]) Node() *incr.Node { return s.n }
+
+func (s *sortChangesIncr[A]) Value() Changes[A] { return s.value }
+
+// Cutoff applies the input's edits; see [filterChangesIncr.Cutoff] for why here.
+func (s *sortChangesIncr[A]) Cutoff(_ context.Context) (bool, error) {
+	input := s.i.Value()
+	if !follows(&s.reader, input) {
+		s.rebuild(input)
+		return false, nil
+	}
+	for _, edit := range input.Edits {
+		switch edit.Kind {
+		case EditInsert:
+			item := s.newItem(edit.Value)
+			s.byInput = s.byInput.insertAt(edit.Index, item, true)
+			s.sorted = s.sorted.insertOrdered(s.compare, item)
+			s.out.insert(s.sorted.rankOf(s.compare, item), edit.Value)
+		case EditRemove:
+			item, _ := s.byInput.at(edit.Index)
+			position := s.sorted.rankOf(s.compare, item)
+			s.byInput = s.byInput.removeAt(edit.Index)
+			s.sorted = s.sorted.removeOrdered(s.compare, item)
+			s.out.remove(position)
+		case EditUpdate:
+			old, _ := s.byInput.at(edit.Index)
+			before := s.sorted.rankOf(s.compare, old)
+			item := sortItem[A]{id: old.id, value: edit.Value}
+			s.byInput = s.byInput.setAt(edit.Index, item, true)
+			s.sorted = s.sorted.removeOrdered(s.compare, old).insertOrdered(s.compare, item)
+			if after := s.sorted.rankOf(s.compare, item); after == before {
+				s.out.update(after, edit.Value)
+			} else {
+				s.out.remove(before)
+				s.out.insert(after, edit.Value)
+			}
+		}
+	}
+	return s.out.unchanged(), nil
+}
+
+func (s *sortChangesIncr[A]) newItem(value A) sortItem[A] {
+	s.nextID++
+	return sortItem[A]{id: s.nextID, value: value}
+}
+
+func (s *sortChangesIncr[A]) rebuild(input Changes[A]) {
+	items := make([]sortItem[A], 0, input.Len())
+	for _, value := range input.All() {
+		items = append(items, s.newItem(value))
+	}
+	s.byInput = seqFromSlice(items, nil)
+	slices.SortFunc(items, s.compare)
+	s.sorted = seqFromSlice(items, nil)
+	values := make([]A, len(items))
+	for index, item := range items {
+		values[index] = item.value
+	}
+	s.out.rebuild(values)
+}
+
+func (s *sortChangesIncr[A]) Stabilize(_ context.Context) error {
+	s.value = s.out.publish()
+	return nil
+}
+
+func (s *sortChangesIncr[A]) String() string { return s.n.String() }
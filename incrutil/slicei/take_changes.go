@@ -0,0 +1,190 @@
+package slicei
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/wcharczuk/go-incr"
+	"github.com/wcharczuk/go-incr/incrutil/pvec"
+)
+
+// TakeFirstChanges is the diff-aware counterpart to [TakeFirst]: the first count
+// elements of a [Changes].
+//
+// Each input edit costs O(log n) and produces at most two output edits -- one for the
+// edit itself if it lands in the window, and one for the element it pushes out of or
+// pulls into the window at the far end. Edits after the window cut off.
+func TakeFirstChanges[A any](scope incr.Scope, input incr.Incr[Changes[A]], count int) incr.Incr[Changes[A]] {
+	return takeChanges(scope, input, "slicei_take_first_changes", func(length int) (int, int) {
+		return 0, max(0, min(count, length))
+	})
+}
+
+// TakeLastChanges is the diff-aware counterpart to [TakeLast]: the last count elements
+// of a [Changes], at the same cost as [TakeFirstChanges].
+//
+// The window is counted from the end, so it moves with the tail of the input: an edit
+// wholly before the window shifts positions without changing what is inside it, and
+// produces no output edits at all. Within the window, an insert pushes its first element
+// out and a removal pulls the one before it in, one output edit each on top of the edit
+// itself.
+func TakeLastChanges[A any](scope incr.Scope, input incr.Incr[Changes[A]], count int) incr.Incr[Changes[A]] {
+	return takeChanges(scope, input, "slicei_take_last_changes", func(length int) (int, int) {
+		return max(0, length-max(0, count)), length
+	})
+}
+
+func takeChanges[A any](scope incr.Scope, input incr.Incr[Changes[A]], kind string, window func(length int) (start, end int)) incr.Incr[Changes[A]] {
+	t := &takeChangesIncr[A]{
+		n:      incr.NewNode(kind),
+		i:      input,
+		window: window,
+	}
+	t.parents[0] = input
+	return incr.WithinScope(scope, t)
+}
+
+var (
+	_ incr.Incr[Changes[int]] = (*takeChangesIncr[int])(nil)
+	_ incr.IStabilize         = (*takeChangesIncr[int])(nil)
+	_ incr.ICutoff            = (*takeChangesIncr[int])(nil)
+	_ incr.IParents           = (*takeChangesIncr[int])(nil)
+	_ fmt.Stringer            = (*takeChangesIncr[int])(nil)
+)
+
+// takeChangesIncr keeps a contiguous window of its input, where window gives the
+// window's bounds for a given input length.
+type takeChangesIncr[A any] struct {
+	n      *incr.Node
+	i      incr.Incr[Changes[A]]
+	window func(length int) (start, end int)
+	reader changesReader
+	// input is a copy of the input as the edits are applied one by one; the contents
+	// on the input describe it after all of them, which is not enough to know what
+	// enters the window part way through.
+	input   pvec.Vector[A]
+	out     changesWriter[A]
+	value   Changes[A]
+	parents [1]incr.INode
+}
+
+func (t *takeChangesIncr[A]) Parents() []incr.INode { return t.parents[:] }
+
+func (t *takeChangesIncr[A]) Node() *incr.Node { return t.n }
+
+func (t *takeChangesIncr[A]) Value() Changes[A] { return t.value }
+
+// Cutoff applies the input's edits; see [filterChangesIncr.Cutoff] for why here.
+func (t *takeChangesIncr[A]) Cutoff(_ context.Context) (bool, error) {
+	input := t.i.Value()
+	if !follows(&t.reader, input) {
+		t.input = input.contents
+		start, end := t.window(input.Len())
+		values := make([]A, 0, end-start)
+		for index := start; index < end; index++ {
+			values = append(values, t.input.At(index))
+		}
+		t.out.rebuild(values)
+		return false, nil
+	}
+	for _, edit := range input.Edits {
+		t.apply(edit)
+	}
+	return t.out.unchanged(), nil
+}
+
+// apply translates one input edit into output edits.
+//
+// The window moves by at most one position per edit, so the only elements that can
+// leave it are the one at either end and the one removed, and the only ones that can
+// enter are the one at either end of the new window and the one inserted. Each
+// candidate is mapped across the edit to see which side of the window it ends up on.
+func (t *takeChangesIncr[A]) apply(edit Edit[A]) {
+	oldStart, oldEnd := t.window(t.input.Len())
+	switch edit.Kind {
+	case EditInsert:
+		t.input = t.input.Insert(edit.Index, edit.Value)
+	case EditRemove:
+		t.input = t.input.Delete(edit.Index)
+	case EditUpdate:
+		t.input = t.input.Set(edit.Index, edit.Value)
+		if edit.Index >= oldStart && edit.Index < oldEnd {
+			t.out.update(edit.Index-oldStart, edit.Value)
+		}
+		return
+	}
+	newStart, newEnd := t.window(t.input.Len())
+
+	// forward maps an old position to a new one, and backward a new one to an old one;
+	// either reports false for the element the edit added or took away
+	forward := func(index int) (int, bool) {
+		switch {
+		case index < edit.Index:
+			return index, true
+		case edit.Kind == EditInsert:
+			return index + 1, true
+		case index == edit.Index:
+			return 0, false
+		default:
+			return index - 1, true
+		}
+	}
+	backward := func(index int) (int, bool) {
+		switch {
+		case index < edit.Index:
+			return index, true
+		case edit.Kind == EditRemove:
+			return index + 1, true
+		case index == edit.Index:
+			return 0, false
+		default:
+			return index - 1, true
+		}
+	}
+
+	// leaving elements are removed from the highest output position down, so the
+	// positions still to go stay valid
+	leaving := candidates(oldEnd-1, edit.Index, oldStart)
+	for _, index := range leaving {
+		if index < oldStart || index >= oldEnd {
+			continue
+		}
+		if mapped, ok := forward(index); ok && mapped >= newStart && mapped < newEnd {
+			continue
+		}
+		t.out.remove(index - oldStart)
+	}
+	// and entering ones inserted from the lowest up, each landing at its final position
+	entering := candidates(newStart, edit.Index, newEnd-1)
+	for _, index := range entering {
+		if index < newStart || index >= newEnd {
+			continue
+		}
+		if mapped, ok := backward(index); ok && mapped >= oldStart && mapped < oldEnd {
+			continue
+		}
+		t.out.insert(index-newStart, t.input.At(index))
+	}
+}
+
+// candidates returns up to three positions in the order given, without repeats.
+//
+// The positions passed are the window's ends with the edited position between them, so
+// given in ascending or descending order they come back in that order.
+func candidates(first, middle, last int) []int {
+	out := []int{first}
+	if middle != first {
+		out = append(out, middle)
+	}
+	if last != first && last != middle {
+		out = append(out, last)
+	}
+	return out
+}
+
+func (t *takeChangesIncr[A]) Stabilize(_ context.Context) error {
+	t.value = t.out.publish()
+	return nil
+}
+
+func (t *takeChangesIncr[A]) String() string { return t.n.String() }
@@ -0,0 +1,84 @@
+package slicei
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/wcharczuk/go-incr"
+	"github.com/wcharczuk/go-incr/incrutil/pvec"
+)
+
+// VectorChanges turns an incremental [pvec.Vector] into [Changes], so the diff-aware
+// operators can read it.
+//
+// This is the counterpart to [Feed] for values that arrive whole rather than as edits.
+// Each pass diffs the new vector against the previous one with [pvec.Vector.Diff], which
+// skips whatever the two share, so a vector derived from the previous one by a few edits
+// costs a few edits however long it is. equal decides whether an element at a position
+// changed, as for [pvec.Vector.Diff].
+func VectorChanges[A any](scope incr.Scope, input incr.Incr[pvec.Vector[A]], equal func(a, b A) bool) incr.Incr[Changes[A]] {
+	v := &vectorChangesIncr[A]{
+		n:     incr.NewNode("slicei_vector_changes"),
+		i:     input,
+		equal: equal,
+	}
+	v.parents[0] = input
+	return incr.WithinScope(scope, v)
+}
+
+var (
+	_ incr.Incr[Changes[int]] = (*vectorChangesIncr[int])(nil)
+	_ incr.IStabilize         = (*vectorChangesIncr[int])(nil)
+	_ incr.ICutoff            = (*vectorChangesIncr[int])(nil)
+	_ incr.IParents           = (*vectorChangesIncr[int])(nil)
+	_ fmt.Stringer            = (*vectorChangesIncr[int])(nil)
+)
+
+type vectorChangesIncr[A any] struct {
+	n       *incr.Node
+	i       incr.Incr[pvec.Vector[A]]
+	equal   func(a, b A) bool
+	seeded  bool
+	last    pvec.Vector[A]
+	out     changesWriter[A]
+	value   Changes[A]
+	parents [1]incr.INode
+}
+
+func (v *vectorChangesIncr[A]) Parents() []incr.INode { return v.parents[:] }
+
+func (v *vectorChangesIncr[A]) Node() *incr.Node { return v.n }
+
+func (v *vectorChangesIncr[A]) Value() Changes[A] { return v.value }
+
+// Cutoff diffs the input against the previous version, and cuts off if nothing changed.
+func (v *vectorChangesIncr[A]) Cutoff(_ context.Context) (bool, error) {
+	current := v.i.Value()
+	if !v.seeded {
+		v.out.contents, v.out.rebuilt = current, true
+		v.last = current
+		return false, nil
+	}
+	for change := range v.last.Diff(current, v.equal) {
+		switch change.Kind {
+		case pvec.ChangeInserted:
+			v.out.edits = append(v.out.edits, Edit[A]{Kind: EditInsert, Index: change.Index, Value: change.New})
+		case pvec.ChangeRemoved:
+			v.out.edits = append(v.out.edits, Edit[A]{Kind: EditRemove, Index: change.Index, Value: change.Old})
+		case pvec.ChangeUpdated:
+			v.out.edits = append(v.out.edits, Edit[A]{Kind: EditUpdate, Index: change.Index, Value: change.New})
+		}
+	}
+	// the input already is the contents the edits produce, so there is nothing to apply
+	v.out.contents = current
+	v.last = current
+	return v.out.unchanged(), nil
+}
+
+func (v *vectorChangesIncr[A]) Stabilize(_ context.Context) error {
+	v.value = v.out.publish()
+	v.seeded = true
+	return nil
+}
+
+func (v *vectorChangesIncr[A]) String() string { return v.n.String() }
@@ -0,0 +1,281 @@
+// Package source feeds values from outside a graph -- a channel, an [iter.Seq] or a pull
+// function -- into a var, through a [driver.Driver].
+//
+// Without it every program that streams data into a graph writes the same loop: receive,
+// set the var, stabilize, repeat. That loop stabilizes once per value however fast the
+// values arrive, cannot be shared by two streams without a lock around the graph, and
+// has no answer for a producer that outpaces the graph beyond blocking it. A source runs
+// the receiving side on a goroutine of its own and hands the values to the driver, which
+// batches them with everything else queued; the [Sink] decides what a batch of values
+// means to the var, and [Options] what happens when they arrive faster than the driver
+// takes them.
+//
+// The var is only ever set on the driver's goroutine, just before a stabilization, so a
+// source composes with anything else that sets the same var there, including a node
+// setting it mid-stabilization, which the graph defers to the next pass as usual.
+package source
+
+import (
+	"context"
+	"iter"
+	"sync"
+
+	"github.com/wcharczuk/go-incr"
+	"github.com/wcharczuk/go-incr/incrutil/driver"
+)
+
+// Sink is where a source's values go: a var, and how the values that arrived since the
+// previous batch become its new value.
+type Sink[T any] struct {
+	apply func(batch []T)
+	// latest sinks only ever need the last value received, so they keep one.
+	latest bool
+}
+
+// Latest sets the var to the most recent value received; values superseded before the
+// driver took them are dropped. With [OverflowBlock] nothing is dropped, and each value
+// is applied in a batch of its own.
+func Latest[T any](v incr.VarIncr[T]) Sink[T] {
+	return Sink[T]{
+		apply:  func(batch []T) { v.Set(batch[len(batch)-1]) },
+		latest: true,
+	}
+}
+
+// Accumulate sets the var to every value received since the previous batch, in arrival
+// order. The var holds a batch until the next one replaces it, so a node downstream
+// sees each value exactly once, in the stabilization after it arrived.
+func Accumulate[T any](v incr.VarIncr[[]T]) Sink[T] {
+	return Sink[T]{
+		apply: func(batch []T) { v.Set(batch) },
+	}
+}
+
+// Overflow is what a source does with a value when its pending batch is full.
+type Overflow uint8
+
+const (
+	// OverflowBlock stops receiving until the driver takes the batch, which pushes
+	// back on the producer: a channel fills and its sender blocks, and a pull function
+	// is not called.
+	OverflowBlock Overflow = iota
+	// OverflowDropOldest discards the value received earliest to make room.
+	OverflowDropOldest
+	// OverflowDropNewest discards the value just received.
+	OverflowDropNewest
+)
+
+// Options are the settings of a source.
+type Options struct {
+	// MaxPending is the most values a batch holds before Overflow applies. Zero means
+	// no limit, except for a [Latest] sink, whose batch holds one value.
+	MaxPending int
+	// Overflow is what happens to a value that arrives when the batch is full. The
+	// default for a [Latest] sink is [OverflowDropOldest], which is what latest wins
+	// means, and [OverflowBlock] otherwise.
+	Overflow Overflow
+	// overflowSet records whether Overflow was given, so the default can depend on the
+	// sink.
+	overflowSet bool
+}
+
+// Option mutates [Options].
+type Option func(*Options)
+
+// OptMaxPending sets [Options.MaxPending].
+func OptMaxPending(count int) Option {
+	return func(o *Options) { o.MaxPending = count }
+}
+
+// OptOverflow sets [Options.Overflow].
+func OptOverflow(overflow Overflow) Option {
+	return func(o *Options) {
+		o.Overflow = overflow
+		o.overflowSet = true
+	}
+}
+
+// Source is a running feed. It stops when its input is exhausted, when its context is
+// cancelled, or when the driver stops.
+type Source struct {
+	cancel context.CancelFunc
+	done   chan struct{}
+	err    error
+}
+
+// Done is closed once the source has stopped receiving.
+func (s *Source) Done() <-chan struct{} { return s.done }
+
+// Err returns why the source stopped, once Done is closed: nil when the input was
+// exhausted, the context's error when it was cancelled, the pull function's error, or
+// [driver.ErrStopped].
+func (s *Source) Err() error {
+	<-s.done
+	return s.err
+}
+
+// Stop cancels the source and waits for it to stop. Values already handed to the driver
+// are still applied.
+func (s *Source) Stop() error {
+	s.cancel()
+	return s.Err()
+}
+
+// Chan feeds a var from a channel until the channel is closed.
+func Chan[T any](ctx context.Context, d *driver.Driver, values <-chan T, sink Sink[T], opts ...Option) *Source {
+	return start(ctx, d, sink, opts, func(ctx context.Context) (value T, ok bool, err error) {
+		select {
+		case <-ctx.Done():
+			err = ctx.Err()
+		case value, ok = <-values:
+		}
+		return
+	}, nil)
+}
+
+// Seq feeds a var from an iterator until it ends.
+//
+// The iterator runs on the source's goroutine. Cancelling the context stops the source
+// between values, but cannot interrupt an iterator blocked producing one.
+func Seq[T any](ctx context.Context, d *driver.Driver, values iter.Seq[T], sink Sink[T], opts ...Option) *Source {
+	next, stop := iter.Pull(values)
+	return start(ctx, d, sink, opts, func(ctx context.Context) (value T, ok bool, err error) {
+		if err = ctx.Err(); err != nil {
+			return
+		}
+		value, ok = next()
+		return
+	}, stop)
+}
+
+// Pull feeds a var from a function, called for each value until it reports there are
+// no more or returns an error. It is passed the source's context and should return
+// when that is cancelled.
+func Pull[T any](ctx context.Context, d *driver.Driver, pull func(context.Context) (T, bool, error), sink Sink[T], opts ...Option) *Source {
+	return start(ctx, d, sink, opts, pull, nil)
+}
+
+func start[T any](ctx context.Context, d *driver.Driver, sink Sink[T], opts []Option, next func(context.Context) (T, bool, error), finish func()) *Source {
+	f := &feed[T]{
+		driver: d,
+		sink:   sink,
+		room:   make(chan struct{}, 1),
+	}
+	for _, opt := range opts {
+		opt(&f.options)
+	}
+	if sink.latest {
+		f.options.MaxPending = 1
+		if !f.options.overflowSet {
+			f.options.Overflow = OverflowDropOldest
+		}
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	s := &Source{cancel: cancel, done: make(chan struct{})}
+	go func() {
+		defer close(s.done)
+		defer cancel()
+		if finish != nil {
+			defer finish()
+		}
+		s.err = f.run(ctx, next)
+	}()
+	return s
+}
+
+// feed is the state shared by a source's goroutine and the driver's.
+type feed[T any] struct {
+	driver  *driver.Driver
+	sink    Sink[T]
+	options Options
+
+	mu sync.Mutex
+	// pending is the batch not yet taken by the driver; queued is whether the driver
+	// has work queued to take it, which is queued once per batch.
+	pending []T
+	queued  bool
+	// room is signalled when the driver takes a batch, for a source blocked on a full
+	// one.
+	room chan struct{}
+}
+
+func (f *feed[T]) run(ctx context.Context, next func(context.Context) (T, bool, error)) error {
+	for {
+		if err := f.waitForRoom(ctx); err != nil {
+			return err
+		}
+		value, ok, err := next(ctx)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return nil
+		}
+		if err := f.push(value); err != nil {
+			return err
+		}
+	}
+}
+
+// waitForRoom blocks while the batch is full and the overflow policy is to block.
+func (f *feed[T]) waitForRoom(ctx context.Context) error {
+	if f.options.Overflow != OverflowBlock {
+		return nil
+	}
+	for {
+		f.mu.Lock()
+		full := f.full()
+		f.mu.Unlock()
+		if !full {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-f.room:
+		}
+	}
+}
+
+func (f *feed[T]) full() bool {
+	return f.options.MaxPending > 0 && len(f.pending) >= f.options.MaxPending
+}
+
+// push adds a value to the batch, queueing the driver to take it if this is the batch's
+// first value.
+func (f *feed[T]) push(value T) error {
+	f.mu.Lock()
+	switch {
+	case !f.full():
+		f.pending = append(f.pending, value)
+	case f.options.Overflow == OverflowDropNewest:
+	default:
+		// a blocking source waited for room before receiving, so this is dropping
+		// the oldest
+		copy(f.pending, f.pending[1:])
+		f.pending[len(f.pending)-1] = value
+	}
+	first := !f.queued
+	f.queued = true
+	f.mu.Unlock()
+	if !first {
+		return nil
+	}
+	return f.driver.Do(func(*incr.Graph) { f.take() })
+}
+
+// take applies the pending batch to the var; it runs on the driver's goroutine.
+func (f *feed[T]) take() {
+	f.mu.Lock()
+	batch := f.pending
+	f.pending, f.queued = nil, false
+	f.mu.Unlock()
+	select {
+	case f.room <- struct{}{}:
+	default:
+	}
+	if len(batch) > 0 {
+		f.sink.apply(batch)
+	}
+}
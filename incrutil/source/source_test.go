@@ -0,0 +1,197 @@
+package source
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/wcharczuk/go-incr"
+	"github.com/wcharczuk/go-incr/incrutil/driver"
+	"github.com/wcharczuk/go-incr/testutil"
+)
+
+// run starts a driver for the length of a test.
+func run(t *testing.T, d *driver.Driver) {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- d.Run(ctx) }()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+}
+
+// watch collects every value an observer delivers.
+func watch[T any](t *testing.T, d *driver.Driver, o incr.ObserveIncr[T]) *[]T {
+	t.Helper()
+	var seen []T
+	testutil.NoError(t, driver.Watch(d, o, func(value T) { seen = append(seen, value) }))
+	return &seen
+}
+
+func Test_Chan_latest(t *testing.T) {
+	ctx := context.Background()
+	g := incr.New()
+	v := incr.Var(g, -1)
+	o := incr.MustObserve(g, v)
+	d := driver.New(g)
+	run(t, d)
+	seen := watch(t, d, o)
+
+	values := make(chan int)
+	s := Chan(ctx, d, values, Latest(v))
+	for value := range 100 {
+		values <- value
+	}
+	close(values)
+	testutil.NoError(t, s.Err())
+	testutil.NoError(t, d.Sync(ctx))
+
+	// values can be skipped, but what is seen is in order and ends on the last
+	testutil.Equal(t, true, len(*seen) > 0)
+	testutil.Equal(t, true, slices.IsSorted(*seen))
+	testutil.Equal(t, 99, (*seen)[len(*seen)-1])
+}
+
+func Test_Chan_latestBlock(t *testing.T) {
+	ctx := context.Background()
+	g := incr.New()
+	v := incr.Var(g, -1)
+	o := incr.MustObserve(g, v)
+	d := driver.New(g)
+	run(t, d)
+	seen := watch(t, d, o)
+
+	values := make(chan int)
+	s := Chan(ctx, d, values, Latest(v), OptOverflow(OverflowBlock))
+	for value := range 100 {
+		values <- value
+	}
+	close(values)
+	testutil.NoError(t, s.Err())
+	testutil.NoError(t, d.Sync(ctx))
+
+	// blocking, each value gets a stabilization of its own
+	testutil.Equal(t, 100, len(*seen))
+	for index, value := range *seen {
+		testutil.Equal(t, index, value)
+	}
+}
+
+func Test_Seq_accumulate(t *testing.T) {
+	ctx := context.Background()
+	g := incr.New()
+	v := incr.Var[[]int](g, nil)
+	o := incr.MustObserve(g, v)
+	d := driver.New(g)
+	run(t, d)
+	seen := watch(t, d, o)
+
+	s := Seq(ctx, d, func(yield func(int) bool) {
+		for value := range 1000 {
+			if !yield(value) {
+				return
+			}
+		}
+	}, Accumulate(v))
+	testutil.NoError(t, s.Err())
+	testutil.NoError(t, d.Sync(ctx))
+
+	// every value exactly once, however they were batched
+	var all []int
+	for _, batch := range *seen {
+		all = append(all, batch...)
+	}
+	testutil.Equal(t, 1000, len(all))
+	for index, value := range all {
+		testutil.Equal(t, index, value)
+	}
+}
+
+func Test_Seq_overflow(t *testing.T) {
+	ctx := context.Background()
+	values := func(yield func(int) bool) {
+		for value := range 10 {
+			if !yield(value) {
+				return
+			}
+		}
+	}
+	for _, testCase := range []struct {
+		overflow Overflow
+		expected []int
+	}{
+		{OverflowDropOldest, []int{7, 8, 9}},
+		{OverflowDropNewest, []int{0, 1, 2}},
+	} {
+		g := incr.New()
+		v := incr.Var[[]int](g, nil)
+		o := incr.MustObserve(g, v)
+		d := driver.New(g)
+
+		// the driver starts only once the source has finished, so nothing is taken
+		// while it runs and the batch overflows
+		s := Seq(ctx, d, values, Accumulate(v), OptMaxPending(3), OptOverflow(testCase.overflow))
+		testutil.NoError(t, s.Err())
+		run(t, d)
+		testutil.NoError(t, d.Sync(ctx))
+		var got []int
+		testutil.NoError(t, d.Do(func(*incr.Graph) { got = o.Value() }))
+		testutil.NoError(t, d.Sync(ctx))
+		testutil.Equal(t, testCase.expected, got)
+	}
+}
+
+func Test_Pull_block(t *testing.T) {
+	ctx := context.Background()
+	g := incr.New()
+	v := incr.Var[[]int](g, nil)
+	incr.MustObserve(g, v)
+	d := driver.New(g)
+
+	// with the driver not yet running, a blocking source stops pulling once its batch
+	// is full
+	calls := make(chan int, 16)
+	var pulled int
+	s := Pull(ctx, d, func(context.Context) (int, bool, error) {
+		pulled++
+		calls <- pulled
+		return pulled, pulled <= 5, nil
+	}, Accumulate(v), OptMaxPending(2))
+	<-calls
+	<-calls
+	select {
+	case <-calls:
+		t.Fatal("a source with a full batch pulled another value")
+	case <-time.After(50 * time.Millisecond):
+	}
+	run(t, d)
+	testutil.NoError(t, s.Err())
+	testutil.Equal(t, 6, pulled)
+}
+
+func Test_Source_stop(t *testing.T) {
+	ctx := context.Background()
+	g := incr.New()
+	v := incr.Var(g, 0)
+	d := driver.New(g)
+	run(t, d)
+
+	// a channel nobody sends on
+	s := Chan(ctx, d, make(chan int), Latest(v))
+	testutil.Equal(t, true, errors.Is(s.Stop(), context.Canceled))
+
+	failure := errors.New("failed")
+	s = Pull(ctx, d, func(context.Context) (int, bool, error) { return 0, false, failure }, Latest(v))
+	testutil.Equal(t, true, errors.Is(s.Err(), failure))
+
+	stopped := driver.New(incr.New())
+	driverCtx, cancel := context.WithCancel(ctx)
+	cancel()
+	_ = stopped.Run(driverCtx)
+	s = Seq(ctx, stopped, slices.Values([]int{1}), Latest(v))
+	testutil.Equal(t, true, errors.Is(s.Err(), driver.ErrStopped))
+}
@@ -0,0 +1,181 @@
+package incr
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// OptGraphCheckInvariants has the graph check its own structure, the way
+// [IExpertGraph.CheckInvariants] does, at the points a given check names.
+//
+// Violations are reported as an [*InvariantViolationsError], which the stabilization
+// returns unless a handler is set with [OptGraphOnInvariantViolations]. Each check walks
+// the whole graph, so this is for tests, CI and canaries rather than for production
+// graphs, and checking after every bind rebuild costs a walk per rebuild.
+func OptGraphCheckInvariants(check InvariantCheck) func(*GraphOptions) {
+	return func(g *GraphOptions) {
+		g.CheckInvariants = check
+	}
+}
+
+// OptGraphOnInvariantViolations sets a handler for the violations found by the checks
+// enabled with [OptGraphCheckInvariants], which is given them instead of the
+// stabilization returning them. This suits a canary that should keep computing while
+// it reports what is wrong.
+//
+// The handler is called at the end of the stabilization the violations were found in,
+// before update handlers run, once for the checks after bind rebuilds and once for the
+// check after the stabilization, for each that found anything.
+func OptGraphOnInvariantViolations(handler func(context.Context, *InvariantViolationsError)) func(*GraphOptions) {
+	return func(g *GraphOptions) {
+		g.OnInvariantViolations = handler
+	}
+}
+
+// InvariantCheck is when a graph checks its own invariants; see
+// [OptGraphCheckInvariants].
+type InvariantCheck uint8
+
+// Invariant checks.
+const (
+	// InvariantCheckNone does not check; it is the default.
+	InvariantCheckNone InvariantCheck = iota
+	// InvariantCheckStabilization checks after every stabilization, successful or not.
+	InvariantCheckStabilization
+	// InvariantCheckBindRebuild checks after every stabilization and also after each
+	// bind rebuilds its right-hand side, where a broken invariant is introduced more
+	// often than anywhere else. On the parallel path the binds of a batch are linked
+	// together, so the check is made once for the batch.
+	InvariantCheckBindRebuild
+)
+
+// Invariant names a structural property of the graph.
+type Invariant string
+
+// Invariants, as reported in an [InvariantViolation].
+const (
+	// InvariantSelf is that a node in the graph knows its own concrete value.
+	InvariantSelf Invariant = "self"
+	// InvariantNodeIndex is that a node records its own position in the graph's node list.
+	InvariantNodeIndex Invariant = "node index"
+	// InvariantInGraph is that a node in the graph's node list is marked as in the graph.
+	InvariantInGraph Invariant = "in graph"
+	// InvariantNodeCount is that the graph's node count matches the nodes, observers and
+	// sentinels it holds.
+	InvariantNodeCount Invariant = "node count"
+	// InvariantEdgeSymmetry is that an edge is recorded on both nodes it joins, the same
+	// number of times.
+	InvariantEdgeSymmetry Invariant = "edge symmetry"
+	// InvariantHeightOrder is that a node sits above each of its parents.
+	InvariantHeightOrder Invariant = "height order"
+	// InvariantNecessaryDependents is that a node keeps edges only to necessary children.
+	InvariantNecessaryDependents Invariant = "necessary dependents"
+	// InvariantRecomputeHeap is that the recompute heap holds each node at its height.
+	InvariantRecomputeHeap Invariant = "recompute heap"
+)
+
+// InvariantViolation is a single broken invariant, and where it is broken.
+type InvariantViolation struct {
+	// Invariant is the invariant broken.
+	Invariant Invariant
+	// Node, Kind and Height are the node it is broken at, if it is about one.
+	Node   Identifier
+	Kind   string
+	Height int
+	// Scope is the binds the node was created in, innermost first, by the identifiers of
+	// their main nodes. It is empty for a node created on the graph itself.
+	Scope []Identifier
+	// Other is the node at the other end of the edge, for a violation about an edge.
+	Other Identifier
+	// Message describes the violation.
+	Message string
+}
+
+// Error implements error.
+func (v *InvariantViolation) Error() string {
+	return v.Message
+}
+
+// InvariantViolationsError is what a check enabled with [OptGraphCheckInvariants] found.
+type InvariantViolationsError struct {
+	// StabilizationNum is the stabilization the check was made in.
+	StabilizationNum uint64
+	// Rebuilt are the binds, by the identifiers of their main nodes, whose rebuilds the
+	// check was made after; it is empty for the check after the stabilization.
+	Rebuilt []Identifier
+	// Violations are every violation the check found.
+	Violations []InvariantViolation
+}
+
+// Error implements error.
+func (e *InvariantViolationsError) Error() string {
+	sb := new(strings.Builder)
+	if len(e.Rebuilt) > 0 {
+		fmt.Fprintf(sb, "incr; stabilization %d; %d invariant violation(s) after rebuilding %v", e.StabilizationNum, len(e.Violations), e.Rebuilt)
+	} else {
+		fmt.Fprintf(sb, "incr; stabilization %d; %d invariant violation(s)", e.StabilizationNum, len(e.Violations))
+	}
+	for _, v := range e.Violations {
+		sb.WriteString("\n\t")
+		sb.WriteString(v.Message)
+	}
+	return sb.String()
+}
+
+// Unwrap returns each violation, so that [errors.As] finds them.
+func (e *InvariantViolationsError) Unwrap() []error {
+	errs := make([]error, len(e.Violations))
+	for index := range e.Violations {
+		errs[index] = &e.Violations[index]
+	}
+	return errs
+}
+
+// bindScope is implemented by bind scopes, to name the bind a node was created in.
+type bindScope interface {
+	scopeMain() INode
+}
+
+// checkInvariantsAfterBindRebuild checks the graph after the given binds have linked
+// their new right-hand sides, if it is to, and holds what it finds until the pass ends.
+func (graph *Graph) checkInvariantsAfterBindRebuild(rebuilt ...INode) {
+	if graph.invariantCheck != InvariantCheckBindRebuild {
+		return
+	}
+	violations := graph.invariantViolations()
+	if len(violations) == 0 {
+		return
+	}
+	report := &InvariantViolationsError{
+		StabilizationNum: graph.stabilizationNum,
+		Violations:       violations,
+	}
+	for _, n := range rebuilt {
+		report.Rebuilt = append(report.Rebuilt, n.(rightHandSideBuilder).bindMain().Node().id)
+	}
+	graph.invariantReports = append(graph.invariantReports, report)
+}
+
+// checkInvariantsAfterStabilization checks the graph at the end of a pass, if it is to,
+// and reports what this and the checks after bind rebuilds found, to the handler or by
+// adding it to the error the pass returns.
+func (graph *Graph) checkInvariantsAfterStabilization(ctx context.Context, err error) error {
+	reports := graph.invariantReports
+	graph.invariantReports = nil
+	if violations := graph.invariantViolations(); len(violations) > 0 {
+		reports = append(reports, &InvariantViolationsError{
+			StabilizationNum: graph.stabilizationNum,
+			Violations:       violations,
+		})
+	}
+	for _, report := range reports {
+		if graph.onInvariantViolations != nil {
+			graph.onInvariantViolations(ctx, report)
+			continue
+		}
+		err = errors.Join(err, report)
+	}
+	return err
+}
@@ -0,0 +1,169 @@
+package incr
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/wcharczuk/go-incr/testutil"
+)
+
+func Test_OptGraphCheckInvariants_clean(t *testing.T) {
+	for name, stabilize := range map[string]func(*Graph, context.Context) error{
+		"serial":       (*Graph).Stabilize,
+		"heightBlocks": (*Graph).ParallelStabilize,
+		"dataflow": func(g *Graph, ctx context.Context) error {
+			g.parallelScheduler = ParallelSchedulerDataflow
+			return g.ParallelStabilize(ctx)
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			ctx := testContext()
+			g := New(OptGraphCheckInvariants(InvariantCheckBindRebuild))
+
+			v := Var(g, 1)
+			m := Map(g, v, func(x int) int { return x + 1 })
+			sel := Var(g, 0)
+			b := Bind(g, sel, func(bs Scope, which int) Incr[int] {
+				return Map2(bs, m, Return(bs, which), func(a, c int) int { return a + c })
+			})
+			o := MustObserve(g, b)
+
+			for i := range 4 {
+				sel.Set(i)
+				testutil.NoError(t, stabilize(g, ctx))
+				testutil.Equal(t, 2+i, o.Value())
+			}
+		})
+	}
+}
+
+func Test_OptGraphCheckInvariants_stabilization(t *testing.T) {
+	ctx := testContext()
+	g := New(OptGraphCheckInvariants(InvariantCheckStabilization))
+
+	v := Var(g, 1)
+	sel := Var(g, 0)
+	var scoped Incr[int]
+	b := Bind(g, sel, func(bs Scope, which int) Incr[int] {
+		scoped = Map(bs, v, func(x int) int { return x + which })
+		return scoped
+	})
+	other := Map(g, v, func(x int) int { return x })
+	MustObserve(g, b)
+	MustObserve(g, other)
+	testutil.NoError(t, g.Stabilize(ctx))
+
+	// record the edge on one side only
+	ExpertNode(scoped).AddParents(other)
+	v.Set(2)
+	err := g.Stabilize(ctx)
+
+	var report *InvariantViolationsError
+	testutil.Equal(t, true, errors.As(err, &report))
+	testutil.Equal(t, uint64(2), report.StabilizationNum)
+	testutil.Equal(t, 0, len(report.Rebuilt))
+
+	var violation *InvariantViolation
+	testutil.Equal(t, true, errors.As(err, &violation), "each violation is an error of its own")
+
+	var asymmetry *InvariantViolation
+	for index, v := range report.Violations {
+		if v.Invariant == InvariantEdgeSymmetry && v.Node == scoped.Node().ID() {
+			asymmetry = &report.Violations[index]
+		}
+	}
+	testutil.NotNil(t, asymmetry)
+	testutil.Equal(t, other.Node().ID(), asymmetry.Other)
+	testutil.Equal(t, "map", asymmetry.Kind)
+	testutil.Equal(t, scoped.Node().height, asymmetry.Height)
+	testutil.Equal(t, []Identifier{b.Node().ID()}, asymmetry.Scope)
+}
+
+func Test_OptGraphCheckInvariants_bindRebuild(t *testing.T) {
+	for name, stabilize := range map[string]func(*Graph, context.Context) error{
+		"serial":       (*Graph).Stabilize,
+		"heightBlocks": (*Graph).ParallelStabilize,
+	} {
+		t.Run(name, func(t *testing.T) {
+			ctx := testContext()
+			var reports []*InvariantViolationsError
+			g := New(
+				OptGraphCheckInvariants(InvariantCheckBindRebuild),
+				OptGraphOnInvariantViolations(func(_ context.Context, report *InvariantViolationsError) {
+					reports = append(reports, report)
+				}),
+			)
+
+			v := Var(g, 1)
+			other := Map(g, v, func(x int) int { return x })
+			m := Map(g, v, func(x int) int { return x + 1 })
+			sel := Var(g, false)
+			b := Bind(g, sel, func(bs Scope, corrupt bool) Incr[int] {
+				if corrupt {
+					ExpertNode(m).AddParents(other)
+				}
+				return m
+			})
+			MustObserve(g, b)
+			MustObserve(g, other)
+			testutil.NoError(t, stabilize(g, ctx))
+			testutil.Equal(t, 0, len(reports))
+
+			sel.Set(true)
+			testutil.NoError(t, stabilize(g, ctx), "a handler takes the violations instead")
+			testutil.Equal(t, 2, len(reports), "one after the rebuild, one after the pass")
+			testutil.Equal(t, []Identifier{b.Node().ID()}, reports[0].Rebuilt)
+			testutil.Equal(t, 0, len(reports[1].Rebuilt))
+			for _, report := range reports {
+				testutil.Equal(t, true, slices.ContainsFunc(report.Violations, func(v InvariantViolation) bool {
+					return v.Invariant == InvariantEdgeSymmetry && v.Node == m.Node().ID()
+				}))
+			}
+		})
+	}
+}
+
+func Test_OptGraphCheckInvariants_stabilizationOnly(t *testing.T) {
+	ctx := testContext()
+	var reports []*InvariantViolationsError
+	g := New(
+		OptGraphCheckInvariants(InvariantCheckStabilization),
+		OptGraphOnInvariantViolations(func(_ context.Context, report *InvariantViolationsError) {
+			reports = append(reports, report)
+		}),
+	)
+	v := Var(g, 1)
+	other := Map(g, v, func(x int) int { return x })
+	m := Map(g, v, func(x int) int { return x + 1 })
+	b := Bind(g, v, func(bs Scope, x int) Incr[int] {
+		if x > 1 {
+			ExpertNode(m).AddParents(other)
+		}
+		return m
+	})
+	MustObserve(g, b)
+	MustObserve(g, other)
+	testutil.NoError(t, g.Stabilize(ctx))
+
+	v.Set(2)
+	testutil.NoError(t, g.Stabilize(ctx))
+	testutil.Equal(t, 1, len(reports), "rebuilds are not checked")
+	testutil.Equal(t, 0, len(reports[0].Rebuilt))
+}
+
+func Test_OptGraphCheckInvariants_none(t *testing.T) {
+	ctx := testContext()
+	g := New()
+	v := Var(g, 1)
+	other := Map(g, v, func(x int) int { return x })
+	m := Map(g, v, func(x int) int { return x + 1 })
+	MustObserve(g, m)
+	MustObserve(g, other)
+	testutil.NoError(t, g.Stabilize(ctx))
+
+	ExpertNode(m).AddParents(other)
+	testutil.NoError(t, g.Stabilize(ctx))
+	testutil.Error(t, ExpertGraph(g).CheckInvariants())
+}
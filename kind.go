@@ -14,10 +14,13 @@ const (
 	KindBind2              = "bind2"
 	KindBind3              = "bind3"
 	KindBind4              = "bind4"
+	KindBridge             = "bridge"
 	KindCutoff             = "cutoff"
 	KindCutoff2            = "cutoff2"
+	KindFixpoint           = "fixpoint"
 	KindFreeze             = "freeze"
 	KindFunc               = "func"
+	KindMapAsync           = "map_async"
 	KindMapIf              = "map_if"
 	KindMapN               = "map_n"
 	KindMap                = "map"
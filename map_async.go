@@ -0,0 +1,202 @@
+package incr
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// AsyncStatus is the state of an [Async] result.
+type AsyncStatus uint8
+
+const (
+	// AsyncPending means the work for the current input has not finished.
+	AsyncPending AsyncStatus = iota
+	// AsyncReady means the work finished and Value holds its result.
+	AsyncReady
+	// AsyncFailed means the work returned an error, held in Err.
+	AsyncFailed
+)
+
+func (s AsyncStatus) String() string {
+	switch s {
+	case AsyncPending:
+		return "pending"
+	case AsyncReady:
+		return "ready"
+	case AsyncFailed:
+		return "failed"
+	default:
+		return "unknown"
+	}
+}
+
+// Async is the value of a [MapAsync] node: where the work for its current input stands.
+//
+// Value is the result of the most recent work that succeeded, and is kept while newer
+// work is pending or after it fails, so a consumer can keep showing the last good value
+// and use Status to decide how to present it.
+type Async[B any] struct {
+	Status AsyncStatus
+	Value  B
+	Err    error
+}
+
+// MapAsync applies a function to an input off the stabilizing goroutine, and produces
+// the result in a later stabilization.
+//
+// A [MapContext] whose function waits on the network holds up the whole pass while it
+// waits, or under [Graph.ParallelStabilize] the whole height block. A MapAsync node
+// instead starts fn on a goroutine of its own when its input changes and immediately
+// becomes [AsyncPending], so the pass carries on. When fn returns, the node is marked
+// stale, and the next stabilization -- which the program has to run, as it would after
+// setting a var -- picks up the result as [AsyncReady] or [AsyncFailed] and passes it
+// downstream.
+//
+// Only the work for the latest input counts. If the input changes while fn is running,
+// its context is canceled and whatever it returns is discarded; the same happens if the
+// node stops being necessary. The context fn is given carries the values of the
+// stabilization context that started it, but not its cancellation, since the pass that
+// started it will have ended long before it finishes.
+func MapAsync[A, B any](scope Scope, input Incr[A], fn func(context.Context, A) (B, error)) Incr[Async[B]] {
+	m := &mapAsyncIncr[A, B]{
+		n:     scope.newNode(KindMapAsync),
+		input: input,
+		fn:    fn,
+	}
+	m.parents[0] = input
+	m.n.OnBecameUnnecessary(m.abandon)
+	return WithinScope(scope, m)
+}
+
+var (
+	_ Incr[Async[string]] = (*mapAsyncIncr[int, string])(nil)
+	_ IStabilize          = (*mapAsyncIncr[int, string])(nil)
+	_ IParents            = (*mapAsyncIncr[int, string])(nil)
+	_ fmt.Stringer        = (*mapAsyncIncr[int, string])(nil)
+)
+
+type mapAsyncIncr[A, B any] struct {
+	n     *Node
+	input Incr[A]
+	fn    func(context.Context, A) (B, error)
+	value Async[B]
+	// launched is whether work has been started for the input as of launchedAt, the
+	// input's change stamp; a later stamp means the input changed and the work is stale.
+	launched   bool
+	launchedAt uint64
+
+	// mu guards what the worker goroutine shares with the stabilizer: which work is
+	// current, how to cancel it, and its result once it lands.
+	mu         sync.Mutex
+	generation uint64
+	cancel     context.CancelFunc
+	result     *Async[B]
+	parents    [1]INode
+}
+
+func (m *mapAsyncIncr[A, B]) Parents() []INode { return m.parents[:] }
+
+func (m *mapAsyncIncr[A, B]) Node() *Node { return m.n }
+
+func (m *mapAsyncIncr[A, B]) Value() Async[B] { return m.value }
+
+// Stabilize runs both when the input changed, to start new work, and when a result
+// arrived, to publish it; the input's change stamp tells the two apart.
+func (m *mapAsyncIncr[A, B]) Stabilize(ctx context.Context) error {
+	if changedAt := m.input.Node().changedAt; !m.launched || changedAt > m.launchedAt {
+		m.launched, m.launchedAt = true, changedAt
+		m.launch(ctx, m.input.Value())
+		m.value = Async[B]{Status: AsyncPending, Value: m.value.Value}
+		return nil
+	}
+	m.mu.Lock()
+	result := m.result
+	m.result = nil
+	m.mu.Unlock()
+	switch {
+	case result == nil:
+	case result.Status == AsyncFailed:
+		m.value = Async[B]{Status: AsyncFailed, Value: m.value.Value, Err: result.Err}
+	default:
+		m.value = *result
+	}
+	return nil
+}
+
+func (m *mapAsyncIncr[A, B]) launch(ctx context.Context, input A) {
+	workCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	m.mu.Lock()
+	if m.cancel != nil {
+		m.cancel()
+	}
+	m.generation++
+	generation := m.generation
+	m.cancel = cancel
+	m.result = nil
+	m.mu.Unlock()
+
+	graph := GraphForNode(m)
+	go func() {
+		value, err := m.fn(workCtx, input)
+		m.mu.Lock()
+		if generation != m.generation {
+			// superseded while running; the context was canceled, and fn's answer to
+			// an input nobody wants any more is dropped
+			m.mu.Unlock()
+			return
+		}
+		result := Async[B]{Status: AsyncReady, Value: value}
+		if err != nil {
+			// the last good value is filled in when the result is published, since
+			// only the stabilizer may read the node's value
+			result = Async[B]{Status: AsyncFailed, Err: err}
+		}
+		m.result = &result
+		m.cancel = nil
+		m.mu.Unlock()
+		cancel()
+		graph.asyncArrived(m)
+	}()
+}
+
+// abandon cancels work in flight when the node stops being necessary; if it becomes
+// necessary again it starts afresh.
+func (m *mapAsyncIncr[A, B]) abandon() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.cancel != nil {
+		m.cancel()
+		m.cancel = nil
+	}
+	m.generation++
+	m.result = nil
+	m.launched = false
+}
+
+func (m *mapAsyncIncr[A, B]) String() string { return m.n.String() }
+
+// asyncArrived records that a node's work finished, for the next stabilization to pick
+// up. It is called from the goroutine that ran the work.
+func (graph *Graph) asyncArrived(n INode) {
+	graph.asyncMu.Lock()
+	graph.asyncArrivals = append(graph.asyncArrivals, n)
+	graph.hasAsyncArrivals.Store(true)
+	graph.asyncMu.Unlock()
+}
+
+// takeAsyncArrivals marks the nodes whose work finished stale, as a stabilization
+// starts. A node that stopped being necessary in the meantime is skipped; it abandoned
+// the work when it did.
+func (graph *Graph) takeAsyncArrivals() {
+	graph.asyncMu.Lock()
+	arrivals := graph.asyncArrivals
+	graph.asyncArrivals = nil
+	graph.hasAsyncArrivals.Store(false)
+	graph.asyncMu.Unlock()
+	for _, n := range arrivals {
+		if n.Node().isNecessary() {
+			graph.SetStale(n)
+		}
+	}
+}
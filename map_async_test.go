@@ -0,0 +1,139 @@
+package incr
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"testing/synctest"
+
+	"github.com/wcharczuk/go-incr/testutil"
+)
+
+// The tests run in a synctest bubble so that synctest.Wait can stand in for "the work
+// has finished": it returns once every goroutine in the bubble is blocked, which a
+// worker goroutine only is once it has landed its result or is waiting to be released.
+
+func Test_MapAsync(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		ctx := context.Background()
+		g := New()
+		v := Var(g, 2)
+		release := make(chan struct{})
+		var started int
+		async := MapAsync(g, v, func(_ context.Context, x int) (int, error) {
+			started++
+			<-release
+			return x * 10, nil
+		})
+		testutil.Equal(t, KindMapAsync, async.Node().Kind())
+		doubled := Map(g, async, func(a Async[int]) int { return a.Value * 2 })
+		o := MustObserve(g, async)
+		od := MustObserve(g, doubled)
+
+		testutil.NoError(t, g.Stabilize(ctx))
+		testutil.Equal(t, AsyncPending, o.Value().Status)
+		synctest.Wait()
+		testutil.Equal(t, 1, started)
+
+		// the pass does not wait for the work
+		release <- struct{}{}
+		synctest.Wait()
+		testutil.Equal(t, AsyncPending, o.Value().Status)
+		testutil.NoError(t, g.Stabilize(ctx))
+		testutil.Equal(t, Async[int]{Status: AsyncReady, Value: 20}, o.Value())
+		testutil.Equal(t, 40, od.Value())
+
+		// pending keeps the last good value
+		v.Set(3)
+		testutil.NoError(t, g.Stabilize(ctx))
+		testutil.Equal(t, Async[int]{Status: AsyncPending, Value: 20}, o.Value())
+		release <- struct{}{}
+		synctest.Wait()
+		testutil.NoError(t, g.Stabilize(ctx))
+		testutil.Equal(t, Async[int]{Status: AsyncReady, Value: 30}, o.Value())
+		testutil.Equal(t, 2, started)
+	})
+}
+
+func Test_MapAsync_supersedes(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		ctx := context.Background()
+		g := New()
+		v := Var(g, 1)
+		var canceled int
+		async := MapAsync(g, v, func(workCtx context.Context, x int) (int, error) {
+			if x == 1 {
+				<-workCtx.Done()
+				canceled++
+				return -1, workCtx.Err()
+			}
+			return x, nil
+		})
+		o := MustObserve(g, async)
+
+		testutil.NoError(t, g.Stabilize(ctx))
+		synctest.Wait()
+
+		// a new input cancels the work for the old one, whose result never lands
+		v.Set(2)
+		testutil.NoError(t, g.Stabilize(ctx))
+		synctest.Wait()
+		testutil.Equal(t, 1, canceled)
+		testutil.NoError(t, g.Stabilize(ctx))
+		testutil.Equal(t, Async[int]{Status: AsyncReady, Value: 2}, o.Value())
+	})
+}
+
+func Test_MapAsync_failed(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		ctx := context.Background()
+		g := New()
+		v := Var(g, 1)
+		failure := errors.New("unavailable")
+		async := MapAsync(g, v, func(_ context.Context, x int) (int, error) {
+			if x < 0 {
+				return 0, failure
+			}
+			return x, nil
+		})
+		o := MustObserve(g, async)
+
+		testutil.NoError(t, g.Stabilize(ctx))
+		synctest.Wait()
+		testutil.NoError(t, g.Stabilize(ctx))
+		testutil.Equal(t, 1, o.Value().Value)
+
+		// failure is a value rather than a pass error, and keeps the last good value
+		v.Set(-1)
+		testutil.NoError(t, g.Stabilize(ctx))
+		synctest.Wait()
+		testutil.NoError(t, g.Stabilize(ctx))
+		testutil.Equal(t, AsyncFailed, o.Value().Status)
+		testutil.Equal(t, 1, o.Value().Value)
+		testutil.Equal(t, true, errors.Is(o.Value().Err, failure))
+	})
+}
+
+func Test_MapAsync_unobserved(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		ctx := context.Background()
+		g := New()
+		v := Var(g, 1)
+		var canceled bool
+		async := MapAsync(g, v, func(workCtx context.Context, x int) (int, error) {
+			<-workCtx.Done()
+			canceled = true
+			return x, nil
+		})
+		o := MustObserve(g, async)
+		testutil.NoError(t, g.Stabilize(ctx))
+		synctest.Wait()
+
+		// a node nobody needs any more abandons its work
+		o.Unobserve(ctx)
+		synctest.Wait()
+		testutil.Equal(t, true, canceled)
+		testutil.NoError(t, g.Stabilize(ctx))
+		testutil.Equal(t, 0, g.recomputeHeap.len())
+	})
+}
@@ -3,6 +3,7 @@ package incr
 import (
 	"context"
 	"fmt"
+	"time"
 )
 
 // NewNode returns a new node.
@@ -174,10 +175,21 @@ type nodeExtra struct {
 	onUpdateHandlers  []func(context.Context)
 	onErrorHandlers   []func(context.Context, error)
 	onAbortedHandlers []func(context.Context, error)
+	// concurrencyGroup and cost are scheduling hints for the parallel path; see
+	// [Node.SetConcurrencyGroup] and [Node.SetCost].
+	concurrencyGroup string
+	cost             Cost
+	// timeout bounds the context the node stabilizes under; see [Node.SetTimeout].
+	timeout time.Duration
 	// the three lifecycle handler sets; see [Node.OnBecameNecessary].
 	onBecameNecessaryHandlers   []func()
 	onInvalidatedHandlers       []func()
 	onBecameUnnecessaryHandlers []func()
+	// onUnobservedHandlers are called as an observer is unobserved; see [Bridge].
+	onUnobservedHandlers []func()
+	// bridgeSource is what an observer bridged into other graphs forwards its updates
+	// through, a *bridgeSource of the observer's type; see [Bridge].
+	bridgeSource any
 }
 
 // extra returns the node's auxiliary fields, allocating them if this is the first
@@ -232,6 +244,22 @@ func (n *Node) becameUnnecessaryHandlers() []func() {
 	return n.ext.onBecameUnnecessaryHandlers
 }
 
+// onUnobserved registers a handler called when the node, an observer, is unobserved.
+func (n *Node) onUnobserved(fn func()) {
+	e := n.extra()
+	e.onUnobservedHandlers = append(e.onUnobservedHandlers, fn)
+}
+
+// takeUnobservedHandlers returns the unobserved handlers and forgets them, so that
+// each is called once however the observer is torn down.
+func (n *Node) takeUnobservedHandlers() (handlers []func()) {
+	if n.ext == nil {
+		return nil
+	}
+	handlers, n.ext.onUnobservedHandlers = n.ext.onUnobservedHandlers, nil
+	return
+}
+
 func (n *Node) nodeSentinels() []ISentinel {
 	if n.ext == nil {
 		return nil
@@ -355,6 +383,69 @@ func (n *Node) SetMetadata(md any) {
 	n.extra().metadata = md
 }
 
+// ConcurrencyGroup returns the name of the concurrency group the node is in, or an
+// empty string if it is in none.
+func (n *Node) ConcurrencyGroup() string {
+	if n.ext == nil {
+		return ""
+	}
+	return n.ext.concurrencyGroup
+}
+
+// SetConcurrencyGroup puts the node in a named concurrency group.
+//
+// [Graph.ParallelStabilize] runs at most as many nodes of a group at once as the limit
+// given for it with [OptGraphConcurrencyGroup], whatever the graph's parallelism, so
+// that nodes sharing a scarce resource -- a database's connection pool, a rate limited
+// API -- do not all hit it at once. Nodes of a group the graph has no limit for are
+// not held back. Serial stabilization runs one node at a time, and ignores groups.
+func (n *Node) SetConcurrencyGroup(name string) {
+	n.extra().concurrencyGroup = name
+}
+
+// Cost returns the node's cost hint.
+func (n *Node) Cost() Cost {
+	if n.ext == nil {
+		return CostDefault
+	}
+	return n.ext.cost
+}
+
+// SetCost sets the node's cost hint; see [Cost].
+func (n *Node) SetCost(cost Cost) {
+	n.extra().cost = cost
+}
+
+// Timeout returns the node's timeout, or zero if it has none.
+func (n *Node) Timeout() time.Duration {
+	if n.ext == nil {
+		return 0
+	}
+	return n.ext.timeout
+}
+
+// SetTimeout gives the node a timeout of its own.
+//
+// Each time the node is recomputed, its function is passed a context derived from the
+// stabilization context that is canceled once the timeout elapses. A call that hangs
+// then fails the one node, which is handled like any other failure, instead of holding
+// up the whole stabilization until the outer context gives out. The function has to
+// honor the context for this to work; the functions passed to [MapContext],
+// [BindContext], [Func] and the like all receive it. On a bind, the timeout applies to
+// the bind function. A timeout of zero, the default, leaves the node running under the
+// stabilization context unchanged.
+func (n *Node) SetTimeout(timeout time.Duration) {
+	n.extra().timeout = timeout
+}
+
+// withTimeout derives the context the node's own work runs under from ctx.
+func (n *Node) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if n.ext == nil || n.ext.timeout <= 0 {
+		return ctx, nil
+	}
+	return context.WithTimeout(ctx, n.ext.timeout)
+}
+
 // Kind returns the meta type of the node.
 func (n *Node) Kind() string {
 	return n.kind
@@ -501,6 +592,10 @@ func (n *Node) maybeInvalidate() {
 
 func (n *Node) maybeStabilize(ctx context.Context) (err error) {
 	if n.stabilizer != nil {
+		ctx, cancel := n.withTimeout(ctx)
+		if cancel != nil {
+			defer cancel()
+		}
 		if err = n.stabilizer.Stabilize(ctx); err != nil {
 			return
 		}
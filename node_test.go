@@ -2,9 +2,12 @@ package incr
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"strings"
 	"testing"
+	"testing/synctest"
+	"time"
 
 	"github.com/wcharczuk/go-incr/testutil"
 )
@@ -633,3 +636,128 @@ func Test_Node_shouldBeInvalidated_fallThrough(t *testing.T) {
 	}
 	testutil.Equal(t, false, n.shouldBeInvalidated())
 }
+
+func Test_Node_SetTimeout(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		ctx := context.Background()
+		g := New()
+
+		hang := true
+		v := Var(g, "hello")
+		m := MapContext(g, v, func(ctx context.Context, s string) (string, error) {
+			if hang {
+				<-ctx.Done()
+				return "", ctx.Err()
+			}
+			return s, nil
+		})
+		m.Node().SetTimeout(time.Second)
+		testutil.Equal(t, time.Second, m.Node().Timeout())
+
+		var handled error
+		m.Node().OnError(func(_ context.Context, err error) {
+			handled = err
+		})
+		o := MustObserve(g, m)
+
+		started := time.Now()
+		err := g.Stabilize(ctx)
+		testutil.Equal(t, true, errors.Is(err, context.DeadlineExceeded))
+		testutil.Equal(t, true, errors.Is(handled, context.DeadlineExceeded))
+		testutil.Equal(t, time.Second, time.Since(started))
+		testutil.Equal(t, true, g.recomputeHeap.has(m), "the node should be retried")
+
+		hang = false
+		err = g.Stabilize(ctx)
+		testutil.NoError(t, err)
+		testutil.Equal(t, "hello", o.Value())
+	})
+}
+
+func Test_Node_SetTimeout_unset(t *testing.T) {
+	ctx := testContext()
+	g := New()
+
+	var hasDeadline bool
+	m := Func(g, func(ctx context.Context) (string, error) {
+		_, hasDeadline = ctx.Deadline()
+		return "ok", nil
+	})
+	testutil.Equal(t, time.Duration(0), m.Node().Timeout())
+	o := MustObserve(g, m)
+
+	err := g.Stabilize(ctx)
+	testutil.NoError(t, err)
+	testutil.Equal(t, "ok", o.Value())
+	testutil.Equal(t, false, hasDeadline)
+}
+
+func Test_Node_SetTimeout_parallel(t *testing.T) {
+	for name, scheduler := range map[string]ParallelScheduler{
+		"heightBlocks": ParallelSchedulerHeightBlocks,
+		"dataflow":     ParallelSchedulerDataflow,
+	} {
+		t.Run(name, func(t *testing.T) {
+			synctest.Test(t, func(t *testing.T) {
+				ctx := context.Background()
+				g := New(
+					OptGraphParallelism(4),
+					OptGraphParallelScheduler(scheduler),
+				)
+
+				v := Var(g, 1)
+				hung := MapContext(g, v, func(ctx context.Context, x int) (int, error) {
+					<-ctx.Done()
+					return 0, ctx.Err()
+				})
+				hung.Node().SetTimeout(time.Second)
+				fine := Map(g, v, func(x int) int { return x + 1 })
+				_ = MustObserve(g, hung)
+				of := MustObserve(g, fine)
+
+				// the outer deadline is far off; the pass ends when the node times out
+				ctx, cancel := context.WithTimeout(ctx, time.Hour)
+				defer cancel()
+				started := time.Now()
+				err := g.ParallelStabilize(ctx)
+				testutil.Equal(t, true, errors.Is(err, context.DeadlineExceeded))
+				testutil.Equal(t, time.Second, time.Since(started))
+				testutil.Equal(t, 2, of.Value())
+				testutil.Equal(t, true, g.recomputeHeap.has(hung))
+			})
+		})
+	}
+}
+
+func Test_Node_SetTimeout_bind(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		ctx := context.Background()
+		g := New()
+
+		hang := true
+		v := Var(g, "a")
+		b := BindContext(g, v, func(ctx context.Context, bs Scope, s string) (Incr[string], error) {
+			if hang {
+				<-ctx.Done()
+				return nil, ctx.Err()
+			}
+			return Return(bs, s), nil
+		})
+		b.Node().SetTimeout(time.Second)
+
+		var handled error
+		b.Node().OnError(func(_ context.Context, err error) {
+			handled = err
+		})
+		o := MustObserve(g, b)
+
+		err := g.Stabilize(ctx)
+		testutil.Equal(t, true, errors.Is(err, context.DeadlineExceeded))
+		testutil.Equal(t, true, errors.Is(handled, context.DeadlineExceeded))
+
+		hang = false
+		err = g.Stabilize(ctx)
+		testutil.NoError(t, err)
+		testutil.Equal(t, "a", o.Value())
+	})
+}
@@ -63,6 +63,7 @@ type IObserver interface {
 
 var (
 	_ ObserveIncr[any] = (*observeIncr[any])(nil)
+	_ viewObserver     = (*observeIncr[any])(nil)
 	_ fmt.Stringer     = (*observeIncr[any])(nil)
 )
 
@@ -91,6 +92,13 @@ func (o *observeIncr[A]) Value() (output A) {
 	return o.observed.Value()
 }
 
+func (o *observeIncr[A]) viewValue() (any, uint64) {
+	if o.observed == nil {
+		return nil, 0
+	}
+	return o.observed.Value(), o.observed.Node().changedAt
+}
+
 func (o *observeIncr[A]) String() string {
 	if label := o.n.Label(); label != "" {
 		return fmt.Sprintf("%s[%s]:%s", o.n.kind, o.n.id.Short(), label)
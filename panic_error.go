@@ -26,6 +26,10 @@ type PanicError struct {
 	// Node describes the node whose computation panicked, or is empty when the panic did
 	// not come from a node.
 	Node string
+	// NodeID is the identifier of the node whose computation panicked, or zero when the
+	// panic did not come from a node. A panic from a node is returned wrapped in a
+	// [*NodeError] like any other failure, which has the rest of what is known about it.
+	NodeID Identifier
 }
 
 func (pe *PanicError) Error() string {
@@ -51,6 +55,7 @@ func newPanicError(n INode, recovered any) *PanicError {
 	}
 	if n != nil {
 		pe.Node = n.Node().String()
+		pe.NodeID = n.Node().id
 	}
 	return pe
 }
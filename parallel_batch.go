@@ -5,28 +5,96 @@ import (
 	"sync"
 )
 
+// Cost is a hint for how expensive a node is to recompute, set with [Node.SetCost].
+type Cost uint8
+
+const (
+	// CostDefault is the cost of a node without a hint. [Graph.ParallelStabilize] hands
+	// it to a goroutine of its own.
+	CostDefault Cost = iota
+	// CostCheap marks a node as cheaper to recompute than to hand to another goroutine,
+	// as a plain [Map] usually is. [Graph.ParallelStabilize] recomputes it on the
+	// goroutine coordinating the pass, alongside the nodes it handed out. A node in a
+	// concurrency group is never run this way, since waiting for room in the group would
+	// hold up the coordinator.
+	CostCheap
+)
+
+// batchPolicy tells parallelBatch how to run an item: inline on the calling goroutine,
+// or on a goroutine of its own, in which case group, if not nil, is a semaphore the item
+// holds while it runs.
+type batchPolicy[A any] func(A) (inline bool, group chan struct{})
+
 // parallelBatch is an iterator processor that runs in parallel, calling a given delegate for each iterator item seen.
-func parallelBatch[A any](ctx context.Context, fn func(context.Context, A) error, iter func() (A, bool), parallelism int) (err error) {
+//
+// At most parallelism items run on goroutines at once, each in one of as many worker
+// slots, numbered from one; fn is told the slot it runs in, or zero for an item run
+// inline. If policy is not nil it is consulted for each item. Items run inline are held
+// until everything else has been handed out, so that they overlap with the work of the
+// others rather than delay its start.
+func parallelBatch[A any](ctx context.Context, fn func(context.Context, int, A) error, iter func() (A, bool), parallelism int, policy batchPolicy[A]) (err error) {
 	var errOnce sync.Once
-	sem := make(chan A, parallelism)
+	parallelism = max(parallelism, 1)
+	workers := make(chan int, parallelism)
+	for worker := 1; worker <= parallelism; worker++ {
+		workers <- worker
+	}
 	wg := new(sync.WaitGroup)
 
-	process := func() {
+	process := func(worker int, w A, group chan struct{}) {
 		defer wg.Done()
-		workErr := fn(ctx, <-sem)
+		defer func() { workers <- worker }()
+		if group != nil {
+			group <- struct{}{}
+			defer func() { <-group }()
+		}
+		workErr := fn(ctx, worker, w)
 		if workErr != nil {
 			errOnce.Do(func() {
 				err = workErr
 			})
 		}
 	}
+	var inline []A
 	w, ok := iter()
 	for ok {
-		sem <- w
-		wg.Add(1)
-		go process()
+		var runInline bool
+		var group chan struct{}
+		if policy != nil {
+			runInline, group = policy(w)
+		}
+		if runInline {
+			inline = append(inline, w)
+		} else {
+			worker := <-workers
+			wg.Add(1)
+			go process(worker, w, group)
+		}
 		w, ok = iter()
 	}
+	for _, w := range inline {
+		if workErr := fn(ctx, 0, w); workErr != nil {
+			errOnce.Do(func() {
+				err = workErr
+			})
+		}
+	}
 	wg.Wait()
 	return
 }
+
+// parallelPolicy is the batchPolicy for recomputing nodes, from the hints set on them.
+func (graph *Graph) parallelPolicy(n INode) (inline bool, group chan struct{}) {
+	ext := n.Node().ext
+	if ext == nil {
+		return
+	}
+	if ext.concurrencyGroup != "" {
+		group = graph.concurrencyGroups[ext.concurrencyGroup]
+		if group != nil {
+			return
+		}
+	}
+	inline = ext.cost == CostCheap
+	return
+}
@@ -7,6 +7,7 @@ import (
 	"sync"
 	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/wcharczuk/go-incr/testutil"
 )
@@ -36,12 +37,12 @@ func Test_parallelBatch(t *testing.T) {
 
 	seen := make(map[string]struct{})
 	var seenMu sync.Mutex
-	err := parallelBatch(testContext(), func(_ context.Context, v string) error {
+	err := parallelBatch(testContext(), func(_ context.Context, _ int, v string) error {
 		seenMu.Lock()
 		seen[v] = struct{}{}
 		seenMu.Unlock()
 		return nil
-	}, workIter.Next, runtime.NumCPU())
+	}, workIter.Next, runtime.NumCPU(), nil)
 	testutil.NoError(t, err)
 	testutil.Equal(t, len(work), len(seen))
 
@@ -60,13 +61,51 @@ func Test_parallelBatch_error(t *testing.T) {
 	workIter := &arrayIter[string]{values: work}
 
 	var processed uint32
-	err := parallelBatch(testContext(), func(_ context.Context, v string) error {
+	err := parallelBatch(testContext(), func(_ context.Context, _ int, v string) error {
 		atomic.AddUint32(&processed, 1)
 		if v == "work-2" {
 			return fmt.Errorf("this is only a test")
 		}
 		return nil
-	}, workIter.Next, runtime.NumCPU())
+	}, workIter.Next, runtime.NumCPU(), nil)
 	testutil.Error(t, err)
 	testutil.Equal(t, len(work), processed, fmt.Sprintf("work=%d processed=%d", len(work), processed))
 }
+
+func Test_parallelBatch_policy(t *testing.T) {
+	var work []int
+	for x := range 16 {
+		work = append(work, x)
+	}
+	workIter := &arrayIter[int]{values: work}
+
+	// odd items share a group of two, even items run inline
+	group := make(chan struct{}, 2)
+	policy := func(v int) (inline bool, g chan struct{}) {
+		if v%2 == 0 {
+			return true, nil
+		}
+		return false, group
+	}
+
+	var running, maxRunning, processed int32
+	err := parallelBatch(testContext(), func(_ context.Context, _ int, v int) error {
+		atomic.AddInt32(&processed, 1)
+		if v%2 == 0 {
+			return nil
+		}
+		now := atomic.AddInt32(&running, 1)
+		for {
+			seen := atomic.LoadInt32(&maxRunning)
+			if now <= seen || atomic.CompareAndSwapInt32(&maxRunning, seen, now) {
+				break
+			}
+		}
+		time.Sleep(time.Millisecond)
+		atomic.AddInt32(&running, -1)
+		return nil
+	}, workIter.Next, 16, policy)
+	testutil.NoError(t, err)
+	testutil.Equal(t, 16, processed)
+	testutil.Equal(t, true, maxRunning <= 2, fmt.Sprintf("max running=%d", maxRunning))
+}
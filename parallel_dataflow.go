@@ -0,0 +1,335 @@
+package incr
+
+import (
+	"context"
+)
+
+// changeScope is implemented by bind scopes. A node created in a bind's scope must not
+// be recomputed before the bind's left-hand side change node, which may be about to
+// replace it; the height ordering guarantees that by placing every scope node above the
+// change node, and the dataflow scheduler has to do it with an extra edge.
+type changeScope interface {
+	scopeChange() INode
+}
+
+// parallelStabilizeDataflow is the [ParallelSchedulerDataflow] pass.
+//
+// The pass runs in epochs. An epoch takes everything in the recompute heap, and counts
+// down through its descendants, recomputing each as its parents resolve. A bind whose
+// left-hand side changed builds its new right-hand side on a worker like any other
+// node, but is not resolved: linking the right-hand side in rewrites the graph the
+// other workers are reading, so it waits for the end of the epoch, and whatever was
+// waiting on the bind goes back to the heap for the next one.
+func (graph *Graph) parallelStabilizeDataflow(ctx context.Context) (err error) {
+	if graph.recomputeHeap.len() == 0 {
+		return
+	}
+	var always []INode
+	for graph.recomputeHeap.len() > 0 {
+		if err = graph.dataflowEpoch(ctx, &always); err != nil {
+			break
+		}
+	}
+	if err != nil {
+		if graph.clearRecomputeHeapOnError {
+			aborted := graph.recomputeHeap.clear()
+			for _, node := range aborted {
+				for _, ah := range node.Node().abortedHandlers() {
+					ah(ctx, err)
+				}
+			}
+		}
+	}
+	if len(always) > 0 {
+		graph.recomputeHeap.mu.Lock()
+		for _, n := range always {
+			if n.Node().heightInRecomputeHeap == HeightUnset {
+				graph.recomputeHeap.addNodeUnsafe(n)
+			}
+		}
+		graph.recomputeHeap.mu.Unlock()
+	}
+	return
+}
+
+// dataflowNode is a node's place in an epoch.
+type dataflowNode struct {
+	n INode
+	// pending is the number of parents, and enclosing bind change nodes, in the epoch
+	// that have yet to resolve.
+	pending int
+	// dependents are the nodes in the epoch waiting on this one, by index, once per
+	// edge so that counting down mirrors counting up.
+	dependents []int
+	// queued is whether the node came out of the recompute heap, which means it is
+	// recomputed whatever its parents do.
+	queued bool
+	// parentChanged is whether a parent recomputed and changed during the epoch.
+	parentChanged bool
+	// built is whether the node is a bind change node that built its right-hand side,
+	// and is waiting for the end of the epoch to link it.
+	built    bool
+	resolved bool
+}
+
+// dataflowResult is what a worker reports back for a node it recomputed.
+type dataflowResult struct {
+	index   int
+	changed bool
+	err     error
+}
+
+// dataflowEpoch runs one epoch.
+//
+// All the bookkeeping is done on the calling goroutine, between handing nodes to
+// workers and collecting them back, so that only the recomputation itself runs
+// concurrently. A node is only decided on once every parent it waits on has resolved,
+// which means nothing it reads to make the decision is being written by a worker.
+func (graph *Graph) dataflowEpoch(ctx context.Context, always *[]INode) (err error) {
+	nodes, index := graph.dataflowRegion()
+
+	ready := make([]int, 0, len(nodes))
+	for i := range nodes {
+		if nodes[i].pending == 0 {
+			ready = append(ready, i)
+		}
+	}
+
+	parallelism := max(graph.parallelism, 1)
+	work := make(chan int)
+	results := make(chan dataflowResult, parallelism)
+	defer close(work)
+	for worker := range parallelism {
+		go func() {
+			for i := range work {
+				changed, err := graph.dataflowRecompute(ctx, nodes[i].n, worker+1)
+				results <- dataflowResult{index: i, changed: changed, err: err}
+			}
+		}()
+	}
+
+	done := ctx.Done()
+	stopped := false
+	var inFlight int
+	// runnable are the ready nodes that need recomputing, waiting on a worker, or on
+	// room in their concurrency group
+	var runnable []int
+	var groupsInFlight map[chan struct{}]int
+
+	finish := func(r dataflowResult) {
+		if r.err != nil {
+			// the node put itself back in the heap; the rest of the epoch is abandoned
+			// once the workers already running are done
+			if err == nil {
+				err = r.err
+			}
+			stopped = true
+			return
+		}
+		n := nodes[r.index].n
+		if _, ok := n.(rightHandSideBuilder); ok && r.changed {
+			nodes[r.index].built = true
+			return
+		}
+		if n.Node().always {
+			*always = append(*always, n)
+		}
+		if r.changed {
+			for _, c := range n.Node().children {
+				cn := c.Node()
+				if cn.childChangedNotifier != nil {
+					cn.childChangedNotifier.ChildChanged(n)
+				}
+				if ci, ok := index[cn]; ok {
+					nodes[ci].parentChanged = true
+				}
+			}
+		}
+		graph.dataflowResolve(nodes, r.index, &ready)
+	}
+
+	for {
+		// decide on everything ready before handing anything out; nodes that do not need
+		// recomputing resolve here, as do cheap nodes, and either may make more ready
+		for !stopped && len(ready) > 0 {
+			i := ready[len(ready)-1]
+			ready = ready[:len(ready)-1]
+			dn := &nodes[i]
+			if !dn.queued && !(dn.parentChanged && shouldRecomputeChild(dn.n.Node(), graph.stabilizationNum)) {
+				graph.dataflowResolve(nodes, i, &ready)
+				continue
+			}
+			if inline, _ := graph.parallelPolicy(dn.n); inline {
+				if err = contextCanceled(ctx, done); err != nil {
+					stopped = true
+					break
+				}
+				changed, recomputeErr := graph.dataflowRecompute(ctx, dn.n, traceEventCoordinator)
+				finish(dataflowResult{index: i, changed: changed, err: recomputeErr})
+				continue
+			}
+			runnable = append(runnable, i)
+		}
+		if !stopped && len(runnable) > 0 {
+			if err = contextCanceled(ctx, done); err != nil {
+				stopped = true
+			}
+		}
+
+		// the most recently readied node whose group has room goes next
+		var send chan int
+		next := -1
+		var nextGroup chan struct{}
+		for k := len(runnable) - 1; !stopped && k >= 0; k-- {
+			_, group := graph.parallelPolicy(nodes[runnable[k]].n)
+			if group == nil || groupsInFlight[group] < cap(group) {
+				send, next, nextGroup = work, k, group
+				break
+			}
+		}
+		if send == nil && inFlight == 0 {
+			break
+		}
+		var nextIndex int
+		if next >= 0 {
+			nextIndex = runnable[next]
+		}
+		select {
+		case send <- nextIndex:
+			runnable = append(runnable[:next], runnable[next+1:]...)
+			inFlight++
+			if nextGroup != nil {
+				if groupsInFlight == nil {
+					groupsInFlight = make(map[chan struct{}]int)
+				}
+				groupsInFlight[nextGroup]++
+			}
+		case r := <-results:
+			inFlight--
+			if _, group := graph.parallelPolicy(nodes[r.index].n); group != nil {
+				groupsInFlight[group]--
+			}
+			finish(r)
+		}
+	}
+
+	// whatever the epoch did not get to goes back to the heap, as do the children of
+	// the binds that rebuilt, as they would have under height blocks; the workers are
+	// idle, so staleness can be read without racing them
+	graph.recomputeHeap.mu.Lock()
+	for i := range nodes {
+		dn := &nodes[i]
+		if dn.resolved {
+			continue
+		}
+		if dn.built {
+			for _, c := range dn.n.Node().children {
+				cn := c.Node()
+				if cn.childChangedNotifier != nil {
+					cn.childChangedNotifier.ChildChanged(dn.n)
+				}
+				if shouldRecomputeChild(cn, graph.stabilizationNum) {
+					graph.recomputeHeap.addNodeUnsafe(c)
+				}
+			}
+			continue
+		}
+		dnn := dn.n.Node()
+		if dnn.heightInRecomputeHeap != HeightUnset {
+			continue
+		}
+		if dn.queued || (dn.parentChanged && shouldRecomputeChild(dnn, graph.stabilizationNum)) {
+			graph.recomputeHeap.addNodeUnsafe(dn.n)
+		}
+	}
+	graph.recomputeHeap.mu.Unlock()
+
+	if linkErr := graph.linkRightHandSides(ctx); err == nil {
+		err = linkErr
+	}
+	return
+}
+
+// dataflowRecompute recomputes a node on a worker, with the worker's own panic guard.
+// Workers are numbered from one, and the coordinator, running cheap nodes itself, as
+// zero.
+func (graph *Graph) dataflowRecompute(ctx context.Context, n INode, worker int) (changed bool, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = graph.recomputePanicked(ctx, n, r)
+		}
+	}()
+	if graph.traceEvents != nil {
+		return graph.recomputeNodeParallelValueTraced(ctx, n, worker)
+	}
+	return graph.recomputeNodeParallelValue(ctx, n)
+}
+
+// dataflowResolve marks a node done, and counts down the nodes waiting on it, adding
+// those with nothing left to wait on to ready.
+func (graph *Graph) dataflowResolve(nodes []dataflowNode, i int, ready *[]int) {
+	nodes[i].resolved = true
+	for _, d := range nodes[i].dependents {
+		nodes[d].pending--
+		if nodes[d].pending == 0 {
+			*ready = append(*ready, d)
+		}
+	}
+}
+
+// dataflowRegion empties the recompute heap into an epoch: the nodes taken from it,
+// everything necessary downstream of them, and for each the count of parents within
+// the epoch it has to wait on.
+func (graph *Graph) dataflowRegion() (nodes []dataflowNode, index map[*Node]int) {
+	index = make(map[*Node]int)
+	graph.recomputeHeap.mu.Lock()
+	for {
+		n, ok := graph.recomputeHeap.removeMinUnsafe()
+		if !ok {
+			break
+		}
+		index[n.Node()] = len(nodes)
+		nodes = append(nodes, dataflowNode{n: n, queued: true})
+	}
+	graph.recomputeHeap.mu.Unlock()
+
+	for i := 0; i < len(nodes); i++ {
+		for _, c := range nodes[i].n.Node().children {
+			cn := c.Node()
+			if !cn.valid || !cn.isNecessary() {
+				continue
+			}
+			if _, ok := index[cn]; !ok {
+				index[cn] = len(nodes)
+				nodes = append(nodes, dataflowNode{n: c})
+			}
+		}
+	}
+
+	for i := range nodes {
+		nn := nodes[i].n.Node()
+		for _, p := range nn.parents {
+			if pi, ok := index[p.Node()]; ok {
+				nodes[i].pending++
+				nodes[pi].dependents = append(nodes[pi].dependents, i)
+			}
+		}
+		// wait on the nearest enclosing bind whose change node is in the epoch; that
+		// change node waits in turn on the next one out
+		scope := nn.createdIn
+		for scope != nil {
+			cs, ok := scope.(changeScope)
+			if !ok {
+				break
+			}
+			change := cs.scopeChange()
+			if ci, ok := index[change.Node()]; ok {
+				nodes[i].pending++
+				nodes[ci].dependents = append(nodes[ci].dependents, i)
+				break
+			}
+			scope = change.Node().createdIn
+		}
+	}
+	return
+}
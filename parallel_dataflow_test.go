@@ -0,0 +1,227 @@
+package incr
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/wcharczuk/go-incr/testutil"
+)
+
+func Test_ParallelStabilize_dataflow(t *testing.T) {
+	ctx := testContext()
+	g := New(OptGraphParallelScheduler(ParallelSchedulerDataflow))
+
+	v0 := Var(g, "foo")
+	v1 := Var(g, "bar")
+	m0 := Map2(g, v0, v1, func(a, b string) string {
+		return a + " " + b
+	})
+	m1 := Map(g, m0, func(a string) string {
+		return a + "!"
+	})
+	o := MustObserve(g, m1)
+
+	err := g.ParallelStabilize(ctx)
+	testutil.NoError(t, err)
+	testutil.Equal(t, "foo bar!", o.Value())
+	testutil.Equal(t, 1, m1.Node().changedAt)
+
+	v0.Set("not foo")
+	err = g.ParallelStabilize(ctx)
+	testutil.NoError(t, err)
+	testutil.Equal(t, "not foo bar!", o.Value())
+	testutil.Equal(t, 2, m1.Node().changedAt)
+	testutil.Equal(t, 0, g.recomputeHeap.len())
+}
+
+func Test_ParallelStabilize_dataflow_doesNotWaitOnHeight(t *testing.T) {
+	ctx := testContext()
+	g := New(
+		OptGraphParallelism(4),
+		OptGraphParallelScheduler(ParallelSchedulerDataflow),
+	)
+
+	// slow is at height 1 and will not finish until c, at height 3 and on a chain of its
+	// own, has run; with height blocks c would wait for slow, and slow would time out
+	released := make(chan struct{})
+	var release sync.Once
+
+	v := Var(g, 1)
+	slow := MapContext(g, v, func(_ context.Context, x int) (int, error) {
+		select {
+		case <-released:
+			return x, nil
+		case <-time.After(5 * time.Second):
+			return 0, fmt.Errorf("the independent chain never ran")
+		}
+	})
+	a := Map(g, v, func(x int) int { return x + 1 })
+	b := Map(g, a, func(x int) int { return x + 1 })
+	c := Map(g, b, func(x int) int {
+		release.Do(func() { close(released) })
+		return x + 1
+	})
+	o := MustObserve(g, Map2(g, slow, c, func(x, y int) int { return x + y }))
+
+	err := g.ParallelStabilize(ctx)
+	testutil.NoError(t, err)
+	testutil.Equal(t, 5, o.Value())
+}
+
+func Test_ParallelStabilize_dataflow_cutoff(t *testing.T) {
+	ctx := testContext()
+	g := New(OptGraphParallelScheduler(ParallelSchedulerDataflow))
+
+	v := Var(g, 1)
+	var recomputes int
+	// even values are cut off
+	co := Cutoff(g, v, func(_, nv int) bool { return nv%2 == 0 })
+	m := Map(g, co, func(x int) int {
+		recomputes++
+		return x * 10
+	})
+	o := MustObserve(g, m)
+
+	err := g.ParallelStabilize(ctx)
+	testutil.NoError(t, err)
+	testutil.Equal(t, 10, o.Value())
+	testutil.Equal(t, 1, recomputes)
+
+	v.Set(2)
+	err = g.ParallelStabilize(ctx)
+	testutil.NoError(t, err)
+	testutil.Equal(t, 10, o.Value(), "the cutoff should keep the change from propagating")
+	testutil.Equal(t, 1, recomputes)
+}
+
+func Test_ParallelStabilize_dataflow_bind(t *testing.T) {
+	ctx := testContext()
+	g := New(OptGraphParallelScheduler(ParallelSchedulerDataflow))
+
+	which := Var(g, "a")
+	av := Var(g, 1)
+	bv := Var(g, 100)
+	var scopeRecomputes int
+	b := Bind(g, which, func(bs Scope, w string) Incr[int] {
+		input := Incr[int](av)
+		if w == "b" {
+			input = bv
+		}
+		return Map(bs, input, func(x int) int {
+			scopeRecomputes++
+			return x + 1
+		})
+	})
+	o := MustObserve(g, b)
+
+	err := g.ParallelStabilize(ctx)
+	testutil.NoError(t, err)
+	testutil.Equal(t, 2, o.Value())
+	testutil.Equal(t, 1, scopeRecomputes)
+
+	// the old right-hand side is stale too, but must not be recomputed ahead of the bind
+	// that is about to throw it away
+	which.Set("b")
+	av.Set(2)
+	err = g.ParallelStabilize(ctx)
+	testutil.NoError(t, err)
+	testutil.Equal(t, 101, o.Value())
+	testutil.Equal(t, 2, scopeRecomputes)
+	testutil.Nil(t, ExpertGraph(g).CheckInvariants())
+}
+
+func Test_ParallelStabilize_dataflow_always(t *testing.T) {
+	ctx := testContext()
+	g := New(OptGraphParallelScheduler(ParallelSchedulerDataflow))
+
+	v := Var(g, "foo")
+	a := Always(g, Map(g, v, ident))
+	m := Map(g, a, ident)
+
+	var updates int
+	m.Node().OnUpdate(func(_ context.Context) {
+		updates++
+	})
+	o := MustObserve(g, m)
+
+	for range 2 {
+		err := g.ParallelStabilize(ctx)
+		testutil.NoError(t, err)
+	}
+	testutil.Equal(t, "foo", o.Value())
+	testutil.Equal(t, 2, updates)
+	testutil.Equal(t, true, g.recomputeHeap.has(a))
+
+	v.Set("bar")
+	err := g.ParallelStabilize(ctx)
+	testutil.NoError(t, err)
+	testutil.Equal(t, "bar", o.Value())
+	testutil.Equal(t, 3, updates)
+}
+
+func Test_ParallelStabilize_dataflow_error(t *testing.T) {
+	ctx := testContext()
+	g := New(OptGraphParallelScheduler(ParallelSchedulerDataflow))
+
+	fail := true
+	v := Var(g, "hello")
+	f := MapContext(g, v, func(_ context.Context, s string) (string, error) {
+		if fail {
+			return "", fmt.Errorf("this is only a test")
+		}
+		return s, nil
+	})
+	m := Map(g, f, func(s string) string { return s + "!" })
+	o := MustObserve(g, m)
+
+	err := g.ParallelStabilize(ctx)
+	testutil.Error(t, err)
+	testutil.Equal(t, true, g.recomputeHeap.has(f), "the failed node should be retried")
+	testutil.Equal(t, true, g.recomputeHeap.has(m), "the node waiting on it should be kept")
+	testutil.Equal(t, "", o.Value())
+
+	fail = false
+	err = g.ParallelStabilize(ctx)
+	testutil.NoError(t, err)
+	testutil.Equal(t, "hello!", o.Value())
+}
+
+func Test_ParallelStabilize_dataflow_error_shouldClear(t *testing.T) {
+	ctx := testContext()
+	g := New(
+		OptGraphClearRecomputeHeapOnError(true),
+		OptGraphParallelScheduler(ParallelSchedulerDataflow),
+	)
+
+	var didCallAbortedHandler bool
+	f := Func(g, func(ctx context.Context) (string, error) {
+		return "", fmt.Errorf("this is only a test")
+	})
+	m := Map(g, f, ident)
+	m.Node().OnAborted(func(_ context.Context, err error) {
+		didCallAbortedHandler = true
+	})
+	_ = MustObserve(g, m)
+
+	err := g.ParallelStabilize(ctx)
+	testutil.Error(t, err)
+	testutil.Equal(t, 0, g.recomputeHeap.len())
+	testutil.Equal(t, true, didCallAbortedHandler)
+}
+
+func Test_ParallelStabilize_dataflow_canceled(t *testing.T) {
+	g := New(OptGraphParallelScheduler(ParallelSchedulerDataflow))
+
+	v := Var(g, 1)
+	m := Map(g, v, func(x int) int { return x + 1 })
+	_ = MustObserve(g, m)
+
+	ctx, cancel := context.WithCancel(testContext())
+	cancel()
+	err := g.ParallelStabilize(ctx)
+	testutil.Equal(t, context.Canceled, err)
+	testutil.Equal(t, true, g.recomputeHeap.has(m), "nodes not reached stay queued")
+}
@@ -0,0 +1,101 @@
+package incr
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"slices"
+)
+
+// deferredNodeError is an error from a deterministic parallel batch, held until the
+// batch is done.
+type deferredNodeError struct {
+	n      INode
+	height int
+	err    *NodeError
+}
+
+// reportDeferredNodeErrors calls the error handlers for the errors recorded during a
+// batch, in canonical order, and returns the first of them; it returns err if there were
+// none. It is called with no workers running.
+func (graph *Graph) reportDeferredNodeErrors(ctx context.Context, err error) error {
+	deferred := graph.deferredNodeErrors
+	if len(deferred) == 0 {
+		return err
+	}
+	slices.SortFunc(deferred, func(a, b deferredNodeError) int {
+		if c := cmp.Compare(a.height, b.height); c != 0 {
+			return c
+		}
+		return compareNodeIdentifiers(a.n, b.n)
+	})
+	for _, d := range deferred {
+		for _, eh := range d.n.Node().errorHandlers() {
+			eh(ctx, d.err.Err)
+		}
+	}
+	err = deferred[0].err
+	clear(deferred)
+	graph.deferredNodeErrors = deferred[:0]
+	return err
+}
+
+// deterministicParallelPolicy is the batchPolicy for a deterministic graph, which runs
+// bind functions inline, in the order the block is handed out, so that identifiers are
+// issued to the nodes they create in that order.
+func (graph *Graph) deterministicParallelPolicy(n INode) (inline bool, group chan struct{}) {
+	if _, ok := n.(rightHandSideBuilder); ok {
+		return true, nil
+	}
+	return graph.parallelPolicy(n)
+}
+
+// canonicalBlockIter takes a height block off an iterator and returns an iterator over
+// it in identifier order.
+func canonicalBlockIter(next func() (INode, bool)) func() (INode, bool) {
+	var block []INode
+	for n, ok := next(); ok; n, ok = next() {
+		block = append(block, n)
+	}
+	slices.SortFunc(block, compareNodeIdentifiers)
+	var index int
+	return func() (n INode, ok bool) {
+		if index == len(block) {
+			return
+		}
+		n, ok = block[index], true
+		index++
+		return
+	}
+}
+
+func compareNodeIdentifiers(a, b INode) int {
+	aid, bid := a.Node().id, b.Node().id
+	return bytes.Compare(aid[:], bid[:])
+}
+
+// sortByIdentifier puts each height block of the heap in identifier order.
+func (rh *recomputeHeap) sortByIdentifier() {
+	rh.mu.Lock()
+	defer rh.mu.Unlock()
+	if rh.numItems == 0 {
+		return
+	}
+	var block []*Node
+	for height := rh.minHeight; height <= rh.maxHeight; height++ {
+		list := &rh.heights[height]
+		if list.count < 2 {
+			continue
+		}
+		block = block[:0]
+		for n := list.popNode(); n != nil; n = list.popNode() {
+			block = append(block, n)
+		}
+		slices.SortFunc(block, func(a, b *Node) int {
+			return bytes.Compare(a.id[:], b.id[:])
+		})
+		for _, n := range block {
+			list.pushNode(n)
+		}
+	}
+}
@@ -2,10 +2,38 @@ package incr
 
 import (
 	"context"
-	"fmt"
 	"sync"
 )
 
+// ParallelScheduler selects how [Graph.ParallelStabilize] decides when a node may be
+// recomputed.
+type ParallelScheduler uint8
+
+const (
+	// ParallelSchedulerHeightBlocks recomputes one height block at a time, all of its
+	// nodes concurrently, and starts the next block once the slowest node of the current
+	// one is done. It is the default.
+	ParallelSchedulerHeightBlocks ParallelScheduler = iota
+	// ParallelSchedulerDataflow starts a node as soon as the parents it waits on are done,
+	// whatever their heights; see [OptGraphParallelScheduler].
+	//
+	// Under height blocks, one slow node at height 3 holds up every node at height 4,
+	// including those that do not depend on it. The dataflow scheduler counts, for each
+	// node downstream of the recompute heap, how many of its parents are still to be
+	// resolved, and hands the node to a worker when that reaches zero, so a slow node
+	// only holds up its own descendants.
+	//
+	// Counting costs a walk over everything downstream of the heap before any node is
+	// recomputed, including nodes a cutoff will keep from recomputing at all, so on graphs
+	// of cheap nodes it is slower than height blocks; it pays off when node recomputation
+	// times are long and uneven. A bind whose left-hand side changed builds its new
+	// right-hand side alongside the other nodes, but what depends on it waits until no
+	// node is running, when the right-hand side is linked in and counting starts over
+	// from the recompute heap. Cancellation of ctx is checked between nodes rather than
+	// between blocks.
+	ParallelSchedulerDataflow
+)
+
 // ParallelStabilize stabilizes a graph in parallel.
 //
 // This is done similarly to [Graph.Stabilize], in that nodes are stabilized
@@ -22,18 +50,45 @@ import (
 //
 // Canceling ctx stops the pass at the next height block boundary and returns the
 // context's cause; nodes not yet recomputed stay in the recompute heap.
+//
+// The order described is that of the default [ParallelSchedulerHeightBlocks]; a graph
+// created with [OptGraphParallelScheduler] can use [ParallelSchedulerDataflow] instead.
+//
+// Binds are stabilized in parallel as well: the bind functions of binds whose inputs
+// changed run concurrently, each building its new right-hand side in its own scope, and
+// the right-hand sides are linked into the graph one at a time once the nodes running
+// alongside them are done, at the end of the height block. A bind function therefore
+// runs concurrently with other nodes' functions, other bind functions among them.
+//
+// Under either scheduler, nodes sharing a scarce resource can be limited as a group with
+// [Node.SetConcurrencyGroup], and nodes too cheap to be worth a goroutine can be run by
+// the coordinating goroutine with [Node.SetCost].
+//
+// A graph created with [OptGraphDeterministic] is stabilized in parallel too, always in
+// height blocks. Nodes compute concurrently, but what their recomputation does beyond
+// their own values is committed in a canonical order, by height and then identifier,
+// rather than in the order the workers finish: each block is handed out in identifier
+// order and its bind functions run one after another in that order, so the nodes they
+// create are numbered the same way every run; error handlers are called once the block
+// is done, in canonical order, and the error returned is the first in that order; and
+// the recompute heap is left in canonical order, which fixes the order requeued nodes
+// are visited in by the next pass. Update handlers and vars set during stabilization
+// are applied in identifier order, as they are when stabilizing serially.
 func (graph *Graph) ParallelStabilize(ctx context.Context) (err error) {
-	if graph.deterministic {
-		err = fmt.Errorf("incr; cannot parallel stabilize if graph is deterministic")
-		return
-	}
 	if err = graph.ensureNotStabilizing(ctx); err != nil {
 		return
 	}
 	ctx = graph.stabilizeStart(ctx)
 	defer func() {
+		if graph.invariantCheck != InvariantCheckNone {
+			err = graph.checkInvariantsAfterStabilization(ctx, err)
+		}
 		graph.stabilizeEnd(ctx, err)
 	}()
+	if graph.parallelScheduler == ParallelSchedulerDataflow && !graph.deterministic {
+		err = graph.parallelStabilizeDataflow(ctx)
+		return
+	}
 	err = graph.parallelStabilize(ctx)
 	return
 }
@@ -48,13 +103,13 @@ func (graph *Graph) parallelStabilize(ctx context.Context) (err error) {
 	// Each worker holds its own guard, which is the only place a panic in a worker
 	// goroutine can be caught: a recover in the caller never sees it, so without this a
 	// panicking node ends the process. The node is a local, so nothing is shared.
-	parallelRecomputeNode := func(ctx context.Context, n INode) (err error) {
+	parallelRecomputeNode := func(ctx context.Context, worker int, n INode) (err error) {
 		defer func() {
 			if r := recover(); r != nil {
 				err = graph.recomputePanicked(ctx, n, r)
 			}
 		}()
-		err = graph.recompute(ctx, n, true)
+		err = graph.recomputeNodeParallel(ctx, n, worker)
 		if n.Node().always {
 			immediateRecomputeMu.Lock()
 			immediateRecompute = append(immediateRecompute, n)
@@ -68,13 +123,33 @@ func (graph *Graph) parallelStabilize(ctx context.Context) (err error) {
 	// stabilization is for nodes expensive enough that block granularity is fine.
 	done := ctx.Done()
 
+	policy := graph.parallelPolicy
+	if graph.deterministic {
+		policy = graph.deterministicParallelPolicy
+		graph.deferNodeErrors = true
+		defer func() {
+			graph.deferNodeErrors = false
+			graph.recomputeHeap.sortByIdentifier()
+		}()
+	}
+
 	var iter recomputeHeapListIter
 	for graph.recomputeHeap.len() > 0 {
 		if err = contextCanceled(ctx, done); err != nil {
 			break
 		}
 		graph.recomputeHeap.setIterToMinHeight(&iter)
-		err = parallelBatch(ctx, parallelRecomputeNode, iter.Next, graph.parallelism)
+		next := iter.Next
+		if graph.deterministic {
+			next = canonicalBlockIter(next)
+		}
+		err = parallelBatch(ctx, parallelRecomputeNode, next, graph.parallelism, policy)
+		if linkErr := graph.linkRightHandSides(ctx); err == nil {
+			err = linkErr
+		}
+		if graph.deterministic {
+			err = graph.reportDeferredNodeErrors(ctx, err)
+		}
 		if err != nil {
 			break
 		}
@@ -3,8 +3,11 @@ package incr
 import (
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -67,9 +70,125 @@ func Test_ParallelStabilize_deterministic(t *testing.T) {
 		return a + " " + b
 	})
 
-	_ = MustObserve(g, m0)
+	o := MustObserve(g, m0)
 	err := g.ParallelStabilize(ctx)
-	testutil.Error(t, err)
+	testutil.NoError(t, err)
+	testutil.Equal(t, "foo bar", o.Value())
+}
+
+func Test_ParallelStabilize_deterministic_updateHandlerOrder(t *testing.T) {
+	ctx := testContext()
+
+	// the same graph stabilized serially and in parallel fires its update handlers in
+	// the same order
+	run := func(parallel bool) (order []string) {
+		g := New(
+			OptGraphDeterministic(true),
+			OptGraphParallelism(4),
+		)
+		v := Var(g, 1)
+		for i := range 16 {
+			m := Map(g, v, func(x int) int { return x + i })
+			label := fmt.Sprintf("m%d", i)
+			m.Node().OnUpdate(func(_ context.Context) {
+				order = append(order, label)
+			})
+			_ = MustObserve(g, m)
+		}
+		var err error
+		if parallel {
+			err = g.ParallelStabilize(ctx)
+		} else {
+			err = g.Stabilize(ctx)
+		}
+		testutil.NoError(t, err)
+		return
+	}
+
+	serial := run(false)
+	testutil.Equal(t, 16, len(serial))
+	for range 8 {
+		testutil.Equal(t, serial, run(true))
+	}
+}
+
+func Test_ParallelStabilize_deterministic_errors(t *testing.T) {
+	ctx := testContext()
+
+	run := func() (order []string, err error) {
+		g := New(
+			OptGraphDeterministic(true),
+			OptGraphParallelism(4),
+		)
+		v := Var(g, 1)
+		for i := range 8 {
+			f := MapContext(g, v, func(_ context.Context, x int) (int, error) {
+				// later nodes fail first, so the order of the handlers is not the order
+				// the failures happened in
+				time.Sleep(time.Duration(8-i) * time.Millisecond)
+				return 0, fmt.Errorf("failure %d", i)
+			})
+			f.Node().OnError(func(_ context.Context, err error) {
+				order = append(order, err.Error())
+			})
+			_ = MustObserve(g, f)
+		}
+		err = g.ParallelStabilize(ctx)
+		return
+	}
+
+	first, firstErr := run()
+	testutil.Error(t, firstErr)
+	testutil.Equal(t, 8, len(first))
+	testutil.Equal(t, "failure 0", first[0], "nodes are numbered in creation order")
+	testutil.Equal(t, first[0], firstErr.Error())
+	for range 4 {
+		order, err := run()
+		testutil.Equal(t, first, order)
+		testutil.Equal(t, firstErr.Error(), err.Error())
+	}
+}
+
+func Test_ParallelStabilize_deterministic_binds(t *testing.T) {
+	ctx := testContext()
+
+	// binds rebuilt in the same block create their nodes in the same order every run,
+	// so the identifiers the nodes get are the same every run
+	run := func() (ids []Identifier) {
+		g := New(
+			OptGraphDeterministic(true),
+			OptGraphParallelism(4),
+		)
+		ids = make([]Identifier, 8)
+		observed := make([]ObserveIncr[int], 0, 8)
+		switches := make([]VarIncr[int], 0, 8)
+		for i := range 8 {
+			sw := Var(g, i)
+			switches = append(switches, sw)
+			observed = append(observed, MustObserve(g, Bind(g, sw, func(bs Scope, which int) Incr[int] {
+				// later binds would create their nodes first if left to run concurrently
+				time.Sleep(time.Duration(8-i) * time.Millisecond)
+				m := Map(bs, Return(bs, which), func(x int) int { return x * 2 })
+				ids[i] = m.Node().ID()
+				return m
+			})))
+		}
+		for round := range 3 {
+			for i, sw := range switches {
+				sw.Set(round*10 + i)
+			}
+			testutil.NoError(t, g.ParallelStabilize(ctx))
+		}
+		for i, o := range observed {
+			testutil.Equal(t, (20+i)*2, o.Value())
+		}
+		return
+	}
+
+	first := run()
+	for range 4 {
+		testutil.Equal(t, first, run())
+	}
 }
 
 func Test_ParallelStabilize_alreadyStabilizing(t *testing.T) {
@@ -395,3 +514,195 @@ func Test_ParallelStabilize_alwaysInRecomputeHeapOnError(t *testing.T) {
 	testutil.Error(t, err)
 	testutil.Equal(t, "this is only a test", err.Error())
 }
+
+func Test_ParallelStabilize_concurrencyGroup(t *testing.T) {
+	schedulers := map[string]ParallelScheduler{
+		"heightBlocks": ParallelSchedulerHeightBlocks,
+		"dataflow":     ParallelSchedulerDataflow,
+	}
+	for name, scheduler := range schedulers {
+		t.Run(name, func(t *testing.T) {
+			ctx := testContext()
+			g := New(
+				OptGraphParallelism(8),
+				OptGraphParallelScheduler(scheduler),
+				OptGraphConcurrencyGroup("db", 2),
+			)
+
+			var running, maxRunning int32
+			query := func(_ context.Context, x int) (int, error) {
+				now := atomic.AddInt32(&running, 1)
+				for {
+					seen := atomic.LoadInt32(&maxRunning)
+					if now <= seen || atomic.CompareAndSwapInt32(&maxRunning, seen, now) {
+						break
+					}
+				}
+				time.Sleep(2 * time.Millisecond)
+				atomic.AddInt32(&running, -1)
+				return x * 10, nil
+			}
+
+			v := Var(g, 1)
+			inputs := make([]Incr[int], 0, 8)
+			for range 8 {
+				q := MapContext(g, v, query)
+				q.Node().SetConcurrencyGroup("db")
+				cheap := Map(g, q, func(x int) int { return x + 1 })
+				cheap.Node().SetCost(CostCheap)
+				inputs = append(inputs, cheap)
+			}
+			o := MustObserve(g, MapN(g, func(values ...int) (out int) {
+				for _, value := range values {
+					out += value
+				}
+				return
+			}, inputs...))
+
+			err := g.ParallelStabilize(ctx)
+			testutil.NoError(t, err)
+			testutil.Equal(t, 88, o.Value())
+			testutil.Equal(t, true, maxRunning <= 2, fmt.Sprintf("max running=%d", maxRunning))
+		})
+	}
+}
+
+func Test_ParallelStabilize_bindsRebuildConcurrently(t *testing.T) {
+	schedulers := map[string]ParallelScheduler{
+		"heightBlocks": ParallelSchedulerHeightBlocks,
+		"dataflow":     ParallelSchedulerDataflow,
+	}
+	for name, scheduler := range schedulers {
+		t.Run(name, func(t *testing.T) {
+			ctx := testContext()
+			const binds = 4
+			g := New(
+				OptGraphParallelism(binds),
+				OptGraphParallelScheduler(scheduler),
+			)
+
+			// each bind function waits for all of them to have started, which only
+			// happens if they are not run one at a time
+			var arrived sync.WaitGroup
+			arrived.Add(binds)
+			allArrived := make(chan struct{})
+			go func() {
+				arrived.Wait()
+				close(allArrived)
+			}()
+
+			switches := make([]VarIncr[int], 0, binds)
+			observed := make([]ObserveIncr[int], 0, binds)
+			for i := range binds {
+				sw := Var(g, i)
+				switches = append(switches, sw)
+				first := true
+				observed = append(observed, MustObserve(g, BindContext(g, sw, func(_ context.Context, bs Scope, which int) (Incr[int], error) {
+					if !first {
+						arrived.Done()
+						select {
+						case <-allArrived:
+						case <-time.After(5 * time.Second):
+							return nil, fmt.Errorf("the other bind functions never started")
+						}
+					}
+					first = false
+					return Map(bs, Return(bs, which), func(x int) int { return x * 10 }), nil
+				})))
+			}
+
+			err := g.ParallelStabilize(ctx)
+			testutil.NoError(t, err)
+			for i, sw := range switches {
+				sw.Set(i + 1)
+			}
+			err = g.ParallelStabilize(ctx)
+			testutil.NoError(t, err)
+			for i, o := range observed {
+				testutil.Equal(t, (i+1)*10, o.Value())
+			}
+			testutil.Nil(t, ExpertGraph(g).CheckInvariants())
+		})
+	}
+}
+
+func Test_ParallelStabilize_bindReturnsBind(t *testing.T) {
+	for name, scheduler := range map[string]ParallelScheduler{
+		"heightBlocks": ParallelSchedulerHeightBlocks,
+		"dataflow":     ParallelSchedulerDataflow,
+	} {
+		t.Run(name, func(t *testing.T) {
+			ctx := testContext()
+			g := New(OptGraphParallelism(4), OptGraphParallelScheduler(scheduler))
+
+			v := Var(g, 1)
+			double := func(x int) int { return x * 2 }
+			inner := Bind(g, v, func(bs Scope, x int) Incr[int] {
+				if x%2 == 0 {
+					return Map(bs, v, double)
+				}
+				return v
+			})
+			// the outer bind returns the inner one, which is not in its scope, so both
+			// rebuild in the same batch; switching away from the inner one leaves it
+			// unnecessary, which must not happen before it is linked
+			outer := Bind(g, v, func(bs Scope, x int) Incr[int] {
+				if x%2 == 0 {
+					return Map(bs, v, double)
+				}
+				return inner
+			})
+			o := MustObserve(g, outer)
+
+			testutil.NoError(t, g.Stabilize(ctx))
+			testutil.Equal(t, 1, o.Value())
+
+			v.Set(2)
+			testutil.NoError(t, g.ParallelStabilize(ctx))
+			testutil.Equal(t, 4, o.Value())
+			testutil.Nil(t, ExpertGraph(g).CheckInvariants())
+
+			v.Set(3)
+			testutil.NoError(t, g.ParallelStabilize(ctx))
+			testutil.Equal(t, 3, o.Value())
+			testutil.Nil(t, ExpertGraph(g).CheckInvariants())
+		})
+	}
+}
+
+func Test_ParallelStabilize_bindLinkFails(t *testing.T) {
+	for name, scheduler := range map[string]ParallelScheduler{
+		"heightBlocks": ParallelSchedulerHeightBlocks,
+		"dataflow":     ParallelSchedulerDataflow,
+	} {
+		t.Run(name, func(t *testing.T) {
+			ctx := testContext()
+			g := New(OptGraphParallelism(4), OptGraphParallelScheduler(scheduler))
+
+			var b1 BindIncr[string]
+			b0v := Var(g, "a")
+			b0 := Bind(g, b0v, func(bs Scope, which string) Incr[string] {
+				if which == "a" {
+					return Return(bs, "foo")
+				}
+				return b1
+			})
+			b1 = Bind(g, Var(g, "a"), func(bs Scope, _ string) Incr[string] {
+				return b0
+			})
+			_ = MustObserve(g, b1)
+			testutil.NoError(t, g.Stabilize(ctx))
+
+			// linking the new right-hand side fails with a cycle, which leaves the bind
+			// stamped as it was before the pass, like any other failed node
+			change := b0.(*bindMainIncr[string, string]).parentsArray[0]
+			previousRecomputedAt := change.Node().recomputedAt
+			testutil.NotEqual(t, uint64(0), previousRecomputedAt)
+
+			b0v.Set("b")
+			var cycleErr *CycleError
+			testutil.Equal(t, true, errors.As(g.ParallelStabilize(ctx), &cycleErr))
+			testutil.Equal(t, previousRecomputedAt, change.Node().recomputedAt)
+		})
+	}
+}
@@ -6,9 +6,9 @@ import (
 	"sync"
 )
 
-func newRecomputeHeap(maxHeight int) *recomputeHeap {
+func newRecomputeHeap(heights int) *recomputeHeap {
 	return &recomputeHeap{
-		heights: make([]recomputeHeapList, maxHeight),
+		heights: make([]recomputeHeapList, heights),
 	}
 }
 
@@ -0,0 +1,328 @@
+package incr
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// NewRecorder returns a recorder that writes a graph's inputs to a given writer, one
+// JSON object per line, for [Replay] to reproduce. Give it to a graph with
+// [OptGraphRecorder].
+//
+// The recording holds what happened to the graph from outside: each [VarIncr.Set],
+// each [Graph.SetStale], each advance of a [Clock] a node in the graph is registered
+// with, and each stabilization, along with every observer's value at the end of it.
+// Inputs changed while a stabilization or its update handlers are running are not
+// recorded; those are taken to come from the graph's own code, which does the same
+// again when replayed.
+//
+// Values are written with encoding/json, so recorded vars and observers need types that
+// round trip through it.
+func NewRecorder(w io.Writer) *Recorder {
+	return &Recorder{
+		enc: json.NewEncoder(w),
+	}
+}
+
+// Recorder writes a graph's inputs to a log; see [NewRecorder].
+type Recorder struct {
+	mu  sync.Mutex
+	enc *json.Encoder
+	err error
+}
+
+// Err returns the first error the recorder hit writing the log or encoding a value,
+// after which the log is incomplete. The graph carries on regardless.
+func (r *Recorder) Err() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.err
+}
+
+// recordedEvent is a line of a recording.
+type recordedEvent struct {
+	Kind string `json:"kind"`
+	// Stabilization is the graph's stabilization number when the event happened; for a
+	// stabilization, the number of the pass.
+	Stabilization uint64                     `json:"stabilization"`
+	Node          Identifier                 `json:"node,omitzero"`
+	Value         json.RawMessage            `json:"value,omitempty"`
+	Clock         int                        `json:"clock,omitempty"`
+	At            time.Time                  `json:"at,omitzero"`
+	Error         string                     `json:"error,omitempty"`
+	Observed      map[string]json.RawMessage `json:"observed,omitempty"`
+}
+
+const (
+	recordedSet       = "set"
+	recordedStale     = "stale"
+	recordedAdvance   = "advance"
+	recordedStabilize = "stabilize"
+)
+
+func (r *Recorder) write(ev recordedEvent) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.err != nil {
+		return
+	}
+	r.err = r.enc.Encode(ev)
+}
+
+func (r *Recorder) fail(err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.err == nil {
+		r.err = err
+	}
+}
+
+func (r *Recorder) recordSet(graph *Graph, id Identifier, value any) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		r.fail(fmt.Errorf("incr; recording var %v: %w", id, err))
+		return
+	}
+	r.write(recordedEvent{Kind: recordedSet, Stabilization: graph.stabilizationNum, Node: id, Value: data})
+}
+
+func (r *Recorder) recordStale(graph *Graph, id Identifier) {
+	r.write(recordedEvent{Kind: recordedStale, Stabilization: graph.stabilizationNum, Node: id})
+}
+
+func (r *Recorder) recordAdvance(graph *Graph, c *Clock, to time.Time) {
+	graph.clocksMu.Lock()
+	index := slices.Index(graph.clocks, c)
+	graph.clocksMu.Unlock()
+	r.write(recordedEvent{Kind: recordedAdvance, Stabilization: graph.stabilizationNum, Clock: index, At: to})
+}
+
+func (r *Recorder) recordStabilize(graph *Graph, err error) {
+	ev := recordedEvent{Kind: recordedStabilize, Stabilization: graph.stabilizationNum}
+	if err != nil {
+		ev.Error = err.Error()
+	}
+	observed, encodeErr := graph.encodeObserved()
+	if encodeErr != nil {
+		r.fail(encodeErr)
+		return
+	}
+	ev.Observed = observed
+	r.write(ev)
+}
+
+// encodeObserved encodes the value of every observer in the graph, by identifier.
+func (graph *Graph) encodeObserved() (map[string]json.RawMessage, error) {
+	graph.observersMu.Lock()
+	defer graph.observersMu.Unlock()
+	observed := make(map[string]json.RawMessage, len(graph.observers))
+	for id, o := range graph.observers {
+		vo, ok := o.(viewObserver)
+		if !ok {
+			continue
+		}
+		value, _ := vo.viewValue()
+		data, err := json.Marshal(value)
+		if err != nil {
+			return nil, fmt.Errorf("incr; recording observer %v: %w", id, err)
+		}
+		observed[id.String()] = data
+	}
+	return observed, nil
+}
+
+// recordedSetter is implemented by vars, to be set from a recording.
+type recordedSetter interface {
+	setRecorded([]byte) error
+}
+
+// Replay reproduces a recording made with [OptGraphRecorder].
+//
+// It calls build for a fresh graph, which has to construct the same graph as the one
+// recorded -- the same nodes, created in the same order from the same identifier
+// provider, with the same observers -- and then applies the recorded events to it one
+// by one, stabilizing where the recorded graph did. After each stabilization it checks
+// that the pass succeeded or failed as it did when recorded, and that every observer
+// holds the value it held then. Replay stabilizes with [Graph.Stabilize] whichever way
+// the recorded graph was stabilized, since parallel stabilization is bound to produce
+// the same values.
+//
+// It returns the rebuilt graph as replay left it, and a [*ReplayMismatchError] at the
+// first point the rebuilt graph does not behave as recorded. Anything else the graph was
+// given that is not recorded, such as a [MapAsync] result, will not be reproduced.
+func Replay(ctx context.Context, recording io.Reader, build func() (*Graph, error)) (*Graph, error) {
+	graph, err := buildForReplay(build)
+	if err != nil {
+		return nil, err
+	}
+	dec := json.NewDecoder(recording)
+	for line := 1; ; line++ {
+		var ev recordedEvent
+		if err = dec.Decode(&ev); err != nil {
+			if errors.Is(err, io.EOF) {
+				return graph, nil
+			}
+			return graph, fmt.Errorf("incr; replay; reading line %d: %w", line, err)
+		}
+		if err = graph.replayEvent(ctx, ev); err != nil {
+			var mismatch *ReplayMismatchError
+			if errors.As(err, &mismatch) {
+				mismatch.Line = line
+			}
+			return graph, err
+		}
+	}
+}
+
+// replayBuilding counts the calls to [Replay] building their graphs. A graph created
+// while it is above zero keeps its vars by identifier, which costs a lock and a map
+// entry per var, so that a recorded set of a var that is not yet observed can find it.
+// Graphs created on other goroutines at the same time keep theirs too, which is
+// harmless; every other graph does without.
+var replayBuilding atomic.Int32
+
+func buildForReplay(build func() (*Graph, error)) (*Graph, error) {
+	replayBuilding.Add(1)
+	defer replayBuilding.Add(-1)
+	return build()
+}
+
+// ReplayMismatchError is returned by [Replay] when the rebuilt graph does not behave as
+// the recorded one did.
+type ReplayMismatchError struct {
+	// Line is the line of the recording being replayed.
+	Line int
+	// Stabilization is the stabilization number the graph was at.
+	Stabilization uint64
+	// Node is the node the mismatch is about, if it is about one.
+	Node Identifier
+	// Reason describes the mismatch.
+	Reason string
+}
+
+// Error implements error.
+func (e *ReplayMismatchError) Error() string {
+	if e.Node.IsZero() {
+		return fmt.Sprintf("incr; replay; line %d, stabilization %d: %s", e.Line, e.Stabilization, e.Reason)
+	}
+	return fmt.Sprintf("incr; replay; line %d, stabilization %d, node %v: %s", e.Line, e.Stabilization, e.Node, e.Reason)
+}
+
+func (graph *Graph) replayEvent(ctx context.Context, ev recordedEvent) error {
+	mismatch := func(node Identifier, format string, args ...any) error {
+		return &ReplayMismatchError{
+			Stabilization: graph.stabilizationNum,
+			Node:          node,
+			Reason:        fmt.Sprintf(format, args...),
+		}
+	}
+	if ev.Stabilization != graph.stabilizationNum {
+		return mismatch(Identifier{}, "recorded at stabilization %d", ev.Stabilization)
+	}
+	switch ev.Kind {
+	case recordedSet:
+		setter, ok := graph.varByID(ev.Node)
+		if !ok {
+			if n, found := graph.nodeByID(ev.Node); found {
+				return mismatch(ev.Node, "recorded as a var, but is a %s", n.Node().Kind())
+			}
+			return mismatch(ev.Node, "var not found")
+		}
+		if err := setter.setRecorded(ev.Value); err != nil {
+			return mismatch(ev.Node, "cannot set recorded value: %v", err)
+		}
+	case recordedStale:
+		n, ok := graph.nodeByID(ev.Node)
+		if !ok {
+			return mismatch(ev.Node, "node not found")
+		}
+		graph.SetStale(n)
+	case recordedAdvance:
+		graph.clocksMu.Lock()
+		var c *Clock
+		if ev.Clock >= 0 && ev.Clock < len(graph.clocks) {
+			c = graph.clocks[ev.Clock]
+		}
+		graph.clocksMu.Unlock()
+		if c == nil {
+			return mismatch(Identifier{}, "clock %d not found", ev.Clock)
+		}
+		c.Advance(ev.At)
+	case recordedStabilize:
+		err := graph.Stabilize(ctx)
+		var errText string
+		if err != nil {
+			errText = err.Error()
+		}
+		if errText != ev.Error {
+			return &ReplayMismatchError{
+				Stabilization: graph.stabilizationNum - 1,
+				Reason:        fmt.Sprintf("stabilization error %q, recorded %q", errText, ev.Error),
+			}
+		}
+		observed, err := graph.encodeObserved()
+		if err != nil {
+			return err
+		}
+		for id, recorded := range ev.Observed {
+			value, ok := observed[id]
+			if !ok {
+				parsed, _ := ParseIdentifier(id)
+				return &ReplayMismatchError{Stabilization: graph.stabilizationNum - 1, Node: parsed, Reason: "observer not found"}
+			}
+			if !bytes.Equal(value, recorded) {
+				parsed, _ := ParseIdentifier(id)
+				return &ReplayMismatchError{
+					Stabilization: graph.stabilizationNum - 1,
+					Node:          parsed,
+					Reason:        fmt.Sprintf("observed %s, recorded %s", value, recorded),
+				}
+			}
+		}
+		if len(observed) != len(ev.Observed) {
+			return &ReplayMismatchError{
+				Stabilization: graph.stabilizationNum - 1,
+				Reason:        fmt.Sprintf("%d observers, recorded %d", len(observed), len(ev.Observed)),
+			}
+		}
+	default:
+		return fmt.Errorf("incr; replay; unknown event %q", ev.Kind)
+	}
+	return nil
+}
+
+// varByID finds a var created in the graph by its identifier, whether or not it is
+// necessary.
+func (graph *Graph) varByID(id Identifier) (recordedSetter, bool) {
+	graph.varsMu.Lock()
+	defer graph.varsMu.Unlock()
+	setter, ok := graph.vars[id]
+	return setter, ok
+}
+
+// unregisterVar forgets a var that has been invalidated, which will not be set again.
+func (graph *Graph) unregisterVar(id Identifier) {
+	graph.varsMu.Lock()
+	delete(graph.vars, id)
+	graph.varsMu.Unlock()
+}
+
+// nodeByID finds a node in the graph by its identifier.
+func (graph *Graph) nodeByID(id Identifier) (INode, bool) {
+	graph.nodesMu.Lock()
+	defer graph.nodesMu.Unlock()
+	for _, n := range graph.nodes {
+		if n.Node().id == id {
+			return n, true
+		}
+	}
+	return nil, false
+}
@@ -0,0 +1,240 @@
+package incr
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/wcharczuk/go-incr/testutil"
+)
+
+type recordTestGraph struct {
+	graph   *Graph
+	a, b    VarIncr[int]
+	clock   *Clock
+	sum     ObserveIncr[int]
+	elapsed ObserveIncr[bool]
+}
+
+func newRecordTestGraph(recorder *Recorder, offset int) *recordTestGraph {
+	opts := []GraphOption{OptGraphDeterministic(true)}
+	if recorder != nil {
+		opts = append(opts, OptGraphRecorder(recorder))
+	}
+	g := New(opts...)
+	rg := &recordTestGraph{graph: g}
+	rg.a = Var(g, 1)
+	rg.b = Var(g, 2)
+	rg.clock = NewClock(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	sum := Map2(g, rg.a, rg.b, func(a, b int) int { return a + b + offset })
+	rg.sum = MustObserve(g, sum)
+	rg.elapsed = MustObserve(g, At(g, rg.clock, time.Date(2024, 1, 1, 1, 0, 0, 0, time.UTC)))
+	// a var set by an update handler is the graph's own doing, and is not recorded
+	rg.sum.OnUpdate(func(_ context.Context, v int) {
+		if v == 10 {
+			rg.b.Set(100)
+		}
+	})
+	return rg
+}
+
+func Test_Replay(t *testing.T) {
+	ctx := testContext()
+	log := new(bytes.Buffer)
+	recorder := NewRecorder(log)
+	rg := newRecordTestGraph(recorder, 0)
+
+	testutil.NoError(t, rg.graph.Stabilize(ctx))
+	rg.a.Set(5)
+	rg.graph.SetStale(rg.b)
+	testutil.NoError(t, rg.graph.Stabilize(ctx))
+	rg.a.Set(8)
+	rg.clock.AdvanceBy(2 * time.Hour)
+	testutil.NoError(t, rg.graph.ParallelStabilize(ctx))
+	testutil.NoError(t, rg.graph.Stabilize(ctx))
+	testutil.NoError(t, recorder.Err())
+
+	testutil.Equal(t, 108, rg.sum.Value())
+	testutil.Equal(t, true, rg.elapsed.Value())
+
+	var kinds []string
+	for _, line := range strings.Split(strings.TrimSpace(log.String()), "\n") {
+		for _, kind := range []string{recordedSet, recordedStale, recordedAdvance, recordedStabilize} {
+			if strings.Contains(line, `"kind":"`+kind+`"`) {
+				kinds = append(kinds, kind)
+			}
+		}
+	}
+	testutil.Equal(t, []string{
+		recordedStabilize,
+		recordedSet, recordedStale, recordedStabilize,
+		recordedSet, recordedAdvance, recordedStabilize,
+		recordedStabilize,
+	}, kinds)
+
+	var replayed *recordTestGraph
+	g, err := Replay(ctx, bytes.NewReader(log.Bytes()), func() (*Graph, error) {
+		replayed = newRecordTestGraph(nil, 0)
+		return replayed.graph, nil
+	})
+	testutil.NoError(t, err)
+	testutil.Equal(t, replayed.graph, g)
+	testutil.Equal(t, 108, replayed.sum.Value())
+	testutil.Equal(t, true, replayed.elapsed.Value())
+	testutil.Equal(t, rg.graph.stabilizationNum, g.stabilizationNum)
+}
+
+func Test_Replay_mismatch(t *testing.T) {
+	ctx := testContext()
+	log := new(bytes.Buffer)
+	rg := newRecordTestGraph(NewRecorder(log), 0)
+
+	testutil.NoError(t, rg.graph.Stabilize(ctx))
+	rg.a.Set(5)
+	testutil.NoError(t, rg.graph.Stabilize(ctx))
+
+	// the rebuilt graph computes something different, starting from the first pass
+	var replayed *recordTestGraph
+	_, err := Replay(ctx, bytes.NewReader(log.Bytes()), func() (*Graph, error) {
+		replayed = newRecordTestGraph(nil, 1)
+		return replayed.graph, nil
+	})
+	var mismatch *ReplayMismatchError
+	testutil.Equal(t, true, errors.As(err, &mismatch))
+	testutil.Equal(t, 1, mismatch.Line)
+	testutil.Equal(t, uint64(1), mismatch.Stabilization)
+	testutil.Equal(t, replayed.sum.Node().ID(), mismatch.Node)
+	testutil.Equal(t, "observed 4, recorded 3", mismatch.Reason)
+}
+
+func Test_Replay_error(t *testing.T) {
+	ctx := testContext()
+	build := func(recorder *Recorder) (*Graph, VarIncr[string]) {
+		opts := []GraphOption{OptGraphDeterministic(true)}
+		if recorder != nil {
+			opts = append(opts, OptGraphRecorder(recorder))
+		}
+		g := New(opts...)
+		v := Var(g, "ok")
+		m := MapContext(g, v, func(_ context.Context, s string) (string, error) {
+			if s == "fail" {
+				return "", fmt.Errorf("this is only a test")
+			}
+			return s, nil
+		})
+		_ = MustObserve(g, m)
+		return g, v
+	}
+
+	log := new(bytes.Buffer)
+	g, v := build(NewRecorder(log))
+	testutil.NoError(t, g.Stabilize(ctx))
+	v.Set("fail")
+	testutil.Error(t, g.Stabilize(ctx))
+	v.Set("fine")
+	testutil.NoError(t, g.Stabilize(ctx))
+
+	_, err := Replay(ctx, bytes.NewReader(log.Bytes()), func() (*Graph, error) {
+		g, _ := build(nil)
+		return g, nil
+	})
+	testutil.NoError(t, err)
+}
+
+func Test_Replay_varNotFound(t *testing.T) {
+	ctx := testContext()
+	log := new(bytes.Buffer)
+	rg := newRecordTestGraph(NewRecorder(log), 0)
+	rg.a.Set(5)
+	testutil.NoError(t, rg.graph.Stabilize(ctx))
+
+	// built with identifiers the recording does not know
+	_, err := Replay(ctx, bytes.NewReader(log.Bytes()), func() (*Graph, error) {
+		return New(OptGraphIdentifierProvider(NewSequentialIdentifierProvider(1000))), nil
+	})
+	var mismatch *ReplayMismatchError
+	testutil.Equal(t, true, errors.As(err, &mismatch))
+	testutil.Equal(t, 1, mismatch.Line)
+	testutil.Equal(t, rg.a.Node().ID(), mismatch.Node)
+	testutil.Equal(t, "var not found", mismatch.Reason)
+}
+
+func Test_Replay_varSetBeforeObserved(t *testing.T) {
+	ctx := testContext()
+	build := func(recorder *Recorder) (*Graph, VarIncr[int], Incr[int]) {
+		opts := []GraphOption{OptGraphDeterministic(true)}
+		if recorder != nil {
+			opts = append(opts, OptGraphRecorder(recorder))
+		}
+		g := New(opts...)
+		v := Var(g, 1)
+		return g, v, Map(g, v, func(x int) int { return x * 10 })
+	}
+
+	log := new(bytes.Buffer)
+	g, v, m := build(NewRecorder(log))
+	// the var is not necessary yet, so it is not among the graph's nodes
+	v.Set(4)
+	o := MustObserve(g, m)
+	testutil.NoError(t, g.Stabilize(ctx))
+	testutil.Equal(t, 40, o.Value())
+
+	var replayed ObserveIncr[int]
+	_, err := Replay(ctx, bytes.NewReader(log.Bytes()), func() (*Graph, error) {
+		rg, _, rm := build(nil)
+		replayed = MustObserve(rg, rm)
+		return rg, nil
+	})
+	testutil.NoError(t, err)
+	testutil.Equal(t, 40, replayed.Value())
+}
+
+func Test_Replay_invalidatedVar(t *testing.T) {
+	g, err := buildForReplay(func() (*Graph, error) { return New(), nil })
+	testutil.NoError(t, err)
+	v := Var(g, 0)
+	var inner VarIncr[int]
+	b := Bind(g, v, func(bs Scope, x int) Incr[int] {
+		inner = Var(bs, x)
+		return inner
+	})
+	MustObserve(g, b)
+	testutil.NoError(t, g.Stabilize(testContext()))
+	first := inner.Node().ID()
+	_, ok := g.varByID(first)
+	testutil.Equal(t, true, ok)
+
+	// a var in a discarded right-hand side will not be set again, and is forgotten
+	v.Set(1)
+	testutil.NoError(t, g.Stabilize(testContext()))
+	_, ok = g.varByID(first)
+	testutil.Equal(t, false, ok)
+	_, ok = g.varByID(inner.Node().ID())
+	testutil.Equal(t, true, ok)
+}
+
+func Test_Replay_varsOnlyKeptForReplay(t *testing.T) {
+	// a graph that is not being replayed into does not keep its vars, recording or not
+	for _, g := range []*Graph{New(), New(OptGraphRecorder(NewRecorder(io.Discard)))} {
+		v := Var(g, 0)
+		_, ok := g.varByID(v.Node().ID())
+		testutil.Equal(t, false, ok)
+		testutil.Equal(t, 0, len(g.vars))
+	}
+}
+
+func Test_Recorder_unencodable(t *testing.T) {
+	log := new(bytes.Buffer)
+	recorder := NewRecorder(log)
+	g := New(OptGraphRecorder(recorder))
+	v := Var(g, func() {})
+	_ = MustObserve(g, v)
+
+	v.Set(func() {})
+	testutil.Error(t, recorder.Err())
+}
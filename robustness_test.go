@@ -271,8 +271,20 @@ func Test_Clock_concurrentReads(t *testing.T) {
 // changes underneath it, which is where the locking around structural work matters. Run
 // under -race this is the check that binds and parallel stabilization compose.
 func Test_ParallelStabilize_withBinds(t *testing.T) {
+	schedulers := map[string]ParallelScheduler{
+		"heightBlocks": ParallelSchedulerHeightBlocks,
+		"dataflow":     ParallelSchedulerDataflow,
+	}
+	for name, scheduler := range schedulers {
+		t.Run(name, func(t *testing.T) {
+			testParallelStabilizeWithBinds(t, scheduler)
+		})
+	}
+}
+
+func testParallelStabilizeWithBinds(t *testing.T, scheduler ParallelScheduler) {
 	ctx := context.Background()
-	g := New(OptGraphMaxHeight(256))
+	g := New(OptGraphMaxHeight(256), OptGraphParallelScheduler(scheduler))
 
 	switches := make([]VarIncr[int], 0, 16)
 	for i := range 16 {
@@ -547,30 +559,42 @@ func Test_serialAndParallelAgree(t *testing.T) {
 
 	for name, build := range shapes {
 		t.Run(name, func(t *testing.T) {
-			// the same shape twice: one graph stabilized serially, one in parallel
+			// the same shape three times: one graph stabilized serially, one in parallel
+			// by height blocks, and one in parallel by dataflow
 			serialGraph := New(OptGraphMaxHeight(256))
 			serialObserved := build(serialGraph)
 			parallelGraph := New(OptGraphMaxHeight(256))
 			parallelObserved := build(parallelGraph)
+			dataflowGraph := New(OptGraphMaxHeight(256), OptGraphParallelScheduler(ParallelSchedulerDataflow))
+			dataflowObserved := build(dataflowGraph)
 
 			serialVars := varsOf(serialGraph)
 			parallelVars := varsOf(parallelGraph)
+			dataflowVars := varsOf(dataflowGraph)
 			testutil.Equal(t, len(serialVars), len(parallelVars), "the two graphs should be the same shape")
+			testutil.Equal(t, len(serialVars), len(dataflowVars), "the two graphs should be the same shape")
 
 			for round := range 6 {
 				for i := range serialVars {
 					serialVars[i].Set(round + i)
 					parallelVars[i].Set(round + i)
+					dataflowVars[i].Set(round + i)
 				}
 				testutil.Nil(t, serialGraph.Stabilize(ctx))
 				testutil.Nil(t, parallelGraph.ParallelStabilize(ctx))
+				testutil.Nil(t, dataflowGraph.ParallelStabilize(ctx))
 
 				testutil.Equal(t, serialObserved.Value(), parallelObserved.Value(),
 					"the two paths disagree on the value after round %d", round)
+				testutil.Equal(t, serialObserved.Value(), dataflowObserved.Value(),
+					"the serial and dataflow paths disagree on the value after round %d", round)
 				testutil.Nil(t, ExpertGraph(serialGraph).CheckInvariants())
 				testutil.Nil(t, ExpertGraph(parallelGraph).CheckInvariants())
+				testutil.Nil(t, ExpertGraph(dataflowGraph).CheckInvariants())
 				testutil.Equal(t, serialGraph.numNodes, parallelGraph.numNodes,
 					"the two paths disagree on the node count after round %d", round)
+				testutil.Equal(t, serialGraph.numNodes, dataflowGraph.numNodes,
+					"the serial and dataflow paths disagree on the node count after round %d", round)
 			}
 		})
 	}
@@ -54,6 +54,9 @@ func (graph *Graph) Stabilize(ctx context.Context) (err error) {
 	}
 	ctx = graph.stabilizeStart(ctx)
 	defer func() {
+		if graph.invariantCheck != InvariantCheckNone {
+			err = graph.checkInvariantsAfterStabilization(ctx, err)
+		}
 		graph.stabilizeEnd(ctx, err)
 	}()
 	// One guard for the whole pass, rather than one per node. A panic in user code becomes
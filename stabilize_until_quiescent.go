@@ -0,0 +1,227 @@
+package incr
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+)
+
+// StabilizeUntilQuiescent stabilizes repeatedly until a pass leaves no work for the
+// next, and returns how many passes that took.
+//
+// An update handler that sets a var, or a node that sets one mid-stabilization, does not
+// affect the pass it ran in: the set lands in the recompute heap for the next one. A
+// program whose handlers react to what they see by setting vars therefore has to call
+// [Graph.Stabilize] again, and again, until the handlers stop setting anything -- which
+// may be never. This is that loop, with a bound on it.
+//
+// A pass leaves work for the next when something other than an always node is queued
+// after it; always nodes, sentinels among them, are queued after every pass by design
+// and do not count, though a sentinel that fires in a pass and sets off handlers that
+// set vars does, through those vars. A graph whose handlers never set anything settles
+// in one pass.
+//
+// If the graph has not settled after maxRounds passes, the error is a
+// [*NotQuiescentError] naming the vars that were still being set. A feedback loop in
+// the handlers -- two vars each set from the other's value, or one flipping between two
+// states -- usually returns to a state it has been in before: a pass leaves the same
+// vars queued, with every var set since the loop started holding the same values as
+// after an earlier pass. The error then says how many passes apart the two were, in
+// [NotQuiescentError.Cycle]. The loop still runs out the rounds rather than stopping at
+// the repeat, since handlers keeping state of their own -- a counter, say -- can go
+// round the same states a few times and still settle. Values are compared by their
+// printed form, so only vars holding plain data -- no pointers, interfaces, channels or
+// functions, at any depth -- take part, and a pass leaving anything else queued is not
+// compared at all.
+//
+// An error from a pass stops the loop and is returned as is, with the passes run so far
+// including the one that failed. A maxRounds below one allows one pass.
+func (graph *Graph) StabilizeUntilQuiescent(ctx context.Context, maxRounds int) (rounds int, err error) {
+	// the number of rounds each var was queued after, to report the persistent ones
+	// first if the graph does not settle, and the passes each was queued after
+	var queuedRounds map[Identifier]int
+	var queuedAfter map[Identifier][]int
+	var vars []INode
+	// values holds what each var set so far will hold in the next pass, states the pass
+	// after which each combination of those and the queued vars was last seen, and cycle
+	// how many passes before the latest one it was last in the same state
+	values := make(map[Identifier]string)
+	states := make(map[string]int)
+	var cycle int
+	for _, n := range graph.recomputeHeap.pendingWork() {
+		if key, ok := quiescenceKey(n); ok {
+			values[n.Node().id] = key
+		}
+	}
+	maxRounds = max(maxRounds, 1)
+	for rounds < maxRounds {
+		rounds++
+		if err = graph.Stabilize(ctx); err != nil {
+			return
+		}
+		pending := graph.recomputeHeap.pendingWork()
+		if len(pending) == 0 {
+			return
+		}
+		if queuedRounds == nil {
+			queuedRounds = make(map[Identifier]int)
+			queuedAfter = make(map[Identifier][]int)
+		}
+		cycle = 0
+		compared := true
+		for _, n := range pending {
+			key, ok := quiescenceKey(n)
+			if !ok {
+				compared = false
+			}
+			if _, isVar := n.(quiescenceKeyer); !isVar {
+				continue
+			}
+			id := n.Node().id
+			if queuedRounds[id] == 0 {
+				vars = append(vars, n)
+			}
+			queuedRounds[id]++
+			queuedAfter[id] = append(queuedAfter[id], rounds)
+			if ok {
+				values[id] = key
+			}
+		}
+		if !compared {
+			continue
+		}
+		state := quiescenceState(pending, values)
+		if last, seen := states[state]; seen {
+			cycle = rounds - last
+		}
+		states[state] = rounds
+	}
+	err = newNotQuiescentError(rounds, cycle, vars, queuedRounds, queuedAfter)
+	return
+}
+
+// newNotQuiescentError reports the vars queued after the passes that did not settle: the
+// passes of the cycle if one was found, or all of them.
+func newNotQuiescentError(rounds, cycle int, vars []INode, queuedRounds map[Identifier]int, queuedAfter map[Identifier][]int) *NotQuiescentError {
+	var changing []INode
+	for _, n := range vars {
+		after := queuedAfter[n.Node().id]
+		if cycle == 0 || after[len(after)-1] > rounds-cycle {
+			changing = append(changing, n)
+		}
+	}
+	slices.SortStableFunc(changing, func(a, b INode) int {
+		return cmp.Compare(queuedRounds[b.Node().id], queuedRounds[a.Node().id])
+	})
+	return &NotQuiescentError{Rounds: rounds, Cycle: cycle, Changing: changing}
+}
+
+// quiescenceKeyer is implemented by vars, which can say what they will hold in the next
+// pass; see [Graph.StabilizeUntilQuiescent].
+type quiescenceKeyer interface {
+	quiescenceKey() (string, bool)
+}
+
+func quiescenceKey(n INode) (string, bool) {
+	if typed, ok := n.(quiescenceKeyer); ok {
+		return typed.quiescenceKey()
+	}
+	return "", false
+}
+
+// quiescenceState describes the queued vars and the values of every var set so far, in
+// an order that does not depend on the order they were queued or set in.
+func quiescenceState(pending []INode, values map[Identifier]string) string {
+	queued := make([]string, 0, len(pending))
+	for _, n := range pending {
+		queued = append(queued, n.Node().id.String())
+	}
+	slices.Sort(queued)
+	set := make([]string, 0, len(values))
+	for id, value := range values {
+		set = append(set, id.String()+"="+value)
+	}
+	slices.Sort(set)
+	return strings.Join(queued, ",") + ";" + strings.Join(set, ";")
+}
+
+// plainData reports whether a value of the given type prints the same exactly when it
+// holds the same data, which is not so of anything that refers to data elsewhere.
+func plainData(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.Bool, reflect.String,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64, reflect.Complex64, reflect.Complex128:
+		return true
+	case reflect.Array, reflect.Slice:
+		return plainData(t.Elem())
+	case reflect.Map:
+		return plainData(t.Key()) && plainData(t.Elem())
+	case reflect.Struct:
+		for index := range t.NumField() {
+			if !plainData(t.Field(index).Type) {
+				return false
+			}
+		}
+		return true
+	default:
+		return false
+	}
+}
+
+// NotQuiescentError is returned by [Graph.StabilizeUntilQuiescent] when the graph still
+// had work queued after the last pass it was allowed.
+type NotQuiescentError struct {
+	// Rounds is the number of passes run.
+	Rounds int
+	// Cycle is the number of passes after which the graph had last been in the state
+	// the final pass left it in, or zero if the final state was new.
+	Cycle int
+	// Changing are the vars the passes kept setting, those set after the most passes
+	// first: the vars queued after any of the last Cycle passes if there was a cycle,
+	// and otherwise after any pass at all.
+	Changing []INode
+}
+
+// notQuiescentNamed is how many of the changing nodes the error message names.
+const notQuiescentNamed = 5
+
+func (nqe *NotQuiescentError) Error() string {
+	names := make([]string, 0, min(len(nqe.Changing), notQuiescentNamed))
+	for _, n := range nqe.Changing[:min(len(nqe.Changing), notQuiescentNamed)] {
+		names = append(names, fmt.Sprint(n))
+	}
+	if extra := len(nqe.Changing) - len(names); extra > 0 {
+		names = append(names, fmt.Sprintf("and %d more", extra))
+	}
+	message := fmt.Sprintf("incr: not quiescent after %d rounds", nqe.Rounds)
+	if nqe.Cycle > 0 {
+		message += fmt.Sprintf("; repeating every %d rounds", nqe.Cycle)
+	}
+	if len(names) > 0 {
+		message += "; still changing: " + strings.Join(names, ", ")
+	}
+	return message
+}
+
+// pendingWork returns the nodes in the heap other than always nodes, which are requeued
+// after every pass whether or not anything else is left to do.
+func (rh *recomputeHeap) pendingWork() (pending []INode) {
+	rh.mu.Lock()
+	defer rh.mu.Unlock()
+	if rh.numItems == 0 {
+		return
+	}
+	for height := rh.minHeight; height <= rh.maxHeight; height++ {
+		for cursor := rh.heights[height].head; cursor != nil; cursor = cursor.nextInRecomputeHeap {
+			if !cursor.always {
+				pending = append(pending, cursor.self)
+			}
+		}
+	}
+	return
+}
@@ -0,0 +1,219 @@
+package incr
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/wcharczuk/go-incr/testutil"
+)
+
+func Test_StabilizeUntilQuiescent(t *testing.T) {
+	ctx := testContext()
+	g := New()
+	v := Var(g, 1)
+	o := MustObserve(g, Map(g, v, func(x int) int { return x * 2 }))
+
+	rounds, err := g.StabilizeUntilQuiescent(ctx, 10)
+	testutil.NoError(t, err)
+	testutil.Equal(t, 1, rounds)
+	testutil.Equal(t, 2, o.Value())
+
+	// a handler that keeps setting the var until it reaches a bound
+	o.OnUpdate(func(_ context.Context, value int) {
+		if value < 16 {
+			v.Set(value)
+		}
+	})
+	v.Set(2)
+	rounds, err = g.StabilizeUntilQuiescent(ctx, 10)
+	testutil.NoError(t, err)
+	// 4, 8 and then 16, which sets nothing
+	testutil.Equal(t, 3, rounds)
+	testutil.Equal(t, 16, o.Value())
+}
+
+func Test_StabilizeUntilQuiescent_setDuringStabilization(t *testing.T) {
+	ctx := testContext()
+	g := New()
+	source := Var(g, 0)
+	target := Var(g, 0)
+	// a node that sets another var while the pass is running, which the graph defers
+	MustObserve(g, Map(g, source, func(x int) int {
+		target.Set(x + 1)
+		return x
+	}))
+	o := MustObserve(g, target)
+
+	source.Set(5)
+	rounds, err := g.StabilizeUntilQuiescent(ctx, 10)
+	testutil.NoError(t, err)
+	testutil.Equal(t, 2, rounds)
+	testutil.Equal(t, 6, o.Value())
+}
+
+func Test_StabilizeUntilQuiescent_sentinels(t *testing.T) {
+	ctx := testContext()
+	g := New()
+	v := Var(g, 0)
+	m := Map(g, v, func(x int) int { return x })
+	MustObserve(g, m)
+	var fire bool
+	Sentinel(g, func() bool { return fire }, m)
+
+	// the sentinel is queued after every pass, which is not work left over
+	rounds, err := g.StabilizeUntilQuiescent(ctx, 10)
+	testutil.NoError(t, err)
+	testutil.Equal(t, 1, rounds)
+	rounds, err = g.StabilizeUntilQuiescent(ctx, 10)
+	testutil.NoError(t, err)
+	testutil.Equal(t, 1, rounds)
+
+	// nor is a sentinel firing, unless what it sets off sets a var
+	fire = true
+	rounds, err = g.StabilizeUntilQuiescent(ctx, 10)
+	testutil.NoError(t, err)
+	testutil.Equal(t, 1, rounds)
+}
+
+func Test_StabilizeUntilQuiescent_oscillates(t *testing.T) {
+	ctx := testContext()
+	g := New()
+	a := Var(g, false)
+	b := Var(g, false)
+	oa := MustObserve(g, a)
+	ob := MustObserve(g, b)
+
+	// b follows a, and a follows not b, which never settles
+	oa.OnUpdate(func(_ context.Context, value bool) { b.Set(value) })
+	ob.OnUpdate(func(_ context.Context, value bool) { a.Set(!value) })
+	a.Set(true)
+
+	// the pair goes through four states and then comes back round, which is reported
+	// once the rounds run out
+	rounds, err := g.StabilizeUntilQuiescent(ctx, 100)
+	testutil.Equal(t, 100, rounds)
+	var nqe *NotQuiescentError
+	testutil.Equal(t, true, errors.As(err, &nqe))
+	testutil.Equal(t, 100, nqe.Rounds)
+	testutil.Equal(t, 4, nqe.Cycle)
+	testutil.Equal(t, 2, len(nqe.Changing))
+	for _, n := range nqe.Changing {
+		testutil.Equal(t, true, n.Node().ID() == a.Node().ID() || n.Node().ID() == b.Node().ID())
+	}
+	testutil.Matches(t, `not quiescent after 100 rounds; repeating every 4 rounds; still changing: var\[`, err.Error())
+}
+
+func Test_StabilizeUntilQuiescent_repeatsThenSettles(t *testing.T) {
+	ctx := testContext()
+	g := New()
+	flip := Var(g, false)
+	o := MustObserve(g, flip)
+
+	// the handler keeps its own count, so the var goes round the same two states a few
+	// times and then stops; the repeat is not taken for a loop that never ends
+	var flips int
+	o.OnUpdate(func(_ context.Context, value bool) {
+		if flips < 6 {
+			flips++
+			flip.Set(!value)
+		}
+	})
+	flip.Set(true)
+
+	rounds, err := g.StabilizeUntilQuiescent(ctx, 100)
+	testutil.NoError(t, err)
+	testutil.Equal(t, 7, rounds)
+	testutil.Equal(t, 6, flips)
+}
+
+func Test_StabilizeUntilQuiescent_oscillatesAfterSettling(t *testing.T) {
+	ctx := testContext()
+	g := New()
+	once := Var(g, 0)
+	flip := Var(g, false)
+	oonce := MustObserve(g, once)
+	oflip := MustObserve(g, flip)
+
+	// once is set a single time on the way in, and then flip toggles forever
+	oonce.OnUpdate(func(context.Context, int) { flip.Set(true) })
+	oflip.OnUpdate(func(_ context.Context, value bool) { flip.Set(!value) })
+	once.Set(1)
+
+	rounds, err := g.StabilizeUntilQuiescent(ctx, 100)
+	var nqe *NotQuiescentError
+	testutil.Equal(t, true, errors.As(err, &nqe))
+	testutil.Equal(t, 2, nqe.Cycle)
+	testutil.Equal(t, rounds, nqe.Rounds)
+	// only what kept changing is named, not everything queued along the way
+	testutil.Equal(t, 1, len(nqe.Changing))
+	testutil.Equal(t, flip.Node().ID(), nqe.Changing[0].Node().ID())
+}
+
+func Test_StabilizeUntilQuiescent_convergesSlowly(t *testing.T) {
+	ctx := testContext()
+	g := New()
+	v := Var(g, 0)
+	o := MustObserve(g, v)
+	// every pass leaves the same var queued, but with a new value, until it stops
+	o.OnUpdate(func(_ context.Context, value int) {
+		if value > 0 {
+			v.Set(value - 1)
+		}
+	})
+	v.Set(40)
+
+	rounds, err := g.StabilizeUntilQuiescent(ctx, 100)
+	testutil.NoError(t, err)
+	testutil.Equal(t, 41, rounds)
+	testutil.Equal(t, 0, o.Value())
+}
+
+func Test_StabilizeUntilQuiescent_notComparable(t *testing.T) {
+	ctx := testContext()
+	g := New()
+	value := new(int)
+	v := Var[*int](g, nil)
+	o := MustObserve(g, v)
+	// the same pointer each time, to data that changes; that prints the same and is not
+	// the same state, so the pass is not compared and the rounds run out
+	o.OnUpdate(func(_ context.Context, p *int) {
+		*p++
+		v.Set(p)
+	})
+	v.Set(value)
+
+	rounds, err := g.StabilizeUntilQuiescent(ctx, 8)
+	testutil.Equal(t, 8, rounds)
+	var nqe *NotQuiescentError
+	testutil.Equal(t, true, errors.As(err, &nqe))
+	testutil.Equal(t, 0, nqe.Cycle)
+	testutil.Equal(t, 1, len(nqe.Changing))
+	testutil.Matches(t, `not quiescent after 8 rounds; still changing: var\[`, err.Error())
+}
+
+func Test_StabilizeUntilQuiescent_error(t *testing.T) {
+	ctx := testContext()
+	g := New()
+	v := Var(g, 0)
+	o := MustObserve(g, MapContext(g, v, func(_ context.Context, x int) (int, error) {
+		if x > 2 {
+			return 0, errors.New("too big")
+		}
+		return x, nil
+	}))
+	o.OnUpdate(func(_ context.Context, value int) { v.Set(value + 1) })
+
+	rounds, err := g.StabilizeUntilQuiescent(ctx, 10)
+	testutil.Error(t, err)
+	testutil.Equal(t, 4, rounds)
+
+	// a maxRounds below one still stabilizes
+	g = New()
+	w := Var(g, 1)
+	ow := MustObserve(g, w)
+	rounds, err = g.StabilizeUntilQuiescent(ctx, 0)
+	testutil.NoError(t, err)
+	testutil.Equal(t, 1, rounds)
+	testutil.Equal(t, 1, ow.Value())
+}
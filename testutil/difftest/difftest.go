@@ -0,0 +1,348 @@
+/*
+Package difftest checks go-incr against the naive reference implementation in
+incrutil/naive, which recomputes everything it is asked for and so cannot be wrong in
+the ways an incremental engine can.
+
+A [Program] builds a graph of int nodes out of vars, maps, binds, cutoffs and folds,
+observes some of them, sets vars and stabilizes. A [Harness] runs it against both
+implementations and, after every stabilization, compares every observer with the value
+the naive graph computes for the same node. When a program fails, [Harness.Shrink]
+deletes ops and lowers args for as long as it keeps failing, so what gets reported is
+the handful of statements that matter rather than the hundred the fuzzer generated.
+
+Custom nodes are checked the same way, by giving the harness a [Custom] with both an
+incremental and a naive construction of the node, which random programs then mix in
+with everything else:
+
+	func FuzzMyNode(f *testing.F) {
+		difftest.New(difftest.Custom{
+			Name:  "MyNode",
+			Arity: 1,
+			Incr:  func(scope incr.Scope, inputs ...incr.Incr[int]) incr.Incr[int] { ... },
+			Naive: func(inputs ...naive.Node[int]) naive.Node[int] { ... },
+		}).Fuzz(f)
+	}
+*/
+package difftest
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"testing"
+
+	"github.com/wcharczuk/go-incr"
+	"github.com/wcharczuk/go-incr/incrutil/naive"
+)
+
+// Custom is a node under test, constructed both ways.
+type Custom struct {
+	// Name is what the node is called in a described program.
+	Name string
+	// Arity is how many inputs the node takes, one or two.
+	Arity int
+	// Stateful marks a node whose value depends on more than its inputs' current values,
+	// the way a cutoff's does, so that programs keep it out of reach of a bind.
+	Stateful bool
+	// Incr adds the node to a go-incr graph.
+	Incr func(incr.Scope, ...incr.Incr[int]) incr.Incr[int]
+	// Naive returns the node's naive counterpart. It is read once after every
+	// stabilization while observed, whether or not go-incr recomputed its counterpart,
+	// so a stateful one has to give the same value again when its inputs have not
+	// changed.
+	Naive func(...naive.Node[int]) naive.Node[int]
+}
+
+// New returns a harness that mixes a given set of custom nodes into the programs it
+// runs.
+func New(customs ...Custom) *Harness {
+	return &Harness{
+		customs: customs,
+	}
+}
+
+// Harness runs programs against go-incr and the naive reference implementation; see
+// the package documentation.
+type Harness struct {
+	customs []Custom
+}
+
+// schedulers are the parallel schedulers a program is run under, once each.
+var schedulers = []struct {
+	name      string
+	scheduler incr.ParallelScheduler
+}{
+	{"heightBlocks", incr.ParallelSchedulerHeightBlocks},
+	{"dataflow", incr.ParallelSchedulerDataflow},
+}
+
+// MismatchError is returned by [Harness.Run] when an observer disagrees with the naive
+// graph.
+type MismatchError struct {
+	// Op is the index in the program of the stabilization after which they disagreed.
+	Op int
+	// Scheduler is the parallel scheduler the graph was built with.
+	Scheduler string
+	// Node is the node observed, numbered as [Harness.Describe] numbers it.
+	Node int
+	// Incr and Naive are the values each implementation gave the node.
+	Incr, Naive int
+}
+
+// Error implements error.
+func (e *MismatchError) Error() string {
+	return fmt.Sprintf("difftest; op %d (%s): n%d observed %d, naive %d", e.Op, e.Scheduler, e.Node, e.Incr, e.Naive)
+}
+
+// Run runs a program against go-incr, once with each parallel scheduler, and against
+// the naive reference implementation. It returns a [*MismatchError] at the first
+// observer that disagrees, or any error or panic from stabilizing, which includes the
+// graph's own invariants breaking after a stabilization or a bind rebuild.
+func (h *Harness) Run(ctx context.Context, p Program) error {
+	steps := h.resolve(p)
+	for _, s := range schedulers {
+		if err := h.run(ctx, steps, s.name, s.scheduler); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type observed struct {
+	node     int
+	observer incr.ObserveIncr[int]
+}
+
+func (h *Harness) run(ctx context.Context, steps []step, schedulerName string, scheduler incr.ParallelScheduler) (err error) {
+	var current int
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("difftest; op %d (%s): panic: %v", current, schedulerName, r)
+		}
+	}()
+
+	g := incr.New(
+		incr.OptGraphMaxHeight(512),
+		incr.OptGraphParallelism(4),
+		incr.OptGraphParallelScheduler(scheduler),
+		incr.OptGraphCheckInvariants(incr.InvariantCheckBindRebuild),
+	)
+	var inodes []incr.Incr[int]
+	var nnodes []naive.Node[int]
+	ivars := make(map[int]incr.VarIncr[int])
+	nvars := make(map[int]naive.VarNode[int])
+	var observers []observed
+	// the naive graph is read once per stabilization, through a memo on every node, so
+	// that a diamond is not read twice and a deep one does not cost exponential time
+	var pass int
+	add := func(in incr.Incr[int], nn naive.Node[int]) {
+		inodes = append(inodes, in)
+		nnodes = append(nnodes, &memo{node: nn, pass: &pass, readAt: -1})
+	}
+	inputs := func(s step) (is []incr.Incr[int], ns []naive.Node[int]) {
+		for _, input := range s.inputs {
+			is = append(is, inodes[input])
+			ns = append(ns, nnodes[input])
+		}
+		return
+	}
+
+	for _, s := range steps {
+		current = s.op
+		is, ns := inputs(s)
+		switch s.kind {
+		case OpVar:
+			iv, nv := incr.Var(g, s.arg), naive.Var(s.arg)
+			ivars[s.node], nvars[s.node] = iv, nv
+			add(iv, nv)
+		case OpMap:
+			offset := s.arg
+			add(
+				incr.Map(g, is[0], func(x int) int { return x + offset }),
+				naive.Map(func(xs ...int) int { return xs[0] + offset }, ns...),
+			)
+		case OpMap2:
+			add(
+				incr.Map2(g, is[0], is[1], func(x, y int) int { return x + y }),
+				naive.Map(func(xs ...int) int { return xs[0] + xs[1] }, ns...),
+			)
+		case OpMapN:
+			add(incr.MapN(g, sum, is...), naive.Map(sum, ns...))
+		case OpFold:
+			add(
+				incr.ArrayFold(g, 0, fold, is...),
+				naive.Map(func(xs ...int) int {
+					acc := 0
+					for _, x := range xs {
+						acc = fold(acc, x)
+					}
+					return acc
+				}, ns...),
+			)
+		case OpBind:
+			ieven, iodd := is[1], is[2]
+			neven, nodd := ns[1], ns[2]
+			add(
+				incr.Bind(g, is[0], func(bs incr.Scope, x int) incr.Incr[int] {
+					if x%2 == 0 {
+						return incr.Map(bs, ieven, double)
+					}
+					return iodd
+				}),
+				naive.Bind(ns[0], func(x int) naive.Node[int] {
+					if x%2 == 0 {
+						return naive.Map(func(xs ...int) int { return double(xs[0]) }, neven)
+					}
+					return nodd
+				}),
+			)
+		case OpCutoff:
+			within := withinFn(s.arg)
+			add(incr.Cutoff(g, is[0], within), naive.Cutoff(ns[0], within))
+		case OpCustom:
+			custom := h.customs[s.arg]
+			add(custom.Incr(g, is...), custom.Naive(ns...))
+		case OpObserve:
+			o, err := incr.Observe(g, inodes[s.node])
+			if err != nil {
+				return fmt.Errorf("difftest; op %d (%s): %w", s.op, schedulerName, err)
+			}
+			observers = append(observers, observed{node: s.node, observer: o})
+		case OpSet:
+			ivars[s.node].Set(s.arg)
+			nvars[s.node].SetValue(s.arg)
+		case OpStabilize:
+			if s.arg == 0 {
+				err = g.Stabilize(ctx)
+			} else {
+				err = g.ParallelStabilize(ctx)
+			}
+			if err != nil {
+				return fmt.Errorf("difftest; op %d (%s): %w", s.op, schedulerName, err)
+			}
+			pass++
+			for _, o := range observers {
+				if got, want := o.observer.Value(), nnodes[o.node].Value(); got != want {
+					return &MismatchError{Op: s.op, Scheduler: schedulerName, Node: o.node, Incr: got, Naive: want}
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func sum(xs ...int) (total int) {
+	for _, x := range xs {
+		total += x
+	}
+	return
+}
+
+// fold depends on the order of its inputs, so that a fold over them in the wrong order
+// shows.
+func fold(acc, x int) int { return acc*31 + x }
+
+func double(x int) int { return x * 2 }
+
+func withinFn(threshold int) func(int, int) bool {
+	return func(oldv, newv int) bool {
+		delta := newv - oldv
+		return delta >= -threshold && delta <= threshold
+	}
+}
+
+// memo reads a naive node at most once per stabilization.
+type memo struct {
+	node   naive.Node[int]
+	pass   *int
+	readAt int
+	value  int
+}
+
+func (m *memo) Value() int {
+	if m.readAt != *m.pass {
+		m.value = m.node.Value()
+		m.readAt = *m.pass
+	}
+	return m.value
+}
+
+// Shrink returns the smallest program it can find that still fails when run, by
+// deleting runs of ops, from half the program down to one op at a time, and then
+// lowering each arg toward zero, until neither makes progress. A program that does not
+// fail is returned as is.
+func (h *Harness) Shrink(ctx context.Context, p Program) Program {
+	fails := func(candidate Program) bool {
+		return h.Run(ctx, candidate) != nil
+	}
+	if !fails(p) {
+		return p
+	}
+	p = slices.Clone(p)
+	for progress := true; progress; {
+		progress = false
+		for chunk := max(len(p)/2, 1); chunk >= 1 && len(p) > 0; chunk /= 2 {
+			for start := 0; start+chunk <= len(p); {
+				candidate := slices.Delete(slices.Clone(p), start, start+chunk)
+				if fails(candidate) {
+					p = candidate
+					progress = true
+					continue
+				}
+				start += chunk
+			}
+		}
+		for i := range p {
+			for j, arg := range p[i].Args {
+				for _, lower := range []uint8{0, arg / 2, arg - 1} {
+					if lower >= arg {
+						continue
+					}
+					candidate := slices.Clone(p)
+					candidate[i].Args[j] = lower
+					if fails(candidate) {
+						p = candidate
+						progress = true
+						break
+					}
+				}
+			}
+		}
+	}
+	return p
+}
+
+// Check runs a program and, if it fails, shrinks it and fails the test with the
+// shrunk program: its error, its statements, and its bytes, to add to a fuzz corpus or
+// decode with [FromBytes] in a regression test.
+func (h *Harness) Check(t *testing.T, p Program) {
+	t.Helper()
+	ctx := context.Background()
+	if err := h.Run(ctx, p); err == nil {
+		return
+	}
+	shrunk := h.Shrink(ctx, p)
+	t.Fatalf("%v\nshrunk from %d ops to %d:\n%s\nbytes: %q", h.Run(ctx, shrunk), len(p), len(shrunk), h.Describe(shrunk), shrunk.Bytes())
+}
+
+// Fuzz runs a fuzz test of programs decoded from the fuzzer's bytes with [FromBytes],
+// seeded with a program exercising each kind of op.
+func (h *Harness) Fuzz(f *testing.F) {
+	for _, seed := range seeds {
+		f.Add(seed.Bytes())
+	}
+	f.Fuzz(func(t *testing.T, data []byte) {
+		h.Check(t, FromBytes(data))
+	})
+}
+
+var seeds = []Program{
+	// a var, a map over it, observed, set and stabilized both ways
+	{{OpVar, [3]uint8{1}}, {OpMap, [3]uint8{0, 3}}, {OpObserve, [3]uint8{1}}, {OpStabilize, [3]uint8{0}}, {OpSet, [3]uint8{0, 5}}, {OpStabilize, [3]uint8{1}}},
+	// a bind switching between its branches
+	{{OpVar, [3]uint8{0}}, {OpVar, [3]uint8{7}}, {OpBind, [3]uint8{0, 1, 1}}, {OpObserve, [3]uint8{2}}, {OpStabilize, [3]uint8{0}}, {OpSet, [3]uint8{0, 1}}, {OpStabilize, [3]uint8{1}}, {OpSet, [3]uint8{0, 2}}, {OpStabilize, [3]uint8{0}}},
+	// a cutoff under a fold and a mapN, with a change it cuts off and one it does not
+	{{OpVar, [3]uint8{10}}, {OpCutoff, [3]uint8{0, 2}}, {OpFold, [3]uint8{0, 1, 0}}, {OpMapN, [3]uint8{1, 2, 0}}, {OpObserve, [3]uint8{3}}, {OpStabilize, [3]uint8{0}}, {OpSet, [3]uint8{0, 11}}, {OpStabilize, [3]uint8{1}}, {OpSet, [3]uint8{0, 20}}, {OpStabilize, [3]uint8{0}}},
+	// a custom node, if there are any, over a map2
+	{{OpVar, [3]uint8{2}}, {OpVar, [3]uint8{3}}, {OpMap2, [3]uint8{0, 1}}, {OpCustom, [3]uint8{0, 2, 1}}, {OpObserve, [3]uint8{3}}, {OpStabilize, [3]uint8{1}}, {OpSet, [3]uint8{1, 9}}, {OpStabilize, [3]uint8{0}}},
+}
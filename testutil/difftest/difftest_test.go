@@ -0,0 +1,172 @@
+package difftest
+
+import (
+	"context"
+	"errors"
+	"math/rand/v2"
+	"strings"
+	"testing"
+
+	"github.com/wcharczuk/go-incr"
+	"github.com/wcharczuk/go-incr/incrutil"
+	"github.com/wcharczuk/go-incr/incrutil/naive"
+	"github.com/wcharczuk/go-incr/testutil"
+)
+
+// customs are nodes from outside the core, checked alongside it.
+var customs = []Custom{
+	{
+		Name:     "CutoffUnchanged",
+		Arity:    1,
+		Stateful: true,
+		Incr: func(scope incr.Scope, inputs ...incr.Incr[int]) incr.Incr[int] {
+			return incrutil.CutoffUnchanged(scope, inputs[0])
+		},
+		Naive: func(inputs ...naive.Node[int]) naive.Node[int] {
+			return naive.Cutoff(inputs[0], func(oldv, newv int) bool { return oldv == newv })
+		},
+	},
+	{
+		Name:  "DependOn",
+		Arity: 2,
+		Incr: func(scope incr.Scope, inputs ...incr.Incr[int]) incr.Incr[int] {
+			return incr.DependOn(scope, inputs[0], inputs[1])
+		},
+		Naive: func(inputs ...naive.Node[int]) naive.Node[int] {
+			return inputs[0]
+		},
+	},
+}
+
+// FuzzDifferential runs random programs against go-incr and the naive reference
+// implementation, and fails on the first observer the two disagree about.
+//
+// Where FuzzGraph checks that the graph's bookkeeping holds together whatever values it
+// computes, this checks the values, which no amount of consistent bookkeeping guarantees:
+// a node recomputed too early, a change lost to a cutoff, a bind returning last pass's
+// subgraph.
+func FuzzDifferential(f *testing.F) {
+	New(customs...).Fuzz(f)
+}
+
+func Test_Harness_Run_seeds(t *testing.T) {
+	h := New(customs...)
+	for _, seed := range seeds {
+		testutil.NoError(t, h.Run(context.Background(), seed), h.Describe(seed))
+	}
+}
+
+func Test_Harness_Run_generated(t *testing.T) {
+	h := New(customs...)
+	r := rand.New(rand.NewPCG(1, 2))
+	for range 200 {
+		h.Check(t, Generate(r, 1+r.IntN(maxOps)))
+	}
+}
+
+func Test_Harness_Run_mismatch(t *testing.T) {
+	// a custom node whose naive counterpart disagrees above a threshold
+	h := New(Custom{
+		Name:  "Broken",
+		Arity: 1,
+		Incr: func(scope incr.Scope, inputs ...incr.Incr[int]) incr.Incr[int] {
+			return incr.Map(scope, inputs[0], func(x int) int { return x })
+		},
+		Naive: func(inputs ...naive.Node[int]) naive.Node[int] {
+			return naive.Map(func(xs ...int) int {
+				if xs[0] > 100 {
+					return xs[0] + 1
+				}
+				return xs[0]
+			}, inputs...)
+		},
+	})
+	p := Program{
+		{OpVar, [3]uint8{1}},
+		{OpVar, [3]uint8{2}},
+		{OpMap, [3]uint8{0, 3}},
+		{OpCustom, [3]uint8{0, 2}},
+		{OpObserve, [3]uint8{3}},
+		{OpObserve, [3]uint8{2}},
+		{OpStabilize, [3]uint8{0}},
+		{OpSet, [3]uint8{1, 50}},
+		{OpStabilize, [3]uint8{1}},
+		{OpSet, [3]uint8{0, 200}},
+		{OpStabilize, [3]uint8{0}},
+	}
+	err := h.Run(context.Background(), p)
+	var mismatch *MismatchError
+	testutil.Equal(t, true, errors.As(err, &mismatch))
+	testutil.Equal(t, 10, mismatch.Op)
+	testutil.Equal(t, 3, mismatch.Node)
+	testutil.Equal(t, 203, mismatch.Incr)
+	testutil.Equal(t, 204, mismatch.Naive)
+
+	shrunk := h.Shrink(context.Background(), p)
+	testutil.Error(t, h.Run(context.Background(), shrunk))
+	// a var set past the threshold, the broken node over it, observed and stabilized
+	testutil.Equal(t, 5, len(shrunk), h.Describe(shrunk))
+	testutil.Equal(t, true, strings.Contains(h.Describe(shrunk), "Broken(n0)"), h.Describe(shrunk))
+}
+
+func Test_Harness_Run_panic(t *testing.T) {
+	h := New(Custom{
+		Name:  "Panics",
+		Arity: 1,
+		Incr: func(scope incr.Scope, inputs ...incr.Incr[int]) incr.Incr[int] {
+			return incr.Map(scope, inputs[0], func(x int) int { panic("only a test") })
+		},
+		Naive: func(inputs ...naive.Node[int]) naive.Node[int] {
+			return inputs[0]
+		},
+	})
+	p := Program{
+		{OpVar, [3]uint8{1}},
+		{OpCustom, [3]uint8{0, 0}},
+		{OpObserve, [3]uint8{1}},
+		{OpStabilize, [3]uint8{0}},
+	}
+	err := h.Run(context.Background(), p)
+	testutil.Error(t, err)
+	var mismatch *MismatchError
+	testutil.Equal(t, false, errors.As(err, &mismatch))
+}
+
+func Test_Harness_Shrink_passing(t *testing.T) {
+	h := New()
+	p := seeds[0]
+	testutil.Equal(t, p, h.Shrink(context.Background(), p))
+}
+
+func Test_FromBytes(t *testing.T) {
+	r := rand.New(rand.NewPCG(3, 4))
+	p := Generate(r, 32)
+	testutil.Equal(t, p, FromBytes(p.Bytes()))
+
+	// kinds wrap, trailing bytes are dropped, and programs are capped
+	testutil.Equal(t, Program{{OpMap, [3]uint8{1, 2, 3}}}, FromBytes([]byte{uint8(numOpKinds) + 1, 1, 2, 3, 9, 9}))
+	testutil.Equal(t, maxOps, len(FromBytes(make([]byte, (maxOps+10)*bytesPerOp))))
+}
+
+func Test_Harness_Describe(t *testing.T) {
+	h := New(customs...)
+	described := h.Describe(Program{
+		{OpMap, [3]uint8{0}}, // skipped, there is nothing to map over
+		{OpVar, [3]uint8{4}},
+		{OpCutoff, [3]uint8{0, 5}},
+		{OpBind, [3]uint8{0, 1, 0}},
+		{OpCustom, [3]uint8{1, 2, 0}},
+		{OpObserve, [3]uint8{3}},
+		{OpSet, [3]uint8{0, 8}},
+		{OpStabilize, [3]uint8{1}},
+	})
+	testutil.Equal(t, strings.Join([]string{
+		"n0 := Var(4)\t// op 1",
+		"n1 := Cutoff(n0, within 1)\t// op 2",
+		"n2 := Bind(n0, even: Map(n0, *2), odd: n0)\t// op 3",
+		"n3 := DependOn(n2, n0)\t// op 4",
+		"Observe(n3)\t// op 5",
+		"n0.Set(8)\t// op 6",
+		"ParallelStabilize()\t// op 7",
+	}, "\n")+"\n", described)
+}
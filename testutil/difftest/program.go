@@ -0,0 +1,283 @@
+package difftest
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"strings"
+)
+
+// OpKind is the kind of an operation in a [Program].
+type OpKind uint8
+
+// Op kinds, in the order [FromBytes] numbers them.
+const (
+	// OpVar adds a var holding Args[0].
+	OpVar OpKind = iota
+	// OpMap adds a map over the node Args[0] picks that adds Args[1] mod 8 to it.
+	OpMap
+	// OpMap2 adds a map summing the nodes Args[0] and Args[1] pick.
+	OpMap2
+	// OpMapN adds a [incr.MapN] summing the nodes all three args pick.
+	OpMapN
+	// OpBind adds a bind over the node Args[0] picks. On an even value it builds a map
+	// doubling the node Args[1] picks in its scope; on an odd one it returns the node
+	// Args[2] picks as is.
+	OpBind
+	// OpCutoff adds a cutoff over the node Args[0] picks that cuts off a change of at
+	// most Args[1] mod 4.
+	OpCutoff
+	// OpFold adds an [incr.ArrayFold] over the nodes all three args pick, which depends
+	// on their order.
+	OpFold
+	// OpObserve observes the node Args[0] picks.
+	OpObserve
+	// OpSet sets the var Args[0] picks to Args[1].
+	OpSet
+	// OpStabilize stabilizes, serially if Args[0] is even and in parallel if it is odd,
+	// and then compares every observer against the naive graph.
+	OpStabilize
+	// OpCustom adds the custom node Args[0] picks, over the nodes Args[1] and Args[2]
+	// pick, as many as it takes.
+	OpCustom
+
+	numOpKinds
+)
+
+var opKindNames = [...]string{
+	OpVar:       "Var",
+	OpMap:       "Map",
+	OpMap2:      "Map2",
+	OpMapN:      "MapN",
+	OpBind:      "Bind",
+	OpCutoff:    "Cutoff",
+	OpFold:      "ArrayFold",
+	OpObserve:   "Observe",
+	OpSet:       "Set",
+	OpStabilize: "Stabilize",
+	OpCustom:    "Custom",
+}
+
+// String implements [fmt.Stringer].
+func (k OpKind) String() string {
+	if k < numOpKinds {
+		return opKindNames[k]
+	}
+	return fmt.Sprintf("OpKind(%d)", uint8(k))
+}
+
+// Op is a single operation of a [Program].
+//
+// Args pick the nodes an op reads by index modulo the number of nodes built so far, and
+// an op that cannot apply yet, like a map before there is anything to map over, is
+// skipped. That way every sequence of ops is a valid program, which is what lets a
+// fuzzer generate them from raw bytes and lets [Harness.Shrink] delete any of them.
+type Op struct {
+	Kind OpKind
+	Args [3]uint8
+}
+
+// Program is a sequence of operations that build a graph, set its vars and stabilize
+// it, which a [Harness] runs against go-incr and the naive reference implementation.
+type Program []Op
+
+const (
+	// maxNodes and maxOps bound a program the way FuzzGraph bounds its own; past them a
+	// naive graph costs more to read than a fuzzer can afford.
+	maxNodes = 24
+	maxOps   = 96
+
+	bytesPerOp = 4
+)
+
+// FromBytes decodes a program from arbitrary bytes, four to an op: the kind, modulo the
+// number of kinds, and then the three args. Trailing bytes short of an op are ignored.
+func FromBytes(data []byte) Program {
+	p := make(Program, 0, len(data)/bytesPerOp)
+	for i := 0; i+bytesPerOp <= len(data) && len(p) < maxOps; i += bytesPerOp {
+		p = append(p, Op{
+			Kind: OpKind(data[i] % uint8(numOpKinds)),
+			Args: [3]uint8{data[i+1], data[i+2], data[i+3]},
+		})
+	}
+	return p
+}
+
+// Bytes encodes the program the way [FromBytes] decodes it, to add a program to a fuzz
+// corpus or reproduce one in a test.
+func (p Program) Bytes() []byte {
+	data := make([]byte, 0, len(p)*bytesPerOp)
+	for _, op := range p {
+		data = append(data, uint8(op.Kind), op.Args[0], op.Args[1], op.Args[2])
+	}
+	return data
+}
+
+// Generate returns a random program of a given number of ops, ending in a stabilization
+// so that whatever it builds is compared at least once.
+func Generate(r *rand.Rand, ops int) Program {
+	if ops <= 0 {
+		return nil
+	}
+	p := make(Program, ops)
+	for i := range p[:ops-1] {
+		p[i] = Op{
+			Kind: OpKind(r.IntN(int(numOpKinds))),
+			Args: [3]uint8{uint8(r.Uint32()), uint8(r.Uint32()), uint8(r.Uint32())},
+		}
+	}
+	p[ops-1] = Op{Kind: OpStabilize, Args: [3]uint8{uint8(r.Uint32())}}
+	return p
+}
+
+// step is an op resolved against the nodes built before it, with the args that picked
+// nodes replaced by the indexes of the nodes they picked.
+type step struct {
+	// op is the index of the op in the program.
+	op   int
+	kind OpKind
+	// node is the node the step adds, or the node it observes or sets.
+	node   int
+	inputs []int
+	// arg is what is left of the args: the value of a var or a set, the offset of a
+	// map, the threshold of a cutoff, the mode of a stabilization, the custom node.
+	arg int
+}
+
+// resolve works out what each op of a program does, skipping those that cannot apply.
+//
+// It also keeps the nodes that hold state out of reach of a bind. A cutoff's value
+// depends on every value it has seen, and go-incr may recompute a node in the same pass
+// that a bind stops depending on it, which the naive graph, reading only what the bind
+// returns, never does; the two can then disagree with neither being wrong. A node holds
+// state if it is a cutoff or a stateful custom node or reads one, and a bind only ever
+// returns nodes that do not. Those that do are only reachable from observers, which
+// never go away, so both graphs see every value they are given.
+func (h *Harness) resolve(p Program) (steps []step) {
+	var stateful []bool
+	var vars, stateless []int
+	pick := func(arg uint8) int { return int(arg) % len(stateful) }
+	pickStateless := func(arg uint8) int { return stateless[int(arg)%len(stateless)] }
+	add := func(s step, holdsState bool) {
+		for _, input := range s.inputs {
+			holdsState = holdsState || stateful[input]
+		}
+		s.node = len(stateful)
+		if !holdsState {
+			stateless = append(stateless, s.node)
+		}
+		stateful = append(stateful, holdsState)
+		steps = append(steps, s)
+	}
+	for index, op := range p {
+		if index == maxOps {
+			break
+		}
+		a, b, c := op.Args[0], op.Args[1], op.Args[2]
+		s := step{op: index, kind: op.Kind}
+		switch op.Kind {
+		case OpVar:
+			if len(stateful) == maxNodes {
+				continue
+			}
+			s.arg = int(a)
+			vars = append(vars, len(stateful))
+			add(s, false)
+			continue
+		case OpObserve:
+			if len(stateful) == 0 {
+				continue
+			}
+			s.node = pick(a)
+			steps = append(steps, s)
+			continue
+		case OpSet:
+			if len(vars) == 0 {
+				continue
+			}
+			s.node = vars[int(a)%len(vars)]
+			s.arg = int(b)
+			steps = append(steps, s)
+			continue
+		case OpStabilize:
+			s.arg = int(a) % 2
+			steps = append(steps, s)
+			continue
+		}
+
+		// everything else adds a node over existing ones; the first node is always a
+		// var, so there is always a node without state for a bind to return
+		if len(stateful) == 0 || len(stateful) == maxNodes {
+			continue
+		}
+		switch op.Kind {
+		case OpMap:
+			s.inputs = []int{pick(a)}
+			s.arg = int(b) % 8
+			add(s, false)
+		case OpMap2:
+			s.inputs = []int{pick(a), pick(b)}
+			add(s, false)
+		case OpMapN, OpFold:
+			s.inputs = []int{pick(a), pick(b), pick(c)}
+			add(s, false)
+		case OpBind:
+			s.inputs = []int{pick(a), pickStateless(b), pickStateless(c)}
+			add(s, false)
+		case OpCutoff:
+			s.inputs = []int{pick(a)}
+			s.arg = int(b) % 4
+			add(s, true)
+		case OpCustom:
+			if len(h.customs) == 0 {
+				continue
+			}
+			s.arg = int(a) % len(h.customs)
+			custom := h.customs[s.arg]
+			s.inputs = []int{pick(b)}
+			if custom.Arity > 1 {
+				s.inputs = append(s.inputs, pick(c))
+			}
+			add(s, custom.Stateful)
+		}
+	}
+	return
+}
+
+// Describe returns a program as the statements it runs, one to a line, with the ops it
+// skips left out, for reading a failing case.
+func (h *Harness) Describe(p Program) string {
+	sb := new(strings.Builder)
+	for _, s := range h.resolve(p) {
+		inputs := make([]string, len(s.inputs))
+		for i, input := range s.inputs {
+			inputs[i] = fmt.Sprintf("n%d", input)
+		}
+		args := strings.Join(inputs, ", ")
+		switch s.kind {
+		case OpVar:
+			fmt.Fprintf(sb, "n%d := Var(%d)", s.node, s.arg)
+		case OpMap:
+			fmt.Fprintf(sb, "n%d := Map(%s, +%d)", s.node, args, s.arg)
+		case OpCutoff:
+			fmt.Fprintf(sb, "n%d := Cutoff(%s, within %d)", s.node, args, s.arg)
+		case OpBind:
+			fmt.Fprintf(sb, "n%d := Bind(n%d, even: Map(n%d, *2), odd: n%d)", s.node, s.inputs[0], s.inputs[1], s.inputs[2])
+		case OpCustom:
+			fmt.Fprintf(sb, "n%d := %s(%s)", s.node, h.customs[s.arg].Name, args)
+		case OpObserve:
+			fmt.Fprintf(sb, "Observe(n%d)", s.node)
+		case OpSet:
+			fmt.Fprintf(sb, "n%d.Set(%d)", s.node, s.arg)
+		case OpStabilize:
+			if s.arg == 0 {
+				sb.WriteString("Stabilize()")
+			} else {
+				sb.WriteString("ParallelStabilize()")
+			}
+		default:
+			fmt.Fprintf(sb, "n%d := %v(%s)", s.node, s.kind, args)
+		}
+		fmt.Fprintf(sb, "\t// op %d\n", s.op)
+	}
+	return sb.String()
+}
@@ -5,10 +5,13 @@ import (
 	"fmt"
 	"io"
 	"log"
+	"log/slog"
 	"os"
 )
 
 // Tracer is a type that can implement a tracer.
+//
+// See [NewSlogTracer] for a tracer that writes structured attributes to a [slog.Handler].
 type Tracer interface {
 	Print(...any)
 	Error(...any)
@@ -54,6 +57,10 @@ func GetTracer(ctx context.Context) Tracer {
 // TracePrintln prints a line to the tracer on a given context.
 func TracePrintln(ctx context.Context, args ...any) {
 	if tracer := GetTracer(ctx); tracer != nil {
+		if st, ok := tracer.(*SlogTracer); ok {
+			st.write(ctx, st.stabilizationLevel, fmt.Sprint(args...), nil)
+			return
+		}
 		tracer.Print(FormatStabilizationNumber(ctx) + fmt.Sprint(args...))
 	}
 }
@@ -62,6 +69,10 @@ func TracePrintln(ctx context.Context, args ...any) {
 // context with a given format and args.
 func TracePrintf(ctx context.Context, format string, args ...any) {
 	if tracer := GetTracer(ctx); tracer != nil {
+		if st, ok := tracer.(*SlogTracer); ok {
+			st.write(ctx, st.stabilizationLevel, fmt.Sprintf(format, args...), nil)
+			return
+		}
 		tracer.Print(FormatStabilizationNumber(ctx) + fmt.Sprintf(format, args...))
 	}
 }
@@ -69,6 +80,10 @@ func TracePrintf(ctx context.Context, format string, args ...any) {
 // TraceErrorln prints a line to the error output of a tracer on a given context.
 func TraceErrorln(ctx context.Context, args ...any) {
 	if tracer := GetTracer(ctx); tracer != nil {
+		if st, ok := tracer.(*SlogTracer); ok {
+			st.write(ctx, slog.LevelError, fmt.Sprint(args...), nil)
+			return
+		}
 		tracer.Error(FormatStabilizationNumber(ctx) + fmt.Sprint(args...))
 	}
 }
@@ -77,6 +92,10 @@ func TraceErrorln(ctx context.Context, args ...any) {
 // on a given context with a given format and args.
 func TraceErrorf(ctx context.Context, format string, args ...any) {
 	if tracer := GetTracer(ctx); tracer != nil {
+		if st, ok := tracer.(*SlogTracer); ok {
+			st.write(ctx, slog.LevelError, fmt.Sprintf(format, args...), nil)
+			return
+		}
 		tracer.Error(FormatStabilizationNumber(ctx) + fmt.Sprintf(format, args...))
 	}
 }
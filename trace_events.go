@@ -0,0 +1,289 @@
+package incr
+
+import (
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"io"
+	"slices"
+	"sync"
+	"time"
+)
+
+// NewTraceEventRecorder returns a [Tracer] that records stabilization passes as a
+// timeline, to be written out with [TraceEventRecorder.WriteTo] in the Chrome trace
+// event format, which chrome://tracing and the Perfetto UI both open directly.
+//
+// Add it to the stabilization context with [WithTracer]. Each graph stabilized with it
+// is a process in the trace, so one recorder can follow several graphs, stabilizing
+// concurrently or not. Each pass is a span on the graph's stabilization track, along
+// with any trace lines printed during it. Each node recomputed is a span on the track of
+// the goroutine that ran it, with instant events marking a node that was cut off and a
+// bind that rebuilt its right-hand side: the coordinator track for the goroutine
+// stabilizing the graph, which is everything under [Graph.Stabilize] and the nodes
+// marked cheap with [Node.SetCost] under [Graph.ParallelStabilize], and a worker track
+// for each of the goroutines [Graph.ParallelStabilize] hands nodes to.
+//
+// The recorder keeps everything until it is reset, so it is for capturing a handful of
+// passes while investigating, not for leaving on.
+func NewTraceEventRecorder() *TraceEventRecorder {
+	return &TraceEventRecorder{
+		started: time.Now(),
+	}
+}
+
+// TraceEventRecorder records stabilization passes as trace events; see
+// [NewTraceEventRecorder].
+type TraceEventRecorder struct {
+	mu      sync.Mutex
+	started time.Time
+	events  []traceEvent
+	// graphs are the graphs recorded since the last reset, each with a process of its
+	// own, by identifier.
+	graphs  map[Identifier]*traceEventGraph
+	lastPid int
+}
+
+var (
+	_ Tracer = (*TraceEventRecorder)(nil)
+	_ Tracer = (*traceEventGraph)(nil)
+)
+
+// traceEventGraph is what a recorder keeps for one graph. It is also the tracer a pass
+// of that graph sees, so that lines printed during the pass land on its track.
+type traceEventGraph struct {
+	r   *TraceEventRecorder
+	id  Identifier
+	pid int
+
+	// the fields below are guarded by the recorder's mu
+
+	// workers is the highest numbered worker that has run a node.
+	workers     int
+	passStarted time.Time
+	passNum     uint64
+}
+
+// traceEvent is a single event in the trace event format. Times are in microseconds
+// since the recorder was created.
+type traceEvent struct {
+	Name  string         `json:"name"`
+	Cat   string         `json:"cat,omitempty"`
+	Phase string         `json:"ph"`
+	Ts    float64        `json:"ts"`
+	Dur   float64        `json:"dur,omitempty"`
+	Pid   int            `json:"pid"`
+	Tid   int            `json:"tid"`
+	Scope string         `json:"s,omitempty"`
+	Args  map[string]any `json:"args,omitempty"`
+}
+
+// the stabilization track comes first, then the coordinator's; worker n's track is
+// the coordinator's plus n
+const (
+	traceEventStabilizationTid = 0
+	traceEventCoordinatorTid   = 1
+)
+
+// traceEventCoordinator is the worker number of the goroutine stabilizing the graph;
+// the goroutines it hands nodes to are numbered from one.
+const traceEventCoordinator = 0
+
+// Print implements [Tracer], recording the line as an instant event on its own track,
+// since a line printed outside a pass belongs to no graph.
+func (r *TraceEventRecorder) Print(args ...any) {
+	r.instant(0, traceEventStabilizationTid, fmt.Sprint(args...), "trace", nil)
+}
+
+// Error implements [Tracer], recording the line as an instant event on its own track.
+func (r *TraceEventRecorder) Error(args ...any) {
+	r.instant(0, traceEventStabilizationTid, fmt.Sprint(args...), "error", nil)
+}
+
+// Print implements [Tracer], recording the line as an instant event on the graph's
+// stabilization track.
+func (tg *traceEventGraph) Print(args ...any) {
+	tg.r.instant(tg.pid, traceEventStabilizationTid, fmt.Sprint(args...), "trace", nil)
+}
+
+// Error implements [Tracer], recording the line as an instant event on the graph's
+// stabilization track.
+func (tg *traceEventGraph) Error(args ...any) {
+	tg.r.instant(tg.pid, traceEventStabilizationTid, fmt.Sprint(args...), "error", nil)
+}
+
+// Reset discards everything recorded so far.
+func (r *TraceEventRecorder) Reset() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.started = time.Now()
+	clear(r.events)
+	r.events = r.events[:0]
+	clear(r.graphs)
+}
+
+// WriteTo writes the events recorded so far to a given writer as a JSON trace.
+func (r *TraceEventRecorder) WriteTo(w io.Writer) (int64, error) {
+	r.mu.Lock()
+	graphs := make([]*traceEventGraph, 0, len(r.graphs))
+	for _, tg := range r.graphs {
+		graphs = append(graphs, tg)
+	}
+	slices.SortFunc(graphs, func(a, b *traceEventGraph) int { return cmp.Compare(a.pid, b.pid) })
+	var events []traceEvent
+	for _, tg := range graphs {
+		events = append(events,
+			traceEvent{
+				Name: "process_name", Phase: "M", Pid: tg.pid,
+				Args: map[string]any{"name": "graph " + tg.id.String()},
+			},
+			traceEvent{
+				Name: "thread_name", Phase: "M", Pid: tg.pid, Tid: traceEventStabilizationTid,
+				Args: map[string]any{"name": "stabilization"},
+			},
+			traceEvent{
+				Name: "thread_name", Phase: "M", Pid: tg.pid, Tid: traceEventCoordinatorTid,
+				Args: map[string]any{"name": "coordinator"},
+			},
+		)
+		for worker := 1; worker <= tg.workers; worker++ {
+			events = append(events, traceEvent{
+				Name: "thread_name", Phase: "M", Pid: tg.pid, Tid: traceEventCoordinatorTid + worker,
+				Args: map[string]any{"name": fmt.Sprintf("worker %d", worker)},
+			})
+		}
+	}
+	events = append(events, r.events...)
+	r.mu.Unlock()
+
+	cw := &countingWriter{w: w}
+	err := json.NewEncoder(cw).Encode(struct {
+		TraceEvents     []traceEvent `json:"traceEvents"`
+		DisplayTimeUnit string       `json:"displayTimeUnit"`
+	}{events, "ms"})
+	return cw.n, err
+}
+
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (cw *countingWriter) Write(p []byte) (n int, err error) {
+	n, err = cw.w.Write(p)
+	cw.n += int64(n)
+	return
+}
+
+func (r *TraceEventRecorder) since(t time.Time) float64 {
+	return float64(t.Sub(r.started).Nanoseconds()) / 1e3
+}
+
+func (r *TraceEventRecorder) instant(pid, tid int, name, cat string, args map[string]any) {
+	now := time.Now()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, traceEvent{
+		Name: name, Cat: cat, Phase: "i", Scope: "t", Ts: r.since(now), Pid: pid, Tid: tid, Args: args,
+	})
+}
+
+// passStart returns what the recorder keeps for a graph about to stabilize, which the
+// graph records the rest of the pass to.
+func (r *TraceEventRecorder) passStart(graph *Graph) *traceEventGraph {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	tg, ok := r.graphs[graph.id]
+	if !ok {
+		if r.graphs == nil {
+			r.graphs = make(map[Identifier]*traceEventGraph)
+		}
+		r.lastPid++
+		tg = &traceEventGraph{r: r, id: graph.id, pid: r.lastPid}
+		r.graphs[graph.id] = tg
+	}
+	tg.passStarted = time.Now()
+	tg.passNum = graph.stabilizationNum
+	return tg
+}
+
+func (tg *traceEventGraph) passEnd(err error) {
+	now := time.Now()
+	r := tg.r
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	args := map[string]any{
+		"graph":         tg.id.String(),
+		"stabilization": tg.passNum,
+	}
+	if err != nil {
+		args["error"] = err.Error()
+	}
+	r.events = append(r.events, traceEvent{
+		Name: "stabilize", Cat: "stabilization", Phase: "X", Pid: tg.pid, Tid: traceEventStabilizationTid,
+		Ts: r.since(tg.passStarted), Dur: float64(now.Sub(tg.passStarted).Nanoseconds()) / 1e3,
+		Args: args,
+	})
+}
+
+// traceEventSpan is a node recompute in progress.
+type traceEventSpan struct {
+	started time.Time
+	tid     int
+}
+
+// nodeStart starts the span for a node about to be recomputed by a given worker.
+func (tg *traceEventGraph) nodeStart(worker int) (span traceEventSpan) {
+	if worker > traceEventCoordinator {
+		tg.r.mu.Lock()
+		tg.workers = max(tg.workers, worker)
+		tg.r.mu.Unlock()
+	}
+	span.tid = traceEventCoordinatorTid + worker
+	span.started = time.Now()
+	return
+}
+
+// nodeEnd records the span for a node once it is recomputed.
+func (tg *traceEventGraph) nodeEnd(span traceEventSpan, n INode, changed bool, err error) {
+	now := time.Now()
+	nn := n.Node()
+	label := nn.Label()
+	name := label
+	if name == "" {
+		name = nn.kind
+	}
+	args := map[string]any{
+		"id":     nn.id.String(),
+		"kind":   nn.kind,
+		"height": nn.height,
+	}
+	if label != "" {
+		args["label"] = label
+	}
+	if err != nil {
+		args["error"] = err.Error()
+	}
+	r := tg.r
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, traceEvent{
+		Name: name, Cat: "node", Phase: "X", Pid: tg.pid, Tid: span.tid,
+		Ts: r.since(span.started), Dur: float64(now.Sub(span.started).Nanoseconds()) / 1e3,
+		Args: args,
+	})
+	if err == nil {
+		var instant string
+		if !changed {
+			instant = "cutoff"
+		} else if _, ok := n.(rightHandSideBuilder); ok {
+			instant = "bind rebuild"
+		}
+		if instant != "" {
+			r.events = append(r.events, traceEvent{
+				Name: instant, Cat: "node", Phase: "i", Scope: "t", Ts: r.since(now), Pid: tg.pid, Tid: span.tid,
+				Args: map[string]any{"id": nn.id.String()},
+			})
+		}
+	}
+}
@@ -0,0 +1,232 @@
+package incr
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/wcharczuk/go-incr/testutil"
+)
+
+type testTraceEvents struct {
+	TraceEvents []struct {
+		Name  string         `json:"name"`
+		Cat   string         `json:"cat"`
+		Phase string         `json:"ph"`
+		Ts    float64        `json:"ts"`
+		Dur   float64        `json:"dur"`
+		Pid   int            `json:"pid"`
+		Tid   int            `json:"tid"`
+		Args  map[string]any `json:"args"`
+	} `json:"traceEvents"`
+	DisplayTimeUnit string `json:"displayTimeUnit"`
+}
+
+func readTraceEvents(t *testing.T, r *TraceEventRecorder) (parsed testTraceEvents) {
+	t.Helper()
+	buf := new(bytes.Buffer)
+	written, err := r.WriteTo(buf)
+	testutil.NoError(t, err)
+	testutil.Equal(t, int64(buf.Len()), written)
+	testutil.NoError(t, json.Unmarshal(buf.Bytes(), &parsed))
+	return
+}
+
+func Test_TraceEventRecorder(t *testing.T) {
+	r := NewTraceEventRecorder()
+	ctx := WithTracer(context.Background(), r)
+
+	g := New()
+	which := Var(g, "a")
+	b := Bind(g, which, func(bs Scope, w string) Incr[string] {
+		return Return(bs, w)
+	})
+	v := Var(g, 1)
+	co := Cutoff(g, v, func(_, nv int) bool { return nv > 1 })
+	m := Map(g, co, func(x int) int { return x * 10 })
+	m.Node().SetLabel("times ten")
+	_ = MustObserve(g, b)
+	_ = MustObserve(g, m)
+
+	testutil.NoError(t, g.Stabilize(ctx))
+	v.Set(2)
+	testutil.NoError(t, g.Stabilize(ctx))
+
+	parsed := readTraceEvents(t, r)
+	testutil.Equal(t, "ms", parsed.DisplayTimeUnit)
+
+	var passes, nodeSpans, cutoffs, rebuilds, lines int
+	var processName string
+	threadNames := make(map[int]string)
+	for _, e := range parsed.TraceEvents {
+		if e.Phase != "M" {
+			testutil.Equal(t, 1, e.Pid)
+		}
+		switch {
+		case e.Phase == "M" && e.Name == "process_name":
+			processName = e.Args["name"].(string)
+		case e.Phase == "M" && e.Name == "thread_name":
+			threadNames[e.Tid] = e.Args["name"].(string)
+		case e.Phase == "X" && e.Name == "stabilize":
+			passes++
+			testutil.Equal(t, 0, e.Tid)
+			testutil.Equal(t, g.ID().String(), e.Args["graph"])
+		case e.Phase == "X" && e.Cat == "node":
+			nodeSpans++
+			testutil.Equal(t, 1, e.Tid, "serial stabilization runs everything on the coordinator track")
+			if e.Args["id"] == m.Node().ID().String() {
+				testutil.Equal(t, "times ten", e.Name)
+				testutil.Equal(t, "map", e.Args["kind"])
+			}
+		case e.Phase == "i" && e.Name == "cutoff":
+			cutoffs++
+			testutil.Equal(t, co.Node().ID().String(), e.Args["id"])
+		case e.Phase == "i" && e.Name == "bind rebuild":
+			rebuilds++
+		case e.Phase == "i" && e.Cat == "trace":
+			lines++
+			testutil.Equal(t, 0, e.Tid)
+		}
+	}
+	testutil.Equal(t, 2, passes)
+	testutil.Equal(t, true, nodeSpans > 0)
+	testutil.Equal(t, 1, cutoffs)
+	testutil.Equal(t, 1, rebuilds)
+	testutil.Equal(t, true, lines > 0)
+	testutil.Equal(t, "graph "+g.ID().String(), processName)
+	testutil.Equal(t, "stabilization", threadNames[0])
+	testutil.Equal(t, "coordinator", threadNames[1])
+	testutil.Equal(t, 2, len(threadNames), "no worker ran anything")
+
+	r.Reset()
+	parsed = readTraceEvents(t, r)
+	testutil.Equal(t, 0, len(parsed.TraceEvents))
+}
+
+func Test_TraceEventRecorder_parallel(t *testing.T) {
+	for name, scheduler := range map[string]ParallelScheduler{
+		"heightBlocks": ParallelSchedulerHeightBlocks,
+		"dataflow":     ParallelSchedulerDataflow,
+	} {
+		t.Run(name, func(t *testing.T) {
+			r := NewTraceEventRecorder()
+			ctx := WithTracer(context.Background(), r)
+			g := New(
+				OptGraphParallelism(4),
+				OptGraphParallelScheduler(scheduler),
+			)
+
+			// the three nodes wait on each other, so they are all running at once, each
+			// on a worker of its own, and a cheap node runs on the coordinator
+			const width = 3
+			var started sync.WaitGroup
+			started.Add(width)
+			v := Var(g, 1)
+			for range width {
+				m := Map(g, v, func(x int) int {
+					started.Done()
+					started.Wait()
+					return x
+				})
+				m.Node().SetLabel("waits")
+				_ = MustObserve(g, m)
+			}
+			cheap := Map(g, v, func(x int) int { return x })
+			cheap.Node().SetLabel("cheap")
+			cheap.Node().SetCost(CostCheap)
+			_ = MustObserve(g, cheap)
+
+			testutil.NoError(t, g.ParallelStabilize(ctx))
+
+			tids := make(map[int]struct{})
+			threadNames := make(map[int]string)
+			var cheapTid int
+			for _, e := range readTraceEvents(t, r).TraceEvents {
+				switch {
+				case e.Phase == "M" && e.Name == "thread_name":
+					threadNames[e.Tid] = e.Args["name"].(string)
+				case e.Phase == "X" && e.Name == "waits":
+					tids[e.Tid] = struct{}{}
+				case e.Phase == "X" && e.Name == "cheap":
+					cheapTid = e.Tid
+				}
+			}
+			testutil.Equal(t, width, len(tids))
+			for tid := range tids {
+				testutil.Equal(t, true, tid >= 2 && tid <= 5, "on one of the four workers")
+				testutil.Equal(t, fmt.Sprintf("worker %d", tid-1), threadNames[tid])
+			}
+			testutil.Equal(t, 1, cheapTid)
+		})
+	}
+}
+
+func Test_TraceEventRecorder_graphs(t *testing.T) {
+	r := NewTraceEventRecorder()
+	ctx := WithTracer(context.Background(), r)
+
+	// two graphs sharing the recorder, stabilizing at the same time
+	graphs := []*Graph{New(OptGraphParallelism(2)), New(OptGraphParallelism(2))}
+	for _, g := range graphs {
+		_ = MustObserve(g, Map(g, Var(g, 1), func(x int) int { return x }))
+	}
+	var wg sync.WaitGroup
+	for _, g := range graphs {
+		wg.Go(func() {
+			testutil.NoError(t, g.ParallelStabilize(ctx))
+			testutil.NoError(t, g.ParallelStabilize(ctx))
+		})
+	}
+	wg.Wait()
+
+	pids := make(map[string]int)
+	passes := make(map[int]int)
+	for _, e := range readTraceEvents(t, r).TraceEvents {
+		switch {
+		case e.Phase == "M" && e.Name == "process_name":
+			pids[e.Args["name"].(string)] = e.Pid
+		case e.Phase == "X" && e.Name == "stabilize":
+			passes[e.Pid]++
+			testutil.Equal(t, true, e.Dur >= 0)
+		}
+	}
+	testutil.Equal(t, 2, len(pids))
+	for _, g := range graphs {
+		pid, ok := pids["graph "+g.ID().String()]
+		testutil.Equal(t, true, ok)
+		testutil.Equal(t, 2, passes[pid])
+	}
+}
+
+func Test_TraceEventRecorder_error(t *testing.T) {
+	r := NewTraceEventRecorder()
+	ctx := WithTracer(context.Background(), r)
+
+	g := New()
+	f := Func(g, func(_ context.Context) (string, error) {
+		return "", fmt.Errorf("this is only a test")
+	})
+	_ = MustObserve(g, f)
+
+	testutil.Error(t, g.Stabilize(ctx))
+
+	var sawNode, sawPass bool
+	for _, e := range readTraceEvents(t, r).TraceEvents {
+		if e.Phase != "X" {
+			continue
+		}
+		if e.Cat == "node" && e.Args["id"] == f.Node().ID().String() {
+			sawNode = true
+			testutil.Equal(t, "this is only a test", e.Args["error"])
+		}
+		if e.Name == "stabilize" {
+			sawPass = true
+			testutil.Equal(t, "this is only a test", e.Args["error"])
+		}
+	}
+	testutil.Equal(t, true, sawNode)
+	testutil.Equal(t, true, sawPass)
+}
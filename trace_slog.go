@@ -0,0 +1,154 @@
+package incr
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+// NewSlogTracer returns a [Tracer] that writes to a [slog.Handler].
+//
+// Every line it writes carries what the context it is traced on knows as structured
+// attributes, rather than formatted into the message: the graph's identifier and label,
+// the stabilization number, and for lines about a node, the node's identifier, kind,
+// label and height. Stabilization events are written at [slog.LevelInfo] and node events
+// at [slog.LevelDebug] unless set otherwise with [OptSlogTracerStabilizationLevel] and
+// [OptSlogTracerNodeLevel]; errors are always written at [slog.LevelError].
+//
+// Node events are only traced by this tracer: a line for each node as it is recomputed,
+// and one for each node that fails. Node code can log through the tracer, with the same
+// attributes attached, using the logger from [TraceLogger].
+func NewSlogTracer(handler slog.Handler, opts ...func(*SlogTracerOptions)) *SlogTracer {
+	options := SlogTracerOptions{
+		StabilizationLevel: slog.LevelInfo,
+		NodeLevel:          slog.LevelDebug,
+	}
+	for _, opt := range opts {
+		opt(&options)
+	}
+	return &SlogTracer{
+		handler:            handler,
+		stabilizationLevel: options.StabilizationLevel,
+		nodeLevel:          options.NodeLevel,
+	}
+}
+
+// SlogTracerOptions are options for a tracer created with [NewSlogTracer].
+type SlogTracerOptions struct {
+	StabilizationLevel slog.Level
+	NodeLevel          slog.Level
+}
+
+// OptSlogTracerStabilizationLevel sets the level stabilization events, like a pass
+// starting and completing, are written at.
+func OptSlogTracerStabilizationLevel(level slog.Level) func(*SlogTracerOptions) {
+	return func(o *SlogTracerOptions) {
+		o.StabilizationLevel = level
+	}
+}
+
+// OptSlogTracerNodeLevel sets the level a node being recomputed is written at.
+func OptSlogTracerNodeLevel(level slog.Level) func(*SlogTracerOptions) {
+	return func(o *SlogTracerOptions) {
+		o.NodeLevel = level
+	}
+}
+
+// SlogTracer is a [Tracer] that writes to a [slog.Handler]; see [NewSlogTracer].
+type SlogTracer struct {
+	handler            slog.Handler
+	stabilizationLevel slog.Level
+	nodeLevel          slog.Level
+}
+
+var (
+	_ Tracer = (*SlogTracer)(nil)
+)
+
+// Print implements [Tracer]. Lines traced with [TracePrintf] and the like are written
+// with the context's attributes; lines printed directly have none to write.
+func (t *SlogTracer) Print(args ...any) {
+	t.write(context.Background(), t.stabilizationLevel, fmt.Sprint(args...), nil)
+}
+
+// Error implements [Tracer], and writes at [slog.LevelError].
+func (t *SlogTracer) Error(args ...any) {
+	t.write(context.Background(), slog.LevelError, fmt.Sprint(args...), nil)
+}
+
+// Handler returns the handler the tracer writes to.
+func (t *SlogTracer) Handler() slog.Handler {
+	return t.handler
+}
+
+// write writes a line with the attributes carried by ctx, and err if it is not nil.
+func (t *SlogTracer) write(ctx context.Context, level slog.Level, msg string, err error) {
+	if !t.handler.Enabled(ctx, level) {
+		return
+	}
+	r := slog.NewRecord(time.Now(), level, msg, 0)
+	r.AddAttrs(traceAttrs(ctx)...)
+	if err != nil {
+		r.AddAttrs(slog.Any("err", err))
+	}
+	_ = t.handler.Handle(ctx, r)
+}
+
+// TraceLogger returns a logger that writes through the [SlogTracer] on a given context,
+// with the context's attributes attached; passed the context a node's function is
+// called with, they include the node's.
+//
+// If the context has no [SlogTracer] the logger discards everything written to it, so
+// node code can log unconditionally.
+func TraceLogger(ctx context.Context) *slog.Logger {
+	st, ok := GetTracer(ctx).(*SlogTracer)
+	if !ok {
+		return slog.New(slog.DiscardHandler)
+	}
+	return slog.New(st.handler.WithAttrs(traceAttrs(ctx)))
+}
+
+type traceGraphKey struct{}
+
+type traceNodeKey struct{}
+
+// traceAttrs returns the attributes a context carries for a [SlogTracer].
+func traceAttrs(ctx context.Context) (attrs []slog.Attr) {
+	if graph, ok := ctx.Value(traceGraphKey{}).(*Graph); ok {
+		attrs = append(attrs, slog.Group("graph",
+			slog.String("id", graph.id.String()),
+			slog.String("label", graph.label),
+		))
+	}
+	if num, ok := GetStabilizationNumber(ctx); ok {
+		attrs = append(attrs, slog.Uint64("stabilization", num))
+	}
+	if n, ok := ctx.Value(traceNodeKey{}).(INode); ok {
+		nn := n.Node()
+		attrs = append(attrs, slog.Group("node",
+			slog.String("id", nn.id.String()),
+			slog.String("kind", nn.kind),
+			slog.String("label", nn.Label()),
+			slog.Int("height", nn.height),
+		))
+	}
+	return
+}
+
+// traceNodeStart is called before a node is recomputed when the pass is being traced
+// by a [SlogTracer], and returns the context to recompute it with.
+func (graph *Graph) traceNodeStart(ctx context.Context, n INode) context.Context {
+	ctx = context.WithValue(ctx, traceNodeKey{}, n)
+	graph.slogTracer.write(ctx, graph.slogTracer.nodeLevel, "recomputing node", nil)
+	return ctx
+}
+
+// traceNodeError is called when a node fails and the pass is being traced by a
+// [SlogTracer].
+func (graph *Graph) traceNodeError(ctx context.Context, n INode, err error) {
+	if traced, _ := ctx.Value(traceNodeKey{}).(INode); traced != n {
+		ctx = context.WithValue(ctx, traceNodeKey{}, n)
+	}
+	graph.slogTracer.write(ctx, slog.LevelError, "node error", err)
+}
@@ -0,0 +1,184 @@
+package incr
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/wcharczuk/go-incr/testutil"
+)
+
+func slogLines(t *testing.T, output *bytes.Buffer) (lines []map[string]any) {
+	t.Helper()
+	for _, line := range strings.Split(strings.TrimSpace(output.String()), "\n") {
+		if line == "" {
+			continue
+		}
+		var parsed map[string]any
+		testutil.NoError(t, json.Unmarshal([]byte(line), &parsed))
+		lines = append(lines, parsed)
+	}
+	return
+}
+
+func slogLinesWithMessage(lines []map[string]any, msg string) (matching []map[string]any) {
+	for _, line := range lines {
+		if line["msg"] == msg {
+			matching = append(matching, line)
+		}
+	}
+	return
+}
+
+func Test_SlogTracer(t *testing.T) {
+	output := new(bytes.Buffer)
+	handler := slog.NewJSONHandler(output, &slog.HandlerOptions{Level: slog.LevelDebug})
+	ctx := WithTracer(context.Background(), NewSlogTracer(handler))
+
+	g := New()
+	g.SetLabel("test-graph")
+	v := Var(g, "foo")
+	m := Map(g, v, func(s string) string { return s + "!" })
+	m.Node().SetLabel("bang")
+	_ = MustObserve(g, m)
+
+	err := g.Stabilize(ctx)
+	testutil.NoError(t, err)
+
+	lines := slogLines(t, output)
+	starting := slogLinesWithMessage(lines, "stabilization starting")
+	testutil.Equal(t, 1, len(starting))
+	testutil.Equal(t, "INFO", starting[0]["level"])
+	testutil.Equal(t, float64(1), starting[0]["stabilization"])
+	graphAttrs := starting[0]["graph"].(map[string]any)
+	testutil.Equal(t, g.ID().String(), graphAttrs["id"])
+	testutil.Equal(t, "test-graph", graphAttrs["label"])
+	testutil.Nil(t, starting[0]["node"])
+
+	var sawMap bool
+	for _, line := range slogLinesWithMessage(lines, "recomputing node") {
+		testutil.Equal(t, "DEBUG", line["level"])
+		testutil.Equal(t, float64(1), line["stabilization"])
+		nodeAttrs := line["node"].(map[string]any)
+		if nodeAttrs["id"] == m.Node().ID().String() {
+			sawMap = true
+			testutil.Equal(t, "map", nodeAttrs["kind"])
+			testutil.Equal(t, "bang", nodeAttrs["label"])
+			testutil.Equal(t, float64(m.Node().height), nodeAttrs["height"])
+		}
+	}
+	testutil.Equal(t, true, sawMap)
+	var completed int
+	for _, line := range lines {
+		if strings.HasPrefix(line["msg"].(string), "stabilization complete") {
+			completed++
+		}
+	}
+	testutil.Equal(t, 1, completed)
+}
+
+func Test_SlogTracer_levels(t *testing.T) {
+	output := new(bytes.Buffer)
+	handler := slog.NewJSONHandler(output, &slog.HandlerOptions{Level: slog.LevelInfo})
+
+	// the handler drops debug, which is where node events go by default; moving
+	// stabilization events down to debug as well leaves nothing
+	ctx := WithTracer(context.Background(), NewSlogTracer(handler,
+		OptSlogTracerStabilizationLevel(slog.LevelDebug),
+	))
+	g := New()
+	_ = MustObserve(g, Map(g, Var(g, "foo"), ident))
+	testutil.NoError(t, g.Stabilize(ctx))
+	testutil.Equal(t, "", output.String())
+
+	ctx = WithTracer(context.Background(), NewSlogTracer(handler,
+		OptSlogTracerNodeLevel(slog.LevelWarn),
+	))
+	g = New()
+	_ = MustObserve(g, Map(g, Var(g, "foo"), ident))
+	testutil.NoError(t, g.Stabilize(ctx))
+	lines := slogLines(t, output)
+	testutil.Equal(t, 1, len(slogLinesWithMessage(lines, "stabilization starting")))
+	for _, line := range slogLinesWithMessage(lines, "recomputing node") {
+		testutil.Equal(t, "WARN", line["level"])
+	}
+	testutil.Equal(t, true, len(slogLinesWithMessage(lines, "recomputing node")) > 0)
+}
+
+func Test_SlogTracer_nodeError(t *testing.T) {
+	for name, stabilize := range map[string]func(*Graph, context.Context) error{
+		"serial":   (*Graph).Stabilize,
+		"parallel": (*Graph).ParallelStabilize,
+	} {
+		t.Run(name, func(t *testing.T) {
+			output := new(bytes.Buffer)
+			handler := slog.NewJSONHandler(output, nil)
+			ctx := WithTracer(context.Background(), NewSlogTracer(handler))
+
+			g := New()
+			f := Func(g, func(_ context.Context) (string, error) {
+				return "", fmt.Errorf("this is only a test")
+			})
+			_ = MustObserve(g, f)
+
+			err := stabilize(g, ctx)
+			testutil.Error(t, err)
+
+			lines := slogLines(t, output)
+			errors := slogLinesWithMessage(lines, "node error")
+			testutil.Equal(t, true, len(errors) > 0)
+			testutil.Equal(t, "ERROR", errors[0]["level"])
+			testutil.Equal(t, "this is only a test", errors[0]["err"])
+			testutil.Equal(t, f.Node().ID().String(), errors[0]["node"].(map[string]any)["id"])
+			testutil.Equal(t, 1, len(slogLinesWithMessage(lines, "stabilization error: this is only a test")))
+		})
+	}
+}
+
+func Test_TraceLogger(t *testing.T) {
+	output := new(bytes.Buffer)
+	handler := slog.NewJSONHandler(output, nil)
+	ctx := WithTracer(context.Background(), NewSlogTracer(handler))
+
+	g := New()
+	v := Var(g, "foo")
+	m := MapContext(g, v, func(ctx context.Context, s string) (string, error) {
+		TraceLogger(ctx).Info("from the node", slog.String("value", s))
+		return s, nil
+	})
+	_ = MustObserve(g, m)
+
+	err := g.ParallelStabilize(ctx)
+	testutil.NoError(t, err)
+
+	logged := slogLinesWithMessage(slogLines(t, output), "from the node")
+	testutil.Equal(t, 1, len(logged))
+	testutil.Equal(t, "foo", logged[0]["value"])
+	testutil.Equal(t, float64(1), logged[0]["stabilization"])
+	testutil.Equal(t, g.ID().String(), logged[0]["graph"].(map[string]any)["id"])
+	testutil.Equal(t, m.Node().ID().String(), logged[0]["node"].(map[string]any)["id"])
+}
+
+func Test_TraceLogger_noTracer(t *testing.T) {
+	ctx := context.Background()
+	g := New()
+	v := Var(g, "foo")
+	m := MapContext(g, v, func(ctx context.Context, s string) (string, error) {
+		TraceLogger(ctx).Info("goes nowhere")
+		return s, nil
+	})
+	o := MustObserve(g, m)
+	testutil.NoError(t, g.Stabilize(ctx))
+	testutil.Equal(t, "foo", o.Value())
+
+	// a tracer that is not a SlogTracer has nowhere to send structured lines either
+	output := new(bytes.Buffer)
+	ctx = WithTracingOutputs(ctx, output, output)
+	v.Set("bar")
+	testutil.NoError(t, g.Stabilize(ctx))
+	testutil.Equal(t, false, strings.Contains(output.String(), "goes nowhere"))
+}
@@ -2,7 +2,9 @@ package incr
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"reflect"
 	"sync/atomic"
 )
 
@@ -15,7 +17,7 @@ import (
 // construction. Calling [Var.Set] will mark the [Var] node stale, as well any of the nodes that
 // take the [Var] node as an input (i.e. the [Var] node's children).
 func Var[T any](scope Scope, t T) VarIncr[T] {
-	return WithinScope(scope, &varIncr[T]{
+	return registerVar(scope, &varIncr[T]{
 		n:     scope.newNode(KindVar),
 		value: t,
 	})
@@ -40,13 +42,30 @@ func VarEqual[T comparable](scope Scope, t T) VarIncr[T] {
 
 // VarEqualFunc is [VarEqual] for types with no ==, taking the comparison.
 func VarEqualFunc[T any](scope Scope, t T, equal func(a, b T) bool) VarIncr[T] {
-	return WithinScope(scope, &varIncr[T]{
+	return registerVar(scope, &varIncr[T]{
 		n:     scope.newNode(KindVar),
 		value: t,
 		equal: equal,
 	})
 }
 
+// registerVar associates a var with its scope, and on a graph built by [Replay] records
+// it with the graph so that the recording can set it by identifier.
+func registerVar[T any](scope Scope, vn *varIncr[T]) *varIncr[T] {
+	WithinScope(scope, vn)
+	graph := scope.scopeGraph()
+	if !graph.replayVars {
+		return vn
+	}
+	graph.varsMu.Lock()
+	if graph.vars == nil {
+		graph.vars = make(map[Identifier]recordedSetter)
+	}
+	graph.vars[vn.n.id] = vn
+	graph.varsMu.Unlock()
+	return vn
+}
+
 // VarIncr is a graph node type that implements an incremental variable.
 type VarIncr[T any] interface {
 	Incr[T]
@@ -73,6 +92,8 @@ var (
 	_ IStale               = (*varIncr[string])(nil)
 	_ IStabilize           = (*varIncr[string])(nil)
 	_ fmt.Stringer         = (*varIncr[string])(nil)
+	_ recordedSetter       = (*varIncr[string])(nil)
+	_ quiescenceKeyer      = (*varIncr[string])(nil)
 )
 
 type varIncr[T any] struct {
@@ -112,10 +133,23 @@ func (vn *varIncr[T]) Set(v T) {
 		graph.setDuringStabilizationMu.Unlock()
 		return
 	}
+	if graph.recorder != nil && atomic.LoadInt32(&graph.status) == StatusNotStabilizing {
+		graph.recorder.recordSet(graph, vn.n.id, v)
+	}
 	vn.value = v
 	if vn.n.isNecessary() {
-		graph.SetStale(vn)
+		graph.setStale(vn)
+	}
+}
+
+// setRecorded sets the var to a value from a recording; see [Replay].
+func (vn *varIncr[T]) setRecorded(data []byte) error {
+	var v T
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
 	}
+	vn.Set(v)
+	return nil
 }
 
 func (vn *varIncr[T]) Update(fn func(T) T) {
@@ -143,6 +177,18 @@ func (vn *varIncr[T]) Stabilize(ctx context.Context) error {
 	return nil
 }
 
+// quiescenceKey returns the value the var holds for the next pass in printed form, if
+// that identifies it; see [Graph.StabilizeUntilQuiescent].
+func (vn *varIncr[T]) quiescenceKey() (string, bool) {
+	if !plainData(reflect.TypeFor[T]()) {
+		return "", false
+	}
+	if vn.setDuringStabilization {
+		return fmt.Sprintf("%#v", vn.setDuringStabilizationValue), true
+	}
+	return fmt.Sprintf("%#v", vn.value), true
+}
+
 func (vn *varIncr[T]) String() string {
 	return vn.n.String()
 }
@@ -0,0 +1,102 @@
+package incr
+
+// View is a snapshot of every observer's value as one stabilization left it.
+//
+// An observer's Value reads straight through to the node it observes, so reading it
+// from another goroutine races with the next pass, and reading two observers one after
+// the other can straddle a pass and return values that never coexisted. A view is
+// captured on the stabilizing goroutine as a pass ends and never changes afterwards, so
+// any number of goroutines can read one without synchronizing with the graph or with
+// each other, and every value in it came from the same stabilization.
+//
+// The view holds the values themselves, not copies of what they point to: a slice or a
+// map that a node mutates in place on a later pass is not protected by the view. Values
+// that are replaced rather than mutated -- which includes everything in incrutil/pmap
+// and incrutil/pvec -- are.
+type View struct {
+	stabilizationNum uint64
+	values           map[Identifier]any
+}
+
+// StabilizationNum returns the number of the stabilization the view was captured at
+// the end of, or zero for the view of a graph that has not yet stabilized.
+func (v *View) StabilizationNum() uint64 { return v.stabilizationNum }
+
+// ViewValue returns an observer's value in a view. It reports false if the observer was
+// not observing when the view was captured, or if the graph does not capture views.
+func ViewValue[A any](v *View, o ObserveIncr[A]) (value A, ok bool) {
+	captured, ok := v.values[o.Node().id]
+	if !ok {
+		return
+	}
+	value, _ = captured.(A)
+	return
+}
+
+// ViewNodeValue returns a node's value in a view returned by [Graph.At]. It reports
+// false if the view does not have the node, which is always the case for a view
+// returned by [Graph.View], as those only hold observers.
+func ViewNodeValue[A any](v *View, n Incr[A]) (value A, ok bool) {
+	captured, ok := v.values[n.Node().id]
+	if !ok {
+		return
+	}
+	value, _ = captured.(A)
+	return
+}
+
+// View returns the most recent snapshot of the graph's observers. It is safe to call
+// from any goroutine, including while the graph is stabilizing, and never blocks.
+//
+// A graph only captures views when created with [OptGraphViews]; without it, the view
+// returned holds no values.
+func (graph *Graph) View() *View {
+	if v := graph.view.Load(); v != nil {
+		return v
+	}
+	return &View{}
+}
+
+// viewObserver is implemented by observers whose values can be captured into a view.
+type viewObserver interface {
+	// viewValue returns the observer's value, and the stabilization number at which the
+	// node it observes last changed.
+	viewValue() (value any, changedAt uint64)
+}
+
+// captureView publishes a new view at the end of a successful stabilization.
+//
+// The values are copied only if an observer has come or gone, or the node one of them
+// observes changed since the last capture; otherwise the new view shares the last one's
+// values and differs only in its stabilization number.
+func (graph *Graph) captureView() {
+	previous := graph.view.Load()
+	graph.observersMu.Lock()
+	defer graph.observersMu.Unlock()
+
+	changed := previous == nil || graph.viewObserversChanged
+	if !changed {
+		for _, o := range graph.observers {
+			if vo, ok := o.(viewObserver); ok {
+				if _, changedAt := vo.viewValue(); changedAt > previous.stabilizationNum {
+					changed = true
+					break
+				}
+			}
+		}
+	}
+	next := &View{stabilizationNum: graph.stabilizationNum}
+	if !changed {
+		next.values = previous.values
+		graph.view.Store(next)
+		return
+	}
+	next.values = make(map[Identifier]any, len(graph.observers))
+	for id, o := range graph.observers {
+		if vo, ok := o.(viewObserver); ok {
+			next.values[id], _ = vo.viewValue()
+		}
+	}
+	graph.viewObserversChanged = false
+	graph.view.Store(next)
+}
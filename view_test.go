@@ -0,0 +1,135 @@
+package incr
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/wcharczuk/go-incr/testutil"
+)
+
+func Test_View(t *testing.T) {
+	ctx := testContext()
+	g := New(OptGraphViews(true))
+	a := Var(g, 1)
+	b := Var(g, "one")
+	oa := MustObserve(g, Map(g, a, func(v int) int { return v * 10 }))
+	ob := MustObserve(g, b)
+
+	_, ok := ViewValue(g.View(), oa)
+	testutil.Equal(t, false, ok)
+	testutil.Equal(t, uint64(0), g.View().StabilizationNum())
+
+	testutil.NoError(t, g.Stabilize(ctx))
+	first := g.View()
+	testutil.Equal(t, uint64(1), first.StabilizationNum())
+	value, ok := ViewValue(first, oa)
+	testutil.Equal(t, true, ok)
+	testutil.Equal(t, 10, value)
+	label, _ := ViewValue(first, ob)
+	testutil.Equal(t, "one", label)
+
+	// a var set but not yet stabilized is not in any view
+	a.Set(2)
+	value, _ = ViewValue(g.View(), oa)
+	testutil.Equal(t, 10, value)
+
+	testutil.NoError(t, g.Stabilize(ctx))
+	second := g.View()
+	testutil.Equal(t, uint64(2), second.StabilizationNum())
+	value, _ = ViewValue(second, oa)
+	testutil.Equal(t, 20, value)
+	// and the earlier view is unaffected
+	value, _ = ViewValue(first, oa)
+	testutil.Equal(t, 10, value)
+
+	// a pass that changes no observed node shares the values rather than copying them
+	testutil.NoError(t, g.Stabilize(ctx))
+	third := g.View()
+	testutil.Equal(t, uint64(3), third.StabilizationNum())
+	testutil.Equal(t, reflect.ValueOf(second.values).Pointer(), reflect.ValueOf(third.values).Pointer())
+
+	ob.Unobserve(ctx)
+	testutil.NoError(t, g.Stabilize(ctx))
+	_, ok = ViewValue(g.View(), ob)
+	testutil.Equal(t, false, ok)
+	_, ok = ViewValue(third, ob)
+	testutil.Equal(t, true, ok)
+}
+
+func Test_View_error(t *testing.T) {
+	ctx := testContext()
+	g := New(OptGraphViews(true))
+	v := Var(g, 1)
+	o := MustObserve(g, v)
+	failing := MustObserve(g, MapContext(g, v, func(_ context.Context, x int) (int, error) {
+		if x < 0 {
+			return 0, errors.New("negative")
+		}
+		return x, nil
+	}))
+	testutil.NoError(t, g.Stabilize(ctx))
+
+	// the var recomputes before the failing map, so the failed pass changed it; the view
+	// keeps what the last successful pass left rather than that half
+	v.Set(-1)
+	testutil.Error(t, g.Stabilize(ctx))
+	testutil.Equal(t, uint64(1), g.View().StabilizationNum())
+	value, _ := ViewValue(g.View(), o)
+	testutil.Equal(t, 1, value)
+
+	// and the next successful pass sees that the var changed in the failed one
+	v.Set(5)
+	testutil.NoError(t, g.Stabilize(ctx))
+	value, _ = ViewValue(g.View(), o)
+	testutil.Equal(t, 5, value)
+	value, _ = ViewValue(g.View(), failing)
+	testutil.Equal(t, 5, value)
+}
+
+func Test_View_disabled(t *testing.T) {
+	g := New()
+	o := MustObserve(g, Var(g, 1))
+	testutil.NoError(t, g.Stabilize(testContext()))
+	_, ok := ViewValue(g.View(), o)
+	testutil.Equal(t, false, ok)
+}
+
+func Test_View_concurrentReaders(t *testing.T) {
+	ctx := testContext()
+	g := New(OptGraphViews(true))
+	v := Var(g, 0)
+	positive := MustObserve(g, Map(g, v, func(x int) int { return x }))
+	negative := MustObserve(g, Map(g, v, func(x int) int { return -x }))
+	testutil.NoError(t, g.Stabilize(ctx))
+
+	// readers check that the two values always came from the same pass, which reading
+	// the observers directly could not promise
+	var stop atomic.Bool
+	var inconsistent atomic.Int64
+	var wg sync.WaitGroup
+	for range 4 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for !stop.Load() {
+				view := g.View()
+				p, _ := ViewValue(view, positive)
+				n, _ := ViewValue(view, negative)
+				if p != -n || uint64(p+1) != view.StabilizationNum() {
+					inconsistent.Add(1)
+				}
+			}
+		}()
+	}
+	for value := 1; value <= 1000; value++ {
+		v.Set(value)
+		testutil.NoError(t, g.Stabilize(ctx))
+	}
+	stop.Store(true)
+	wg.Wait()
+	testutil.Equal(t, int64(0), inconsistent.Load())
+}